@@ -1,12 +1,30 @@
 package mcpvmix
 
 type BaseVMixArguments struct {
-	IP   string `json:"ip" jsonschema:"required,description=The IP address of the vMix instance. generally this is 127.0.0.1"`
-	Port int    `json:"port" jsonschema:"required,description=The port of the vMix instance. generally this is 8088."`
+	Instance string `json:"instance,omitempty" jsonschema:"description=The name of the vMix instance to use. See vmix_list_instances. If omitted the selected instance is used."`
+	IP       string `json:"ip,omitempty" jsonschema:"description=Optional override of the IP address of the vMix instance. generally this is 127.0.0.1"`
+	Port     int    `json:"port,omitempty" jsonschema:"description=Optional override of the port of the vMix instance. generally this is 8088."`
+	// Transport lets a tool call pick the vMix API regardless of the tcp setting of the instance.
+	Transport string `json:"transport,omitempty" jsonschema:"enum=auto,enum=tcp,enum=http,description=The vMix API used by this call. 'tcp' uses the lower-latency TCP API (port 8099) and fails instead of falling back to HTTP. 'http' uses the HTTP API. 'auto' (default) follows the tcp setting of the instance."`
+}
+
+type ListInstancesArguments struct{}
+
+type SelectInstanceArguments struct {
+	Name string `json:"name" jsonschema:"required,description=The name of the vMix instance to use for following tool calls."`
 }
 
 type ConnectVmixArguments struct {
 	BaseVMixArguments
+	Format string   `json:"format,omitempty" jsonschema:"enum=text,enum=json,description=Output format. 'json' returns the complete vMix state (inputs with layers/title fields/list items/audio, overlays, transitions, mixes, audio buses, recording/streaming flags, active/preview) as one JSON document. Default is 'text'."`
+	Types  []string `json:"types,omitempty" jsonschema:"description=Only return inputs of these types (e.g. Video, GT, Colour, Capture). Case insensitive."`
+	Name   string   `json:"name,omitempty" jsonschema:"description=Only return inputs whose name or title matches this glob pattern (e.g. 'Cam*'). Case insensitive."`
+	OnAir  bool     `json:"on_air,omitempty" jsonschema:"description=Only return inputs currently on air: program of each mix, overlay channels and their layers."`
+}
+
+type VmixEventsArguments struct {
+	BaseVMixArguments
+	Limit int `json:"limit,omitempty" jsonschema:"description=The maximum number of latest activator events to return. 0 returns all kept events."`
 }
 
 type VmixInput struct {
@@ -16,12 +34,14 @@ type VmixInput struct {
 type VmixCutArguments struct {
 	BaseVMixArguments
 	VmixInput
+	VmixMix
 }
 
 type VmixFadeArguments struct {
 	BaseVMixArguments
 	VmixInput
 	Duration int `json:"duration" jsonschema:"required,description=The duration of the fade. This is the duration of the fade in milliseconds."`
+	VmixMix
 }
 type VmixRecordingArguments struct {
 	BaseVMixArguments
@@ -36,16 +56,38 @@ type VmixBasicArguments struct {
 	BaseVMixArguments
 }
 
+// VmixConfirmation is the token returned by the first call of a destructive tool when confirmation is enabled.
+type VmixConfirmation struct {
+	ConfirmationToken string `json:"confirmationToken,omitempty" jsonschema:"description=The confirmation token returned by the previous call of this tool. Omit it on the first call. Only pass it after the user agreed to what the previous call described."`
+}
+
+type VmixFadeToBlackArguments struct {
+	BaseVMixArguments
+	VmixConfirmation
+}
+
+type VmixStopRecordingArguments struct {
+	BaseVMixArguments
+	VmixConfirmation
+}
+
+type VmixStopStreamingArguments struct {
+	BaseVMixArguments
+	StreamNumber int `json:"streamNumber" jsonschema:"required,description=The stream number to stop streaming on. Generally this is 1~4."`
+	VmixConfirmation
+}
+
 type GetShortcutURLArguments struct {
 	BaseVMixArguments
 	Function string            `json:"function" jsonschema:"required,description=The function to get the shortcut URL for"`
-	Queries  map[string]string `json:"queries" jsonschema:"required,description=The Key/Value queries for the function arguments. e.g. {\"Input\": \"1\"} would be for the function Input=1 and the argument Input. {\"Mix\": \"1\"} would be for the function Mix=1 and the argument Mix."`
+	Queries  map[string]string `json:"queries" jsonschema:"required,description=The Key/Value queries for the function arguments. e.g. {\"Input\": \"1\"} would be for the function Input=1 and the argument Input. {\"Mix\": \"2\"} targets Mix 2. Mix is counted like the vMix UI from 1 and converted to the API parameter in the URL."`
 }
 
 type AddBlankArguments struct {
 	BaseVMixArguments
-	Numbers       int  `json:"numbers" jsonschema:"required,description=The number of blank inputs to add"`
-	IsTransparent bool `json:"isTransparent" jsonschema:"required,description=Whether the blank inputs should be transparent"`
+	Numbers       int      `json:"numbers" jsonschema:"required,description=The number of blank inputs to add. Up to 50."`
+	IsTransparent bool     `json:"isTransparent" jsonschema:"required,description=Whether the blank inputs should be transparent"`
+	Names         []string `json:"names,omitempty" jsonschema:"description=Names of the new inputs in order. One per input. An empty name keeps the name given by vMix."`
 }
 
 type GetCurrentScreenshotArguments struct {
@@ -103,3 +145,309 @@ type AdjustLayersLayerArguments struct {
 	// RectangleWidth  *float64 `json:"rectangleWidth" jsonschema:"description=The layer rectangle width to make the scene for. This resizes the layer absolute resolution. default is null."`
 	// RectangleHeight *float64 `json:"rectangleHeight" jsonschema:"description=The layer rectangle height to make the scene for. This resizes the layer absolute resolution. default is null."`
 }
+
+type VmixFunctionArguments struct {
+	BaseVMixArguments
+	Function      string `json:"function" jsonschema:"required,description=The name of the vMix shortcut function. e.g. SetText or OverlayInput1In or ReplayMarkIn. Case insensitive."`
+	Input         string `json:"input,omitempty" jsonschema:"description=The Input parameter. This could be input number or input name or input key(UUID). key would be preferred."`
+	Value         string `json:"value,omitempty" jsonschema:"description=The Value parameter. Its format depends on the function."`
+	Duration      *int   `json:"duration,omitempty" jsonschema:"description=The Duration parameter of transitions in milliseconds."`
+	Mix           *int   `json:"mix,omitempty" jsonschema:"description=The mix the function works on counted like the vMix UI. 1 is the main program/preview and 2~16 are Mix 2~16. Omit it for the main mix."`
+	SelectedName  string `json:"selectedName,omitempty" jsonschema:"description=The SelectedName parameter. The name of a title field. e.g. Headline.Text"`
+	SelectedIndex *int   `json:"selectedIndex,omitempty" jsonschema:"description=The SelectedIndex parameter. The index of a title field starting at 0."`
+}
+
+type SearchFunctionsArguments struct {
+	Query    string `json:"query,omitempty" jsonschema:"description=Words to search in the function names and descriptions. e.g. countdown or replay mark. Every word must match."`
+	Category string `json:"category,omitempty" jsonschema:"description=Only return functions of this category. e.g. Title or Replay or Audio. Omit it to list the categories when query is empty too."`
+	Limit    int    `json:"limit,omitempty" jsonschema:"description=The maximum number of functions to return. Default is 20."`
+}
+
+// VmixMix selects the mix a switching tool works on.
+type VmixMix struct {
+	Mix *int `json:"mix,omitempty" jsonschema:"description=The mix to switch. 1 is the main program/preview (default). 2~16 are Mix 2~16 of vMix 4K/Pro."`
+}
+
+// mix returns the selected mix, or Mix 1 when it is omitted.
+func (v VmixMix) mix() int {
+	if v.Mix == nil {
+		return 1
+	}
+	return *v.Mix
+}
+
+type PreviewInputArguments struct {
+	BaseVMixArguments
+	Input string `json:"input" jsonschema:"required,description=The input to put in preview. This could be input number or input key(UUID). key would be preferred."`
+	VmixMix
+}
+
+type PreviewStepArguments struct {
+	BaseVMixArguments
+	VmixMix
+}
+
+type TransitionArguments struct {
+	BaseVMixArguments
+	Button int `json:"button" jsonschema:"required,description=The transition button to perform. 1~4. The effect and duration are the ones set on the button in vMix."`
+	VmixMix
+}
+
+type StingerArguments struct {
+	BaseVMixArguments
+	Stinger int    `json:"stinger" jsonschema:"required,description=The stinger to perform. 1~4."`
+	Input   string `json:"input,omitempty" jsonschema:"description=The input to transition to. The input in preview is used if omitted."`
+	VmixMix
+}
+
+type TransitionEffectArguments struct {
+	BaseVMixArguments
+	Effect   string `json:"effect" jsonschema:"required,enum=Fade,enum=Zoom,enum=Wipe,enum=Slide,enum=Fly,enum=CrossZoom,enum=FlyRotate,enum=Cube,enum=CubeZoom,enum=VerticalWipe,enum=VerticalSlide,enum=Merge,enum=WipeReverse,enum=SlideReverse,enum=VerticalWipeReverse,enum=VerticalSlideReverse,enum=BarnDoor,enum=RollerDoor,description=The transition effect."`
+	Input    string `json:"input,omitempty" jsonschema:"description=The input to transition to. The input in preview is used if omitted."`
+	Duration int    `json:"duration,omitempty" jsonschema:"description=The duration of the transition in milliseconds. The vMix default is used if omitted."`
+	VmixMix
+}
+
+type MixStatusArguments struct {
+	BaseVMixArguments
+	Mix *int `json:"mix,omitempty" jsonschema:"description=Only return this mix. 1 is the main program/preview and 2~16 are Mix 2~16. Every mix is returned if omitted."`
+}
+
+type OverlayArguments struct {
+	BaseVMixArguments
+	Channel int    `json:"channel" jsonschema:"required,description=The overlay channel. 1~4."`
+	Action  string `json:"action" jsonschema:"required,enum=in,enum=out,enum=off,enum=toggle,description=in brings the input in with the overlay transition. out takes the channel out with the transition. off removes it immediately. toggle brings the input in or takes it out."`
+	Input   string `json:"input,omitempty" jsonschema:"description=The input to bring in for in and toggle. The input in preview is used if omitted."`
+}
+
+type PreviewOverlayArguments struct {
+	BaseVMixArguments
+	Channel int    `json:"channel" jsonschema:"required,description=The overlay channel. 1~4."`
+	Input   string `json:"input,omitempty" jsonschema:"description=The input to toggle on the preview overlay. The input in preview is used if omitted."`
+}
+
+type OverlayStatusArguments struct {
+	BaseVMixArguments
+}
+
+type TitleFieldsArguments struct {
+	BaseVMixArguments
+	Input string `json:"input" jsonschema:"required,description=The title (GT) input. This could be input number or input name or input key(UUID). key would be preferred."`
+}
+
+type SetTitleFieldsArguments struct {
+	BaseVMixArguments
+	Input  string             `json:"input" jsonschema:"required,description=The title (GT) input. This could be input number or input name or input key(UUID). key would be preferred."`
+	Fields []TitleFieldUpdate `json:"fields" jsonschema:"required,description=The fields to update. Several fields can be updated in one call. Nothing is sent if any field is invalid."`
+}
+
+type TitleFieldUpdate struct {
+	Field   string  `json:"field" jsonschema:"required,description=The field name from vmix_title_fields. e.g. Headline.Text or Logo.Source. The index of the field is accepted too."`
+	Text    *string `json:"text,omitempty" jsonschema:"description=The new text of a text field. An empty string clears it."`
+	Colour  string  `json:"colour,omitempty" jsonschema:"description=The new colour of a text field. #RRGGBB or #AARRGGBB or a colour name such as Red."`
+	Visible *bool   `json:"visible,omitempty" jsonschema:"description=Show or hide the field."`
+	Image   string  `json:"image,omitempty" jsonschema:"description=The new source of an image field. A file path or URL."`
+}
+
+type AudioInputArguments struct {
+	BaseVMixArguments
+	Input     string   `json:"input" jsonschema:"required,description=The input to change. This could be input number or input name or input key(UUID). key would be preferred."`
+	Volume    *int     `json:"volume,omitempty" jsonschema:"description=The volume 0~100."`
+	FadeMs    int      `json:"fadeMs,omitempty" jsonschema:"description=Fade to volume over this many milliseconds instead of setting it at once."`
+	Muted     *bool    `json:"muted,omitempty" jsonschema:"description=true mutes the input and false unmutes it."`
+	Solo      *bool    `json:"solo,omitempty" jsonschema:"description=Solo the input or remove the solo."`
+	BusesOn   []string `json:"busesOn,omitempty" jsonschema:"description=Route the input to these buses. M is master and A~G are the sub buses."`
+	BusesOff  []string `json:"busesOff,omitempty" jsonschema:"description=Remove the input from these buses. M is master and A~G are the sub buses."`
+	AudioAuto *bool    `json:"audioAuto,omitempty" jsonschema:"description=Turn audio follows video on or off."`
+	Gain      *int     `json:"gain,omitempty" jsonschema:"description=The gain 0~24 dB."`
+	Balance   *float64 `json:"balance,omitempty" jsonschema:"description=The balance -1 (left) ~ 1 (right). 0 is centre."`
+}
+
+type AudioBusArguments struct {
+	BaseVMixArguments
+	Bus          string `json:"bus" jsonschema:"required,enum=Master,enum=A,enum=B,enum=C,enum=D,enum=E,enum=F,enum=G,description=The bus to change."`
+	Volume       *int   `json:"volume,omitempty" jsonschema:"description=The volume 0~100."`
+	Muted        *bool  `json:"muted,omitempty" jsonschema:"description=true mutes the bus and false unmutes it."`
+	Solo         *bool  `json:"solo,omitempty" jsonschema:"description=Solo the bus or remove the solo. Not available on Master."`
+	SendToMaster *bool  `json:"sendToMaster,omitempty" jsonschema:"description=Send the bus to master or stop sending it. Not available on Master."`
+}
+
+type AudioStatusArguments struct {
+	BaseVMixArguments
+	Input string `json:"input,omitempty" jsonschema:"description=Only report this input. Master and buses are always reported."`
+}
+
+type AudioAlertsArguments struct {
+	BaseVMixArguments
+	History int `json:"history,omitempty" jsonschema:"description=How many ended alerts to return. Defaults to 10."`
+}
+
+// ReplayInput selects the replay input. vMix uses the first replay input when it is empty.
+type ReplayInput struct {
+	Input string `json:"input,omitempty" jsonschema:"description=The replay input. This could be input number or input name or input key(UUID). Defaults to the first replay input."`
+}
+
+type ReplayMarkArguments struct {
+	BaseVMixArguments
+	ReplayInput
+	Mark    string `json:"mark" jsonschema:"required,enum=in,enum=out,enum=inOut,enum=cancel,description=in starts a new event and out ends it. inOut creates an event of the last seconds. cancel drops the event being marked."`
+	Seconds int    `json:"seconds,omitempty" jsonschema:"description=Length of the event for inOut in seconds."`
+}
+
+type ReplayPlayArguments struct {
+	BaseVMixArguments
+	ReplayInput
+	Event    string `json:"event" jsonschema:"required,enum=last,enum=selected,enum=all,enum=index,description=Which events to play: the last event or the selected event or all events of the current list or the event at index."`
+	Index    *int   `json:"index,omitempty" jsonschema:"description=Event index in the current list starting at 0. Only for event index."`
+	ToOutput bool   `json:"toOutput,omitempty" jsonschema:"description=Also cut the replay input to the output (program) when playback starts."`
+}
+
+type ReplaySpeedArguments struct {
+	BaseVMixArguments
+	ReplayInput
+	Speed  *float64 `json:"speed,omitempty" jsonschema:"description=Set the playback speed -1~1. 1 is normal speed and 0.5 is half speed. Negative plays backwards."`
+	Change *float64 `json:"change,omitempty" jsonschema:"description=Add this amount -1~1 to the playback speed instead."`
+}
+
+type ReplaySelectEventsArguments struct {
+	BaseVMixArguments
+	ReplayInput
+	List int `json:"list" jsonschema:"required,minimum=1,maximum=20,description=The event list 1~20."`
+}
+
+type ReplayCameraArguments struct {
+	BaseVMixArguments
+	ReplayInput
+	Camera  int    `json:"camera" jsonschema:"required,minimum=1,maximum=8,description=The replay camera 1~8."`
+	Channel string `json:"channel,omitempty" jsonschema:"enum=A,enum=B,description=Switch only channel A or B. Omit it to switch the current channel."`
+}
+
+type ReplayRecordingArguments struct {
+	BaseVMixArguments
+	ReplayInput
+}
+
+type ReplayStatusArguments struct {
+	BaseVMixArguments
+	ReplayInput
+}
+
+type ReplayEventsArguments struct {
+	BaseVMixArguments
+	ReplayInput
+}
+
+// PTZMotion is a continuous PTZ motion. It keeps going until it is stopped, or for durationMs.
+type PTZMotion struct {
+	Speed      *float64 `json:"speed,omitempty" jsonschema:"description=Speed 0~1. Defaults to the speed configured in vMix."`
+	DurationMs int      `json:"durationMs,omitempty" jsonschema:"description=Stop after this many milliseconds (up to 10000). Omit it to keep going until stop is sent."`
+}
+
+type PTZMoveArguments struct {
+	BaseVMixArguments
+	Input     string `json:"input" jsonschema:"required,description=The PTZ camera input. This could be input number or input name or input key(UUID). key would be preferred."`
+	Direction string `json:"direction" jsonschema:"required,enum=up,enum=down,enum=left,enum=right,enum=upLeft,enum=upRight,enum=downLeft,enum=downRight,enum=home,enum=stop,description=Direction to pan/tilt. home moves to the home position and stop stops moving."`
+	PTZMotion
+}
+
+type PTZZoomArguments struct {
+	BaseVMixArguments
+	Input string `json:"input" jsonschema:"required,description=The PTZ camera input. This could be input number or input name or input key(UUID). key would be preferred."`
+	Zoom  string `json:"zoom" jsonschema:"required,enum=in,enum=out,enum=stop,description=Zoom in or out or stop zooming."`
+	PTZMotion
+}
+
+type PTZFocusArguments struct {
+	BaseVMixArguments
+	Input string `json:"input" jsonschema:"required,description=The PTZ camera input. This could be input number or input name or input key(UUID). key would be preferred."`
+	Focus string `json:"focus" jsonschema:"required,enum=auto,enum=manual,enum=near,enum=far,enum=stop,description=Turn auto or manual focus on or focus near or far or stop focusing."`
+	PTZMotion
+}
+
+type PTZCreateVirtualInputArguments struct {
+	BaseVMixArguments
+	Input string `json:"input" jsonschema:"required,description=The PTZ camera input. This could be input number or input name or input key(UUID). key would be preferred."`
+}
+
+type PTZMoveToVirtualInputArguments struct {
+	BaseVMixArguments
+	Input string `json:"input" jsonschema:"required,description=The PTZ virtual input to move its camera to. With index this is the camera input instead."`
+	Index *int   `json:"index,omitempty" jsonschema:"description=Move the camera input to the position of its virtual input at this index starting at 0."`
+}
+
+type PTZInputsArguments struct {
+	BaseVMixArguments
+}
+
+type AddInputArguments struct {
+	BaseVMixArguments
+	Type   string `json:"type" jsonschema:"required,enum=Video,enum=Image,enum=Photos,enum=Audio,enum=Title,enum=Browser,enum=NDI,enum=SRT,enum=Colour,enum=VirtualSet,description=The input type."`
+	Source string `json:"source" jsonschema:"required,description=File path for Video/Image/Audio/Title/VirtualSet or folder for Photos or URL for Browser (http/https) and SRT (srt://) or the source name for NDI or a colour name or #RRGGBB for Colour."`
+	Name   string `json:"name,omitempty" jsonschema:"description=Rename the new input to this."`
+}
+
+type InputArguments struct {
+	BaseVMixArguments
+	Input string `json:"input" jsonschema:"required,description=The input. This could be input number or input name or input key(UUID). key would be preferred."`
+}
+
+type RemoveInputArguments struct {
+	BaseVMixArguments
+	Input string `json:"input" jsonschema:"required,description=The input to remove. This could be input number or input name or input key(UUID). key would be preferred."`
+	Force bool   `json:"force,omitempty" jsonschema:"description=Remove the input even when it is on air."`
+}
+
+type RenameInputArguments struct {
+	BaseVMixArguments
+	Input string `json:"input" jsonschema:"required,description=The input to rename. This could be input number or input name or input key(UUID). key would be preferred."`
+	Name  string `json:"name" jsonschema:"required,description=The new name."`
+}
+
+type MoveInputArguments struct {
+	BaseVMixArguments
+	Input  string `json:"input" jsonschema:"required,description=The input to move. This could be input number or input name or input key(UUID). key would be preferred."`
+	Number int    `json:"number" jsonschema:"required,minimum=1,description=The new input number. Inputs in between shift by one."`
+}
+
+type DuplicateInputArguments struct {
+	BaseVMixArguments
+	Input string `json:"input" jsonschema:"required,description=The input to duplicate. This could be input number or input name or input key(UUID). key would be preferred."`
+	Name  string `json:"name,omitempty" jsonschema:"description=Rename the copy to this."`
+}
+
+type PlaybackArguments struct {
+	BaseVMixArguments
+	Input  string `json:"input" jsonschema:"required,description=The video or audio input. This could be input number or input name or input key(UUID). key would be preferred."`
+	Action string `json:"action" jsonschema:"required,enum=play,enum=pause,enum=playPause,enum=restart,description=play or pause or toggle between them or restart from the beginning."`
+}
+
+type SeekArguments struct {
+	BaseVMixArguments
+	Input      string `json:"input" jsonschema:"required,description=The video or audio input. This could be input number or input name or input key(UUID). key would be preferred."`
+	PositionMs *int   `json:"positionMs,omitempty" jsonschema:"description=Seek to this position in milliseconds from the start."`
+	FromEndMs  *int   `json:"fromEndMs,omitempty" jsonschema:"description=Seek to this many milliseconds before the end (or the mark out point) instead."`
+}
+
+type PlaybackRateArguments struct {
+	BaseVMixArguments
+	Input string  `json:"input" jsonschema:"required,description=The video or audio input. This could be input number or input name or input key(UUID). key would be preferred."`
+	Rate  float64 `json:"rate" jsonschema:"required,description=Playback rate. 1 is normal speed and 0.5 is half speed slow motion."`
+}
+
+type LoopArguments struct {
+	BaseVMixArguments
+	Input string `json:"input" jsonschema:"required,description=The video or audio input. This could be input number or input name or input key(UUID). key would be preferred."`
+	Loop  bool   `json:"loop" jsonschema:"required,description=true turns loop on and false turns it off."`
+}
+
+type MarkArguments struct {
+	BaseVMixArguments
+	Input string `json:"input" jsonschema:"required,description=The video or audio input. This could be input number or input name or input key(UUID). key would be preferred."`
+	Mark  string `json:"mark" jsonschema:"required,enum=in,enum=out,enum=reset,enum=resetIn,enum=resetOut,description=Set the mark in or out point at the current position or clear both or one of them."`
+}
+
+type TimeRemainingArguments struct {
+	BaseVMixArguments
+	Input       string `json:"input,omitempty" jsonschema:"description=Only report this input. Omit it to report every input with a duration."`
+	WarnSeconds int    `json:"warnSeconds,omitempty" jsonschema:"description=Flag inputs on air which end within this many seconds. Defaults to 10."`
+}
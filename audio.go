@@ -0,0 +1,324 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/samber/lo"
+)
+
+// subBuses are the letters of the audio buses besides master.
+var subBuses = []string{"A", "B", "C", "D", "E", "F", "G"}
+
+// namedBus is master or a sub bus of the audio element.
+type namedBus struct {
+	Name string // Master or A~G
+	*stateBus
+}
+
+// buses returns master and the sub buses vMix reported, in mixer order.
+func (a stateAudio) buses() []namedBus {
+	all := []namedBus{{"Master", a.Master}, {"A", a.BusA}, {"B", a.BusB}, {"C", a.BusC}, {"D", a.BusD}, {"E", a.BusE}, {"F", a.BusF}, {"G", a.BusG}}
+	return lo.Filter(all, func(b namedBus, _ int) bool { return b.stateBus != nil })
+}
+
+// meterDB converts a meter value (linear amplitude 0~1) to dBFS.
+func meterDB(meter float64) float64 {
+	if meter <= 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(meter)
+}
+
+func formatMeters(f1, f2 float64) string {
+	format := func(v float64) string {
+		db := meterDB(v)
+		if math.IsInf(db, -1) {
+			return "-inf"
+		}
+		return fmt.Sprintf("%.1f", db)
+	}
+	return fmt.Sprintf("%s/%s dBFS", format(f1), format(f2))
+}
+
+// audioFunction is a function sent to change the mixer.
+type audioFunction struct {
+	function string
+	params   map[string]string
+}
+
+func onOff(function string, on bool) string {
+	return function + lo.Ternary(on, "On", "Off")
+}
+
+// inputAudioFunctions validates arguments and returns the functions to send to input.
+func inputAudioFunctions(input string, arguments AudioInputArguments) ([]audioFunction, error) {
+	var functions []audioFunction
+	var errs []string
+	add := func(function, value string) {
+		params := map[string]string{"Input": input}
+		if value != "" {
+			params["Value"] = value
+		}
+		functions = append(functions, audioFunction{function, params})
+	}
+
+	if v := arguments.Volume; v != nil {
+		switch {
+		case *v < 0 || *v > 100:
+			errs = append(errs, fmt.Sprintf("volume must be 0~100: %d", *v))
+		case arguments.FadeMs > 0:
+			add("SetVolumeFade", fmt.Sprintf("%d,%d", *v, arguments.FadeMs))
+		default:
+			add("SetVolume", strconv.Itoa(*v))
+		}
+	} else if arguments.FadeMs > 0 {
+		errs = append(errs, "fadeMs needs volume")
+	}
+	if arguments.Muted != nil {
+		add(lo.Ternary(*arguments.Muted, "AudioOff", "AudioOn"), "")
+	}
+	if arguments.Solo != nil {
+		add(onOff("Solo", *arguments.Solo), "")
+	}
+	for _, bus := range arguments.BusesOn {
+		if b, ok := parseBus(bus); ok {
+			add("AudioBusOn", b)
+		} else {
+			errs = append(errs, fmt.Sprintf("unknown bus: %s. Use M or A~G", bus))
+		}
+	}
+	for _, bus := range arguments.BusesOff {
+		if b, ok := parseBus(bus); ok {
+			add("AudioBusOff", b)
+		} else {
+			errs = append(errs, fmt.Sprintf("unknown bus: %s. Use M or A~G", bus))
+		}
+	}
+	if arguments.AudioAuto != nil {
+		add(onOff("AudioAuto", *arguments.AudioAuto), "")
+	}
+	if g := arguments.Gain; g != nil {
+		if *g < 0 || *g > 24 {
+			errs = append(errs, fmt.Sprintf("gain must be 0~24: %d", *g))
+		} else {
+			add("SetGain", strconv.Itoa(*g))
+		}
+	}
+	if b := arguments.Balance; b != nil {
+		if *b < -1 || *b > 1 {
+			errs = append(errs, fmt.Sprintf("balance must be -1~1: %g", *b))
+		} else {
+			add("SetBalance", strconv.FormatFloat(*b, 'g', -1, 64))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	if len(functions) == 0 {
+		return nil, fmt.Errorf("nothing to change")
+	}
+	return functions, nil
+}
+
+// parseBus parses M (or Master) and A~G, case insensitive.
+func parseBus(bus string) (string, bool) {
+	bus = strings.ToUpper(strings.TrimSpace(bus))
+	if bus == "M" || bus == "MASTER" {
+		return "M", true
+	}
+	return bus, lo.Contains(subBuses, bus)
+}
+
+// busAudioFunctions validates arguments and returns the functions to send.
+func busAudioFunctions(arguments AudioBusArguments) ([]audioFunction, error) {
+	bus, ok := parseBus(arguments.Bus)
+	if !ok {
+		return nil, fmt.Errorf("unknown bus: %s. Use Master or A~G", arguments.Bus)
+	}
+	master := bus == "M"
+
+	var functions []audioFunction
+	var errs []string
+	add := func(function, value string) {
+		params := map[string]string{}
+		if value != "" {
+			params["Value"] = value
+		}
+		functions = append(functions, audioFunction{function, params})
+	}
+
+	if v := arguments.Volume; v != nil {
+		switch {
+		case *v < 0 || *v > 100:
+			errs = append(errs, fmt.Sprintf("volume must be 0~100: %d", *v))
+		case master:
+			add("SetMasterVolume", strconv.Itoa(*v))
+		default:
+			add(fmt.Sprintf("SetBus%sVolume", bus), strconv.Itoa(*v))
+		}
+	}
+	if arguments.Muted != nil {
+		if master {
+			add(lo.Ternary(*arguments.Muted, "MasterAudioOff", "MasterAudioOn"), "")
+		} else {
+			add(lo.Ternary(*arguments.Muted, "BusXAudioOff", "BusXAudioOn"), bus)
+		}
+	}
+	if arguments.Solo != nil {
+		if master {
+			errs = append(errs, "master has no solo")
+		} else {
+			add(onOff("BusXSolo", *arguments.Solo), bus)
+		}
+	}
+	if arguments.SendToMaster != nil {
+		if master {
+			errs = append(errs, "master cannot be sent to master")
+		} else {
+			add(onOff("BusXSendToMaster", *arguments.SendToMaster), bus)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	if len(functions) == 0 {
+		return nil, fmt.Errorf("nothing to change")
+	}
+	return functions, nil
+}
+
+// sendAudioFunctions sends functions in order and stops at the first failure.
+func (m *mcpVmix) sendAudioFunctions(ctx context.Context, target vmixTarget, what string, functions []audioFunction) error {
+	m.log(ctx).Info("Attempting to change audio", "instance", target, "source", what)
+	for i, f := range functions {
+		if err := m.sendFunction(ctx, target, f.function, f.params); err != nil {
+			errMsg := fmt.Sprintf("Failed to send %s to %s: %v. %d of %d functions were applied", f.function, what, err, i, len(functions))
+			m.log(ctx).Error(errMsg, "instance", target)
+			return fmt.Errorf(errMsg)
+		}
+	}
+	m.log(ctx).Info("Successfully changed audio", "instance", target, "source", what)
+	return nil
+}
+
+// AudioInputVMix implements MCPvMix.
+func (m *mcpVmix) AudioInputVMix(ctx context.Context, arguments AudioInputArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	functions, err := inputAudioFunctions(arguments.Input, arguments)
+	if err != nil {
+		errMsg := fmt.Sprintf("Invalid audio change of input %s, nothing was sent to vMix: %v", arguments.Input, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	what := fmt.Sprintf("input %s", arguments.Input)
+	if err := m.sendAudioFunctions(ctx, target, what, functions); err != nil {
+		return nil, err
+	}
+	return m.audioStatusResponse(ctx, target, arguments.Input, describeAudioFunctions(functions))
+}
+
+// AudioBusVMix implements MCPvMix.
+func (m *mcpVmix) AudioBusVMix(ctx context.Context, arguments AudioBusArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	functions, err := busAudioFunctions(arguments)
+	if err != nil {
+		errMsg := fmt.Sprintf("Invalid audio change of bus %s, nothing was sent to vMix: %v", arguments.Bus, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	what := fmt.Sprintf("bus %s", arguments.Bus)
+	if err := m.sendAudioFunctions(ctx, target, what, functions); err != nil {
+		return nil, err
+	}
+	return m.audioStatusResponse(ctx, target, "-", describeAudioFunctions(functions))
+}
+
+// AudioStatus implements MCPvMix.
+func (m *mcpVmix) AudioStatus(ctx context.Context, arguments AudioStatusArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+	return m.audioStatusResponse(ctx, target, arguments.Input, "")
+}
+
+func describeAudioFunctions(functions []audioFunction) string {
+	lines := lo.Map(functions, func(f audioFunction, _ int) string {
+		if v, ok := f.params["Value"]; ok {
+			return fmt.Sprintf("Sent %s %s", f.function, v)
+		}
+		return "Sent " + f.function
+	})
+	return strings.Join(lines, "\n")
+}
+
+// audioStatusResponse reads the latest state and reports master, buses and inputs with audio.
+// input limits the inputs reported. "-" reports no inputs.
+func (m *mcpVmix) audioStatusResponse(ctx context.Context, target vmixTarget, input, header string) (*mcp_golang.ToolResponse, error) {
+	state, err := m.pool.refresh(target)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	var lines []string
+	if header != "" {
+		lines = append(lines, header)
+	}
+	for _, bus := range state.Audio.buses() {
+		line := fmt.Sprintf("%s: volume %g, %s, meters %s", lo.Ternary(bus.Name == "Master", "Master", "Bus "+bus.Name), bus.Volume, lo.Ternary(bus.Muted, "muted", "on"), formatMeters(bus.MeterF1, bus.MeterF2))
+		if bus.Solo != nil && *bus.Solo {
+			line += ", solo"
+		}
+		if bus.SendToMaster != nil {
+			line += lo.Ternary(*bus.SendToMaster, ", sent to master", ", not sent to master")
+		}
+		if bus.HeadphonesVolume != nil {
+			line += fmt.Sprintf(", headphones %g", *bus.HeadphonesVolume)
+		}
+		lines = append(lines, line)
+	}
+
+	if input != "-" {
+		inputs := state.Inputs
+		if input != "" {
+			in, ok := state.input(input)
+			if !ok {
+				errMsg := fmt.Sprintf("Input %s not found", input)
+				m.log(ctx).Error(errMsg, "instance", target)
+				return nil, fmt.Errorf(errMsg)
+			}
+			inputs = []stateInput{*in}
+		}
+		for _, in := range inputs {
+			if in.Volume == nil {
+				continue
+			}
+			line := fmt.Sprintf("Input %d %s (key %s): volume %g, %s, buses %s, gain %g dB, balance %g, meters %s",
+				in.Number, strings.TrimSpace(in.Title), in.Key, *in.Volume, lo.Ternary(lo.FromPtr(in.Muted), "muted", "on"),
+				lo.Ternary(lo.FromPtr(in.AudioBusses) == "", "none", lo.FromPtr(in.AudioBusses)), lo.FromPtr(in.GainDb), lo.FromPtr(in.Balance),
+				formatMeters(lo.FromPtr(in.MeterF1), lo.FromPtr(in.MeterF2)))
+			if lo.FromPtr(in.Solo) {
+				line += ", solo"
+			}
+			lines = append(lines, line)
+		}
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(strings.Join(lines, "\n"))), nil
+}
@@ -0,0 +1,187 @@
+package mcpvmix
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/samber/lo"
+)
+
+func TestInputAudioFunctions(t *testing.T) {
+	cases := []struct {
+		name      string
+		arguments AudioInputArguments
+		want      []audioFunction
+		wantErr   string
+	}{
+		{
+			name:      "volume",
+			arguments: AudioInputArguments{Volume: lo.ToPtr(80)},
+			want:      []audioFunction{{"SetVolume", map[string]string{"Input": "3", "Value": "80"}}},
+		},
+		{
+			name:      "volume bounds",
+			arguments: AudioInputArguments{Volume: lo.ToPtr(0), Gain: lo.ToPtr(24), Balance: lo.ToPtr(-1.0)},
+			want: []audioFunction{
+				{"SetVolume", map[string]string{"Input": "3", "Value": "0"}},
+				{"SetGain", map[string]string{"Input": "3", "Value": "24"}},
+				{"SetBalance", map[string]string{"Input": "3", "Value": "-1"}},
+			},
+		},
+		{
+			name:      "volume fade",
+			arguments: AudioInputArguments{Volume: lo.ToPtr(50), FadeMs: 2000},
+			want:      []audioFunction{{"SetVolumeFade", map[string]string{"Input": "3", "Value": "50,2000"}}},
+		},
+		{
+			name:      "mute, solo and audio follows video",
+			arguments: AudioInputArguments{Muted: lo.ToPtr(true), Solo: lo.ToPtr(false), AudioAuto: lo.ToPtr(true)},
+			want: []audioFunction{
+				{"AudioOff", map[string]string{"Input": "3"}},
+				{"SoloOff", map[string]string{"Input": "3"}},
+				{"AudioAutoOn", map[string]string{"Input": "3"}},
+			},
+		},
+		{
+			name:      "unmute",
+			arguments: AudioInputArguments{Muted: lo.ToPtr(false)},
+			want:      []audioFunction{{"AudioOn", map[string]string{"Input": "3"}}},
+		},
+		{
+			name:      "buses",
+			arguments: AudioInputArguments{BusesOn: []string{"master", " a "}, BusesOff: []string{"G"}},
+			want: []audioFunction{
+				{"AudioBusOn", map[string]string{"Input": "3", "Value": "M"}},
+				{"AudioBusOn", map[string]string{"Input": "3", "Value": "A"}},
+				{"AudioBusOff", map[string]string{"Input": "3", "Value": "G"}},
+			},
+		},
+		{
+			name:      "fractional balance",
+			arguments: AudioInputArguments{Balance: lo.ToPtr(0.25)},
+			want:      []audioFunction{{"SetBalance", map[string]string{"Input": "3", "Value": "0.25"}}},
+		},
+		{name: "volume over 100", arguments: AudioInputArguments{Volume: lo.ToPtr(101)}, wantErr: "volume must be 0~100: 101"},
+		{name: "negative volume", arguments: AudioInputArguments{Volume: lo.ToPtr(-1)}, wantErr: "volume must be 0~100: -1"},
+		{name: "fade without volume", arguments: AudioInputArguments{FadeMs: 500}, wantErr: "fadeMs needs volume"},
+		{name: "gain over 24", arguments: AudioInputArguments{Gain: lo.ToPtr(25)}, wantErr: "gain must be 0~24: 25"},
+		{name: "negative gain", arguments: AudioInputArguments{Gain: lo.ToPtr(-1)}, wantErr: "gain must be 0~24"},
+		{name: "balance over 1", arguments: AudioInputArguments{Balance: lo.ToPtr(1.5)}, wantErr: "balance must be -1~1: 1.5"},
+		{name: "unknown bus", arguments: AudioInputArguments{BusesOn: []string{"H"}}, wantErr: "unknown bus: H"},
+		{
+			name:      "every error at once",
+			arguments: AudioInputArguments{Volume: lo.ToPtr(200), BusesOff: []string{"X"}, Gain: lo.ToPtr(30)},
+			wantErr:   "volume must be 0~100: 200; unknown bus: X. Use M or A~G; gain must be 0~24: 30",
+		},
+		{name: "nothing", wantErr: "nothing to change"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := inputAudioFunctions("3", tc.arguments)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("inputAudioFunctions() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("inputAudioFunctions() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("inputAudioFunctions() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseBus(t *testing.T) {
+	cases := []struct {
+		bus    string
+		want   string
+		wantOK bool
+	}{
+		{bus: "M", want: "M", wantOK: true},
+		{bus: "Master", want: "M", wantOK: true},
+		{bus: "master", want: "M", wantOK: true},
+		{bus: "a", want: "A", wantOK: true},
+		{bus: " G ", want: "G", wantOK: true},
+		{bus: "H"},
+		{bus: "AB"},
+		{bus: ""},
+	}
+	for _, tc := range cases {
+		got, ok := parseBus(tc.bus)
+		if ok != tc.wantOK || (ok && got != tc.want) {
+			t.Errorf("parseBus(%q) = %s, %v, want %s, %v", tc.bus, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestBusAudioFunctions(t *testing.T) {
+	cases := []struct {
+		name      string
+		arguments AudioBusArguments
+		want      []audioFunction
+		wantErr   string
+	}{
+		{
+			name:      "master",
+			arguments: AudioBusArguments{Bus: "Master", Volume: lo.ToPtr(90), Muted: lo.ToPtr(false)},
+			want: []audioFunction{
+				{"SetMasterVolume", map[string]string{"Value": "90"}},
+				{"MasterAudioOn", map[string]string{}},
+			},
+		},
+		{
+			name:      "sub bus",
+			arguments: AudioBusArguments{Bus: "b", Volume: lo.ToPtr(40), Muted: lo.ToPtr(true), Solo: lo.ToPtr(true), SendToMaster: lo.ToPtr(false)},
+			want: []audioFunction{
+				{"SetBusBVolume", map[string]string{"Value": "40"}},
+				{"BusXAudioOff", map[string]string{"Value": "B"}},
+				{"BusXSoloOn", map[string]string{"Value": "B"}},
+				{"BusXSendToMasterOff", map[string]string{"Value": "B"}},
+			},
+		},
+		{name: "unknown bus", arguments: AudioBusArguments{Bus: "Z", Volume: lo.ToPtr(40)}, wantErr: "unknown bus: Z"},
+		{name: "volume out of range", arguments: AudioBusArguments{Bus: "A", Volume: lo.ToPtr(101)}, wantErr: "volume must be 0~100"},
+		{name: "solo on master", arguments: AudioBusArguments{Bus: "M", Solo: lo.ToPtr(true)}, wantErr: "master has no solo"},
+		{name: "master to master", arguments: AudioBusArguments{Bus: "Master", SendToMaster: lo.ToPtr(true)}, wantErr: "master cannot be sent to master"},
+		{name: "nothing", arguments: AudioBusArguments{Bus: "A"}, wantErr: "nothing to change"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := busAudioFunctions(tc.arguments)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("busAudioFunctions() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("busAudioFunctions() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("busAudioFunctions() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMeterDB(t *testing.T) {
+	cases := []struct {
+		meter float64
+		want  float64
+	}{
+		{meter: 1, want: 0},
+		{meter: 0.1, want: -20},
+		{meter: 0.01, want: -40},
+		{meter: 0, want: math.Inf(-1)},
+	}
+	for _, tc := range cases {
+		if got := meterDB(tc.meter); math.Abs(got-tc.want) > 1e-9 && got != tc.want {
+			t.Errorf("meterDB(%g) = %g, want %g", tc.meter, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/samber/lo"
+
+	"github.com/FlowingSPDG/mcp-vmix/config"
+)
+
+const (
+	// maxAlertHistory is how many ended alerts are kept per instance.
+	maxAlertHistory = 50
+	// defaultAlertHistory is how many ended alerts vmix_audio_alerts returns by default.
+	defaultAlertHistory = 10
+	// minLevelDB is reported instead of levels below it, including digital silence (-inf), so alerts can be encoded as JSON.
+	minLevelDB = -100
+	// maxSampleBackoff is the longest wait between samples of an instance which does not respond.
+	maxSampleBackoff = 30 * time.Second
+)
+
+const (
+	alertSilence  = "silence"
+	alertClipping = "clipping"
+)
+
+// audioAlert is sustained silence or clipping of an input, bus or master.
+type audioAlert struct {
+	Kind    string     `json:"kind"`   // silence or clipping
+	Source  string     `json:"source"` // Master, Bus A~G or Input N title
+	Key     string     `json:"key,omitempty"`
+	Since   time.Time  `json:"since"`
+	Ended   *time.Time `json:"ended,omitempty"`
+	LevelDB float64    `json:"levelDb"` // the latest peak level of the left/right meters
+}
+
+// audioSource is a meter sampled by the monitor.
+type audioSource struct {
+	id      string // input key, or master / bus letter
+	name    string
+	key     string
+	peak    float64
+	silence bool // whether silence of the source is a problem
+	clip    bool // whether clipping of the source is a problem
+}
+
+// audioMonitor samples the audio meters of an instance and raises alerts for sustained silence or clipping.
+type audioMonitor struct {
+	m      *mcpVmix
+	target vmixTarget
+	cfg    config.AudioMonitor
+
+	mu        sync.Mutex
+	since     map[string]time.Time   // by kind/source id. when the source started to be silent or clipping
+	active    map[string]*audioAlert // by kind/source id
+	history   []audioAlert           // ended alerts, oldest first
+	sampledAt time.Time
+	err       error // error of the last sample
+	onChange  func()
+}
+
+func newAudioMonitor(m *mcpVmix, target vmixTarget, cfg config.AudioMonitor) *audioMonitor {
+	return &audioMonitor{
+		m:      m,
+		target: target,
+		cfg:    cfg,
+		since:  map[string]time.Time{},
+		active: map[string]*audioAlert{},
+	}
+}
+
+// setOnChange sets the function called after alerts are raised or ended.
+func (a *audioMonitor) setOnChange(f func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onChange = f
+}
+
+// run samples the meters until ctx is done.
+// While the instance does not respond the wait doubles up to maxSampleBackoff, so unreachable instances are not polled at the sample interval.
+func (a *audioMonitor) run(ctx context.Context) {
+	interval := time.Duration(a.cfg.IntervalMs) * time.Millisecond
+	wait := interval
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-timer.C:
+			if a.sample(now) {
+				wait = interval
+			} else {
+				wait = min(wait*2, max(maxSampleBackoff, interval))
+			}
+			timer.Reset(wait)
+		}
+	}
+}
+
+// sample reads the meters once and updates the alerts. It reports whether the meters could be read.
+func (a *audioMonitor) sample(now time.Time) bool {
+	state, err := a.m.pool.poll(a.target)
+
+	a.mu.Lock()
+	a.err = err
+	if err != nil {
+		a.mu.Unlock()
+		a.m.logger.Debug("Failed to sample audio meters", "instance", a.target, "error", err)
+		return false
+	}
+	a.sampledAt = now
+
+	changed := false
+	seen := map[string]struct{}{}
+	for _, src := range state.audioSources() {
+		level := max(meterDB(src.peak), minLevelDB)
+		silenceFor := time.Duration(a.cfg.SilenceSeconds * float64(time.Second))
+		clipFor := time.Duration(max(a.cfg.ClipSeconds, 0) * float64(time.Second))
+		if a.track(now, alertSilence, src, level, src.silence && level < a.cfg.SilenceThresholdDB, silenceFor) {
+			changed = true
+		}
+		if a.track(now, alertClipping, src, level, src.clip && level >= a.cfg.ClipThresholdDB, clipFor) {
+			changed = true
+		}
+		seen[src.id] = struct{}{}
+	}
+	// 削除またはミュートされたソースのアラートを終了する
+	for id := range a.active {
+		if _, srcID, _ := strings.Cut(id, "/"); !lo.HasKey(seen, srcID) {
+			a.end(now, id)
+			changed = true
+		}
+	}
+	for id := range a.since {
+		if _, srcID, _ := strings.Cut(id, "/"); !lo.HasKey(seen, srcID) {
+			delete(a.since, id)
+		}
+	}
+	onChange := a.onChange
+	a.mu.Unlock()
+
+	if changed && onChange != nil {
+		onChange()
+	}
+	return true
+}
+
+// track updates the alert of kind for src. a.mu must be held. It reports whether an alert was raised or ended.
+func (a *audioMonitor) track(now time.Time, kind string, src audioSource, level float64, bad bool, after time.Duration) bool {
+	id := kind + "/" + src.id
+	alert, active := a.active[id]
+	if !bad {
+		delete(a.since, id)
+		if active {
+			a.end(now, id)
+			return true
+		}
+		return false
+	}
+
+	since, ok := a.since[id]
+	if !ok {
+		since = now
+		a.since[id] = now
+	}
+	if active {
+		alert.LevelDB = level
+		return false
+	}
+	if now.Sub(since) < after {
+		return false
+	}
+	a.active[id] = &audioAlert{Kind: kind, Source: src.name, Key: src.key, Since: since, LevelDB: level}
+	a.m.logger.Warn(fmt.Sprintf("Audio alert: %s is %s", src.name, a.describeKind(kind)), "instance", a.target, "kind", kind, "source", src.name, "level", formatDB(level))
+	return true
+}
+
+// end moves the active alert id to the history. a.mu must be held.
+func (a *audioMonitor) end(now time.Time, id string) {
+	alert := a.active[id]
+	delete(a.active, id)
+	alert.Ended = &now
+	a.history = append(a.history, *alert)
+	if len(a.history) > maxAlertHistory {
+		a.history = a.history[len(a.history)-maxAlertHistory:]
+	}
+	a.m.logger.Info("Audio alert ended", "instance", a.target, "kind", alert.Kind, "source", alert.Source, "duration", now.Sub(alert.Since).Round(time.Second))
+}
+
+// describeKind explains the alert with the thresholds, e.g. "silent (below -60 dBFS for 10s)".
+func (a *audioMonitor) describeKind(kind string) string {
+	if kind == alertSilence {
+		return fmt.Sprintf("silent (below %g dBFS for %gs)", a.cfg.SilenceThresholdDB, a.cfg.SilenceSeconds)
+	}
+	if a.cfg.ClipSeconds <= 0 {
+		return fmt.Sprintf("clipping (%g dBFS or above)", a.cfg.ClipThresholdDB)
+	}
+	return fmt.Sprintf("clipping (%g dBFS or above for %gs)", a.cfg.ClipThresholdDB, a.cfg.ClipSeconds)
+}
+
+// alerts returns the active alerts and up to limit latest ended alerts, newest first.
+func (a *audioMonitor) alerts(limit int) (active, ended []audioAlert, sampledAt time.Time, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, alert := range a.active {
+		active = append(active, *alert)
+	}
+	slices.SortFunc(active, func(x, y audioAlert) int { return x.Since.Compare(y.Since) })
+	ended = slices.Clone(a.history)
+	slices.Reverse(ended)
+	if len(ended) > limit {
+		ended = ended[:limit]
+	}
+	return active, ended, a.sampledAt, a.err
+}
+
+// audioSources lists master, the buses and the inputs with audio.
+// Silence matters only for unmuted inputs with volume routed to a bus, and for buses such an input is routed to.
+// Inputs which are muted because audio follows video is on and they are off air are therefore ignored.
+func (s *vmixState) audioSources() []audioSource {
+	var sources []audioSource
+	used := map[string]bool{}
+	for _, in := range s.Inputs {
+		if in.Volume == nil || lo.FromPtr(in.Muted) {
+			continue
+		}
+		buses := lo.Filter(strings.Split(lo.FromPtr(in.AudioBusses), ","), func(b string, _ int) bool { return b != "" })
+		live := *in.Volume > 0 && len(buses) > 0
+		if live {
+			for _, b := range buses {
+				used[b] = true
+			}
+		}
+		sources = append(sources, audioSource{
+			id:      in.Key,
+			name:    fmt.Sprintf("Input %d %s", in.Number, strings.TrimSpace(in.Title)),
+			key:     in.Key,
+			peak:    max(lo.FromPtr(in.MeterF1), lo.FromPtr(in.MeterF2)),
+			silence: live,
+			clip:    true,
+		})
+	}
+	for _, bus := range s.Audio.buses() {
+		if bus.Muted {
+			continue
+		}
+		letter := lo.Ternary(bus.Name == "Master", "M", bus.Name)
+		sources = append(sources, audioSource{
+			id:      "bus/" + letter,
+			name:    lo.Ternary(bus.Name == "Master", "Master", "Bus "+bus.Name),
+			peak:    max(bus.MeterF1, bus.MeterF2),
+			silence: used[letter],
+			clip:    true,
+		})
+	}
+	return sources
+}
+
+// alertState returns "silent" or "clipping".
+func alertState(kind string) string {
+	return lo.Ternary(kind == alertSilence, "silent", "clipping")
+}
+
+func formatDB(db float64) string {
+	if db <= minLevelDB || math.IsInf(db, -1) {
+		return "-inf dBFS"
+	}
+	return fmt.Sprintf("%.1f dBFS", db)
+}
+
+// audioAlertsResource is the content of vmix://{instance}/audio/alerts.
+type audioAlertsResource struct {
+	Thresholds config.AudioMonitor `json:"thresholds"`
+	SampledAt  *time.Time          `json:"sampledAt,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	Active     []audioAlert        `json:"active"`
+	Ended      []audioAlert        `json:"ended"`
+}
+
+func (a *audioMonitor) resource() audioAlertsResource {
+	active, ended, sampledAt, err := a.alerts(maxAlertHistory)
+	res := audioAlertsResource{
+		Thresholds: a.cfg,
+		Active:     lo.Ternary(active == nil, []audioAlert{}, active),
+		Ended:      lo.Ternary(ended == nil, []audioAlert{}, ended),
+	}
+	if !sampledAt.IsZero() {
+		res.SampledAt = &sampledAt
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// audioMonitor returns the monitor of the target.
+func (m *mcpVmix) audioMonitor(target vmixTarget) (*audioMonitor, error) {
+	if m.audioMonitors == nil {
+		return nil, fmt.Errorf("the audio monitor is off. Start the server with -audio-monitor to enable it")
+	}
+	monitor, ok := m.audioMonitors[target.Name]
+	if !ok || monitor.target.Host != target.Host || monitor.target.Port != target.Port {
+		return nil, fmt.Errorf("audio is monitored only for the configured instances, %s is not one of them", target)
+	}
+	return monitor, nil
+}
+
+// AudioAlerts implements MCPvMix.
+func (m *mcpVmix) AudioAlerts(ctx context.Context, arguments AudioAlertsArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+	monitor, err := m.audioMonitor(target)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to get audio alerts: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	limit := arguments.History
+	if limit <= 0 {
+		limit = defaultAlertHistory
+	}
+	active, ended, sampledAt, sampleErr := monitor.alerts(limit)
+	now := time.Now()
+
+	lines := []string{fmt.Sprintf("Alerts when a source is %s or %s.", monitor.describeKind(alertSilence), monitor.describeKind(alertClipping))}
+	switch {
+	case sampleErr != nil:
+		lines = append(lines, fmt.Sprintf("The last sample failed: %v", sampleErr))
+	case sampledAt.IsZero():
+		lines = append(lines, "The meters have not been sampled yet.")
+	}
+	if len(active) == 0 {
+		lines = append(lines, "No active audio alerts.")
+	} else {
+		lines = append(lines, "Active:")
+		for _, alert := range active {
+			lines = append(lines, fmt.Sprintf("- %s is %s for %s (now %s, since %s)", alert.Source, alertState(alert.Kind), now.Sub(alert.Since).Round(time.Second), formatDB(alert.LevelDB), alert.Since.Format(time.TimeOnly)))
+		}
+	}
+	if len(ended) > 0 {
+		lines = append(lines, "Recently ended:")
+		for _, alert := range ended {
+			lines = append(lines, fmt.Sprintf("- %s was %s for %s (%s ~ %s)", alert.Source, alertState(alert.Kind), alert.Ended.Sub(alert.Since).Round(time.Second), alert.Since.Format(time.TimeOnly), alert.Ended.Format(time.TimeOnly)))
+		}
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(strings.Join(lines, "\n"))), nil
+}
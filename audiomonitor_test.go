@@ -0,0 +1,361 @@
+package mcpvmix
+
+import (
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/FlowingSPDG/mcp-vmix/config"
+	"github.com/FlowingSPDG/mcp-vmix/logger"
+)
+
+var monitorConfig = config.AudioMonitor{
+	Enabled:            true,
+	IntervalMs:         500,
+	SilenceThresholdDB: -60,
+	SilenceSeconds:     10,
+	ClipThresholdDB:    -1,
+	ClipSeconds:        2,
+}
+
+// Meter values of the levels used in the tests. 0.1 is -20 dBFS.
+const (
+	meterSilent   = 0.0
+	meterNormal   = 0.1
+	meterClipping = 1.0
+)
+
+func newTestAudioMonitor(t *testing.T, target vmixTarget, cfg config.AudioMonitor) *audioMonitor {
+	t.Helper()
+	log, _ := logger.New(logger.Options{})
+	m := &mcpVmix{logger: log, pool: newClientPool(defaultStateMaxAge, defaultIdleTTL)}
+	return newAudioMonitor(m, target, cfg)
+}
+
+func audioInput(number int, key string, volume float64, buses string, meter float64) stateInput {
+	return stateInput{
+		Number:      number,
+		Key:         key,
+		Title:       "Mic " + strconv.Itoa(number),
+		Volume:      lo.ToPtr(volume),
+		Muted:       lo.ToPtr(false),
+		AudioBusses: lo.ToPtr(buses),
+		MeterF1:     lo.ToPtr(meter),
+		MeterF2:     lo.ToPtr(meter / 2),
+	}
+}
+
+func TestAudioSources(t *testing.T) {
+	muted := audioInput(4, "k4", 100, "M", meterSilent)
+	muted.Muted = lo.ToPtr(true)
+	video := stateInput{Number: 5, Key: "k5", Title: "Colour"}
+	state := &vmixState{
+		Inputs: []stateInput{
+			audioInput(1, "k1", 100, "M,A", meterNormal),
+			audioInput(2, "k2", 0, "M", meterSilent),
+			audioInput(3, "k3", 100, "", meterSilent),
+			muted,
+			video,
+		},
+		Audio: stateAudio{
+			Master: &stateBus{MeterF1: 0.2, MeterF2: 0.3},
+			BusA:   &stateBus{},
+			BusB:   &stateBus{},
+			BusC:   &stateBus{Muted: true},
+		},
+	}
+
+	type source struct {
+		id      string
+		name    string
+		peak    float64
+		silence bool
+	}
+	want := []source{
+		{id: "k1", name: "Input 1 Mic 1", peak: meterNormal, silence: true},
+		// 音量0やバスに送られていない入力の無音は問題にしない
+		{id: "k2", name: "Input 2 Mic 2", peak: meterSilent},
+		{id: "k3", name: "Input 3 Mic 3", peak: meterSilent},
+		{id: "bus/M", name: "Master", peak: 0.3, silence: true},
+		{id: "bus/A", name: "Bus A", silence: true},
+		// ライブの入力が送られていないバスの無音は問題にしない
+		{id: "bus/B", name: "Bus B"},
+	}
+	got := state.audioSources()
+	if len(got) != len(want) {
+		t.Fatalf("audioSources() = %+v, want %d sources", got, len(want))
+	}
+	for i, src := range got {
+		w := want[i]
+		if src.id != w.id || src.name != w.name || src.peak != w.peak || src.silence != w.silence || !src.clip {
+			t.Errorf("source %d = %+v, want %+v with clip", i, src, w)
+		}
+	}
+}
+
+func TestAudioMonitorTrack(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	src := audioSource{id: "k1", name: "Input 1 Mic 1", key: "k1"}
+
+	type step struct {
+		at      time.Duration // since start
+		level   float64
+		bad     bool
+		changed bool
+	}
+	cases := []struct {
+		name       string
+		after      time.Duration
+		steps      []step
+		wantActive bool
+		wantSince  time.Duration
+		wantLevel  float64
+		wantEnded  int
+	}{
+		{
+			name:  "raise after the duration",
+			after: 10 * time.Second,
+			steps: []step{
+				{at: 0, level: -80, bad: true},
+				{at: 5 * time.Second, level: -80, bad: true},
+				{at: 10 * time.Second, level: -75, bad: true, changed: true},
+			},
+			wantActive: true,
+			wantLevel:  -75,
+		},
+		{
+			name:  "hold updates the level",
+			after: 10 * time.Second,
+			steps: []step{
+				{at: 0, level: -80, bad: true},
+				{at: 10 * time.Second, level: -80, bad: true, changed: true},
+				{at: 20 * time.Second, level: -90, bad: true},
+			},
+			wantActive: true,
+			wantLevel:  -90,
+		},
+		{
+			name:  "end",
+			after: 10 * time.Second,
+			steps: []step{
+				{at: 0, level: -80, bad: true},
+				{at: 10 * time.Second, level: -80, bad: true, changed: true},
+				{at: 12 * time.Second, level: -20, changed: true},
+				{at: 13 * time.Second, level: -20},
+			},
+			wantEnded: 1,
+		},
+		{
+			name:  "recovery before the duration restarts the count",
+			after: 10 * time.Second,
+			steps: []step{
+				{at: 0, level: -80, bad: true},
+				{at: 8 * time.Second, level: -20},
+				{at: 9 * time.Second, level: -80, bad: true},
+				{at: 15 * time.Second, level: -80, bad: true},
+				{at: 19 * time.Second, level: -80, bad: true, changed: true},
+			},
+			wantActive: true,
+			wantSince:  9 * time.Second,
+			wantLevel:  -80,
+		},
+		{
+			name:  "no duration raises at the first sample",
+			after: 0,
+			steps: []step{
+				{at: 0, level: 0, bad: true, changed: true},
+			},
+			wantActive: true,
+			wantLevel:  0,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newTestAudioMonitor(t, vmixTarget{Name: "main"}, monitorConfig)
+			for i, s := range tc.steps {
+				if changed := a.track(start.Add(s.at), alertSilence, src, s.level, s.bad, tc.after); changed != s.changed {
+					t.Errorf("step %d: track() = %v, want %v", i, changed, s.changed)
+				}
+			}
+			alert, active := a.active[alertSilence+"/k1"]
+			if active != tc.wantActive {
+				t.Fatalf("active = %v, want %v", active, tc.wantActive)
+			}
+			if active {
+				if !alert.Since.Equal(start.Add(tc.wantSince)) || alert.LevelDB != tc.wantLevel || alert.Source != src.name || alert.Key != src.key {
+					t.Errorf("alert = %+v, want since %v and level %g", alert, start.Add(tc.wantSince), tc.wantLevel)
+				}
+			}
+			if len(a.history) != tc.wantEnded {
+				t.Errorf("history = %+v, want %d ended alerts", a.history, tc.wantEnded)
+			}
+			for _, ended := range a.history {
+				if ended.Ended == nil || ended.Ended.Before(ended.Since) {
+					t.Errorf("ended alert = %+v, want the end time", ended)
+				}
+			}
+		})
+	}
+}
+
+func TestAudioMonitorHistory(t *testing.T) {
+	a := newTestAudioMonitor(t, vmixTarget{Name: "main"}, monitorConfig)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < maxAlertHistory+10; i++ {
+		src := audioSource{id: strconv.Itoa(i), name: "Input " + strconv.Itoa(i)}
+		now := start.Add(time.Duration(i) * time.Second)
+		a.track(now, alertClipping, src, 0, true, 0)
+		a.track(now, alertClipping, src, -20, false, 0)
+	}
+	if len(a.history) != maxAlertHistory {
+		t.Fatalf("history has %d alerts, want %d", len(a.history), maxAlertHistory)
+	}
+	if a.history[0].Source != "Input 10" {
+		t.Errorf("oldest alert = %s, want Input 10", a.history[0].Source)
+	}
+
+	_, ended, _, _ := a.alerts(3)
+	got := lo.Map(ended, func(alert audioAlert, _ int) string { return alert.Source })
+	if strings.Join(got, ",") != "Input 59,Input 58,Input 57" {
+		t.Errorf("alerts(3) ended = %v, want the latest three, newest first", got)
+	}
+}
+
+// fakeMeters serves the XML state of an instance whose audio is changed by the test.
+type fakeMeters struct {
+	mu    sync.Mutex
+	state string
+}
+
+func (f *fakeMeters) set(inputs, audio string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state = "<vmix><version>27.0.0.49</version><edition>4K</edition><inputs>" + inputs + "</inputs><audio>" + audio + "</audio></vmix>"
+}
+
+func (f *fakeMeters) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Write([]byte(f.state))
+}
+
+func newFakeMeters(t *testing.T) (*fakeMeters, vmixTarget) {
+	t.Helper()
+	f := &fakeMeters{}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	p, _ := strconv.Atoi(port)
+	return f, vmixTarget{Name: "main", Host: host, Port: p}
+}
+
+const (
+	micLive   = `<input key="k1" number="1" type="Audio" title="Mic 1" volume="100" muted="False" audiobusses="M,A" meterF1="0" meterF2="0">Mic 1</input>`
+	micNormal = `<input key="k1" number="1" type="Audio" title="Mic 1" volume="100" muted="False" audiobusses="M,A" meterF1="0.1" meterF2="0.1">Mic 1</input>`
+	micMuted  = `<input key="k1" number="1" type="Audio" title="Mic 1" volume="100" muted="True" audiobusses="M,A" meterF1="0" meterF2="0">Mic 1</input>`
+	micOffBus = `<input key="k1" number="1" type="Audio" title="Mic 1" volume="100" muted="False" audiobusses="" meterF1="0.1" meterF2="0.1">Mic 1</input>`
+	musicClip = `<input key="k2" number="2" type="Audio" title="Music" volume="100" muted="False" audiobusses="M" meterF1="1" meterF2="0.5">Music</input>`
+
+	masterNormal = `<master volume="100" muted="False" meterF1="0.1" meterF2="0.1" />`
+	busASilent   = `<busA volume="100" muted="False" meterF1="0" meterF2="0" />`
+)
+
+func TestAudioMonitorSample(t *testing.T) {
+	meters, target := newFakeMeters(t)
+	a := newTestAudioMonitor(t, target, monitorConfig)
+	changes := 0
+	a.setOnChange(func() { changes++ })
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	type step struct {
+		at          time.Duration
+		inputs      string
+		audio       string
+		wantActive  []string // kind/source id
+		wantEnded   int
+		wantChanges int
+	}
+	steps := []step{
+		// Mic 1 はバスAに送られているため、Mic 1 とバスAの無音が数え始められる
+		{at: 0, inputs: micLive + musicClip, audio: masterNormal + busASilent},
+		{at: 2 * time.Second, inputs: micLive + musicClip, audio: masterNormal + busASilent, wantActive: []string{"clipping/k2"}, wantChanges: 1},
+		{at: 10 * time.Second, inputs: micLive + musicClip, audio: masterNormal + busASilent, wantActive: []string{"clipping/k2", "silence/bus/A", "silence/k1"}, wantChanges: 2},
+		// 保持している間は変化を通知しない
+		{at: 11 * time.Second, inputs: micLive + musicClip, audio: masterNormal + busASilent, wantActive: []string{"clipping/k2", "silence/bus/A", "silence/k1"}, wantChanges: 2},
+		// Music が削除され、Mic 1 がミュートされるとそれぞれのアラートが終わる。バスAに送るライブの入力もなくなる
+		{at: 12 * time.Second, inputs: micMuted, audio: masterNormal + busASilent, wantEnded: 3, wantChanges: 3},
+		// バスから外れた入力は無音でもアラートにならない
+		{at: 13 * time.Second, inputs: micOffBus, audio: masterNormal + busASilent, wantEnded: 3, wantChanges: 3},
+		{at: 30 * time.Second, inputs: micOffBus, audio: masterNormal + busASilent, wantEnded: 3, wantChanges: 3},
+		{at: 31 * time.Second, inputs: micNormal, audio: masterNormal, wantEnded: 3, wantChanges: 3},
+	}
+	for i, s := range steps {
+		meters.set(s.inputs, s.audio)
+		if !a.sample(start.Add(s.at)) {
+			t.Fatalf("step %d: sample() failed: %v", i, a.err)
+		}
+		active := slices.Sorted(maps.Keys(a.active))
+		if strings.Join(active, ",") != strings.Join(s.wantActive, ",") {
+			t.Errorf("step %d: active = %v, want %v", i, active, s.wantActive)
+		}
+		if len(a.history) != s.wantEnded {
+			t.Errorf("step %d: %d ended alerts, want %d", i, len(a.history), s.wantEnded)
+		}
+		if changes != s.wantChanges {
+			t.Errorf("step %d: onChange called %d times, want %d", i, changes, s.wantChanges)
+		}
+	}
+	if !a.sampledAt.Equal(start.Add(31 * time.Second)) {
+		t.Errorf("sampledAt = %v, want the last sample", a.sampledAt)
+	}
+}
+
+func TestAudioMonitorClipSeconds(t *testing.T) {
+	cases := []struct {
+		name        string
+		clipSeconds float64
+		want        bool
+	}{
+		{name: "first sample", clipSeconds: -1, want: true},
+		{name: "sustained", clipSeconds: 2, want: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meters, target := newFakeMeters(t)
+			cfg := monitorConfig
+			cfg.ClipSeconds = tc.clipSeconds
+			a := newTestAudioMonitor(t, target, cfg)
+			meters.set(musicClip, masterNormal)
+			if !a.sample(time.Now()) {
+				t.Fatalf("sample() failed: %v", a.err)
+			}
+			if _, ok := a.active["clipping/k2"]; ok != tc.want {
+				t.Errorf("clipping alert after the first sample = %v, want %v", ok, tc.want)
+			}
+		})
+	}
+}
+
+func TestAudioMonitorSampleError(t *testing.T) {
+	// Nothing listens on port 1.
+	a := newTestAudioMonitor(t, vmixTarget{Name: "main", Host: "127.0.0.1", Port: 1}, monitorConfig)
+	a.setOnChange(func() { t.Error("onChange called after a failed sample") })
+	if a.sample(time.Now()) {
+		t.Fatal("sample() of an unreachable instance succeeded")
+	}
+	if _, _, sampledAt, err := a.alerts(defaultAlertHistory); err == nil || !sampledAt.IsZero() {
+		t.Errorf("alerts() sampledAt = %v, error = %v, want the error and no sample", sampledAt, err)
+	}
+	if _, ok := a.m.pool.clients[poolKey(a.target)]; !ok {
+		t.Error("a failed sample evicted the client")
+	}
+}
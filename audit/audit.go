@@ -0,0 +1,153 @@
+// Package audit writes an append-only JSON lines log of tool calls and the vMix functions they sent,
+// so what a model did during a show can be reviewed and replayed.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// GetAuditFilePath は%appdata%/RSLT/vmix-mcp/audit.jsonl のパスを返します
+func GetAuditFilePath() (string, error) {
+	appData, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(appData, "RSLT", "vmix-mcp", "audit.jsonl"), nil
+}
+
+// FunctionCall is a vMix function sent while a tool was running.
+type FunctionCall struct {
+	Time      time.Time         `json:"time"`
+	Function  string            `json:"function"`
+	Query     map[string]string `json:"query,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Host      string            `json:"host"`
+	Port      int               `json:"port"`
+	Transport string            `json:"transport"` // http or tcp
+	Error     string            `json:"error,omitempty"`
+	LatencyMs float64           `json:"latencyMs"`
+}
+
+// Record is a line of the audit log. One record is written per tool call.
+type Record struct {
+	Time      time.Time       `json:"time"`
+	Run       string          `json:"run"`               // changes every time the server starts
+	Session   string          `json:"session,omitempty"` // MCP session of the http transport
+	Tool      string          `json:"tool"`
+	Class     string          `json:"class,omitempty"` // safety class of the tool, e.g. program-affecting
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Functions []FunctionCall  `json:"functions,omitempty"`
+	Result    string          `json:"result"` // ok or error
+	Error     string          `json:"error,omitempty"`
+	Response  string          `json:"response,omitempty"` // text of the response, truncated
+	LatencyMs float64         `json:"latencyMs"`
+}
+
+const (
+	ResultOK    = "ok"
+	ResultError = "error"
+)
+
+// Log is an append-only audit log file.
+type Log struct {
+	mu  sync.Mutex
+	w   io.WriteCloser
+	run string
+
+	// Session returns the MCP session of a tool call, if any.
+	Session func(ctx context.Context) string
+}
+
+// Open opens the audit log at path for appending, creating the directory if needed.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return New(f), nil
+}
+
+// New creates an audit log writing to w.
+func New(w io.WriteCloser) *Log {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return &Log{w: w, run: hex.EncodeToString(b)}
+}
+
+// Run returns the ID of this server run written to every record.
+func (l *Log) Run() string {
+	return l.run
+}
+
+// Write appends a record as a single line.
+func (l *Log) Write(rec Record) error {
+	if rec.Run == "" {
+		rec.Run = l.run
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	b = append(b, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(b); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Close()
+}
+
+// maxLineSize is the longest record Read accepts.
+const maxLineSize = 16 * 1024 * 1024
+
+// Read reads every record of an audit log.
+func Read(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("invalid audit record at line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return records, nil
+}
+
+// ReadFile reads every record of the audit log at path.
+func ReadFile(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+	return Read(f)
+}
@@ -0,0 +1,115 @@
+package audit_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FlowingSPDG/mcp-vmix/audit"
+)
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestRead(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		wantTools []string
+		wantErr   string
+	}{
+		{
+			name:      "records",
+			input:     `{"tool":"vmix_cut","result":"ok"}` + "\n" + `{"tool":"vmix_fade","result":"error"}` + "\n",
+			wantTools: []string{"vmix_cut", "vmix_fade"},
+		},
+		{
+			name:      "blank lines and no trailing newline",
+			input:     "\n" + `{"tool":"vmix_cut"}` + "\n\n" + `{"tool":"vmix_fade"}`,
+			wantTools: []string{"vmix_cut", "vmix_fade"},
+		},
+		{
+			name:  "empty",
+			input: "",
+		},
+		{
+			name:    "truncated last line",
+			input:   `{"tool":"vmix_cut"}` + "\n" + `{"tool":"vmix_fa`,
+			wantErr: "line 2",
+		},
+		{
+			name:    "malformed line",
+			input:   `{"tool":"vmix_cut"}` + "\n" + `not json` + "\n" + `{"tool":"vmix_fade"}` + "\n",
+			wantErr: "line 2",
+		},
+		{
+			name:    "wrong field type",
+			input:   "\n" + `{"tool":"vmix_cut","functions":"Cut"}` + "\n",
+			wantErr: "line 2",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			records, err := audit.Read(strings.NewReader(tc.input))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Read() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			var tools []string
+			for _, rec := range records {
+				tools = append(tools, rec.Tool)
+			}
+			if strings.Join(tools, ",") != strings.Join(tc.wantTools, ",") {
+				t.Errorf("tools = %v, want %v", tools, tc.wantTools)
+			}
+		})
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	var buf bytes.Buffer
+	l := audit.New(nopCloser{&buf})
+	want := audit.Record{
+		Time:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Tool:      "vmix_cut",
+		Arguments: json.RawMessage(`{"mix":2}`),
+		Functions: []audit.FunctionCall{{Function: "Cut", Query: map[string]string{"Mix": "1"}, Instance: "main", Host: "127.0.0.1", Port: 8088, Transport: "http"}},
+		Result:    audit.ResultOK,
+	}
+	if err := l.Write(want); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := l.Write(audit.Record{Run: "other", Tool: "vmix_fade"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Fatalf("wrote %d lines, want 2:\n%s", n, buf.String())
+	}
+
+	records, err := audit.Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("read %d records, want 2", len(records))
+	}
+	got := records[0]
+	if got.Run != l.Run() || got.Tool != want.Tool || !got.Time.Equal(want.Time) || string(got.Arguments) != string(want.Arguments) {
+		t.Errorf("record = %+v, want %+v with run %s", got, want, l.Run())
+	}
+	if len(got.Functions) != 1 || got.Functions[0].Function != "Cut" || got.Functions[0].Query["Mix"] != "1" {
+		t.Errorf("functions = %+v, want %+v", got.Functions, want.Functions)
+	}
+	if records[1].Run != "other" {
+		t.Errorf("run = %s, want the run of the record", records[1].Run)
+	}
+}
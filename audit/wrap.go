@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// maxResponseLength is how much of the response text is kept in a record.
+const maxResponseLength = 1024
+
+type callKey struct{}
+
+// call collects the functions sent during a tool call.
+type call struct {
+	mu        sync.Mutex
+	functions []FunctionCall
+}
+
+func withCall(ctx context.Context) (context.Context, *call) {
+	c := &call{}
+	return context.WithValue(ctx, callKey{}, c), c
+}
+
+// RecordFunction adds a vMix function to the record of the tool call running in ctx.
+// It does nothing when ctx does not belong to an audited tool call.
+func RecordFunction(ctx context.Context, fc FunctionCall) {
+	c, ok := ctx.Value(callKey{}).(*call)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.functions = append(c.functions, fc)
+}
+
+func (c *call) recorded() []FunctionCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	functions := append([]FunctionCall(nil), c.functions...)
+	// 並行して送られた関数は完了順に追加されるため、送信順に並べ直す
+	sort.SliceStable(functions, func(i, j int) bool { return functions[i].Time.Before(functions[j].Time) })
+	return functions
+}
+
+var (
+	contextType  = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	responseType = reflect.TypeOf((*mcp_golang.ToolResponse)(nil))
+)
+
+// Wrap wraps an mcp-golang tool handler so every call is written to the log.
+// The returned handler has the same type as handler, so the input schema of the tool does not change.
+// Functions are recorded only when the handler takes a context.Context and passes it to RecordFunction.
+// class is the safety class of the tool, so a replay can apply the safe mode to the recorded functions.
+func (l *Log) Wrap(tool, class string, handler any) (any, error) {
+	v := reflect.ValueOf(handler)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumOut() != 2 || t.Out(1) != errorType || t.NumIn() < 1 {
+		return nil, fmt.Errorf("handler of %s must be a function returning (response, error)", tool)
+	}
+	hasContext := t.NumIn() == 2 && t.In(0) == contextType
+
+	return reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		ctx := context.Background()
+		if hasContext {
+			ctx = args[0].Interface().(context.Context)
+		}
+		ctx, c := withCall(ctx)
+		if hasContext {
+			args[0] = reflect.ValueOf(ctx)
+		}
+
+		start := time.Now()
+		out := v.Call(args)
+		rec := Record{
+			Time:      start,
+			Tool:      tool,
+			Class:     class,
+			Functions: c.recorded(),
+			Result:    ResultOK,
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if l.Session != nil {
+			rec.Session = l.Session(ctx)
+		}
+		if b, err := json.Marshal(args[len(args)-1].Interface()); err == nil {
+			rec.Arguments = b
+		}
+		if err, _ := out[1].Interface().(error); err != nil {
+			rec.Result = ResultError
+			rec.Error = err.Error()
+		} else if t.Out(0) == responseType {
+			rec.Response = responseText(out[0].Interface().(*mcp_golang.ToolResponse))
+		}
+
+		if err := l.Write(rec); err != nil {
+			// 監査ログの失敗でツール呼び出しを失敗させない
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		return out
+	}).Interface(), nil
+}
+
+// responseText returns the text contents of a response. Images are replaced with a placeholder.
+func responseText(res *mcp_golang.ToolResponse) string {
+	if res == nil {
+		return ""
+	}
+	texts := make([]string, 0, len(res.Content))
+	for _, content := range res.Content {
+		switch {
+		case content.TextContent != nil:
+			texts = append(texts, content.TextContent.Text)
+		case content.ImageContent != nil:
+			texts = append(texts, fmt.Sprintf("[image %s]", content.ImageContent.MimeType))
+		}
+	}
+	text := strings.Join(texts, "\n")
+	if len(text) > maxResponseLength {
+		n := maxResponseLength
+		for n > 0 && !utf8.RuneStart(text[n]) {
+			n--
+		}
+		text = text[:n] + "..."
+	}
+	return text
+}
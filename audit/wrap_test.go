@@ -0,0 +1,126 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/FlowingSPDG/mcp-vmix/audit"
+)
+
+type cutArguments struct {
+	Instance string `json:"instance"`
+	Mix      int    `json:"mix"`
+}
+
+type sessionKey struct{}
+
+func TestWrap(t *testing.T) {
+	var buf bytes.Buffer
+	l := audit.New(nopCloser{&buf})
+	l.Session = func(ctx context.Context) string {
+		id, _ := ctx.Value(sessionKey{}).(string)
+		return id
+	}
+	start := time.Now()
+	handler := func(ctx context.Context, args cutArguments) (*mcp_golang.ToolResponse, error) {
+		if args.Mix == 0 {
+			return nil, errors.New("mix must be at least 1")
+		}
+		// 完了順に記録されても送信順に並べ直される
+		audit.RecordFunction(ctx, audit.FunctionCall{Time: start.Add(time.Millisecond), Function: "Fade", Instance: args.Instance})
+		audit.RecordFunction(ctx, audit.FunctionCall{Time: start, Function: "Cut", Instance: args.Instance})
+		return mcp_golang.NewToolResponse(
+			mcp_golang.NewTextContent("Cut"),
+			mcp_golang.NewImageContent("", "image/png"),
+		), nil
+	}
+
+	wrapped, err := l.Wrap("vmix_cut", "program-affecting", handler)
+	if err != nil {
+		t.Fatalf("Wrap() error = %v", err)
+	}
+	call, ok := wrapped.(func(context.Context, cutArguments) (*mcp_golang.ToolResponse, error))
+	if !ok {
+		t.Fatalf("Wrap() returned %T, want the type of the handler", wrapped)
+	}
+
+	ctx := context.WithValue(context.Background(), sessionKey{}, "session-1")
+	if _, err := call(ctx, cutArguments{Instance: "main", Mix: 2}); err != nil {
+		t.Fatalf("wrapped handler error = %v", err)
+	}
+	if _, err := call(ctx, cutArguments{Instance: "main"}); err == nil {
+		t.Fatal("wrapped handler did not return the error of the handler")
+	}
+	// 監査対象外のコンテキストでは何もしない
+	audit.RecordFunction(context.Background(), audit.FunctionCall{Function: "Cut"})
+
+	records, err := audit.Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("read %d records, want 2", len(records))
+	}
+
+	ok1 := records[0]
+	if ok1.Tool != "vmix_cut" || ok1.Class != "program-affecting" || ok1.Session != "session-1" || ok1.Run != l.Run() {
+		t.Errorf("record = %+v, want the tool, class, session and run", ok1)
+	}
+	if string(ok1.Arguments) != `{"instance":"main","mix":2}` {
+		t.Errorf("arguments = %s", ok1.Arguments)
+	}
+	if ok1.Result != audit.ResultOK || ok1.Response != "Cut\n[image image/png]" {
+		t.Errorf("result = %s, response = %q", ok1.Result, ok1.Response)
+	}
+	if len(ok1.Functions) != 2 || ok1.Functions[0].Function != "Cut" || ok1.Functions[1].Function != "Fade" {
+		t.Errorf("functions = %+v, want Cut then Fade", ok1.Functions)
+	}
+
+	failed := records[1]
+	if failed.Result != audit.ResultError || failed.Error != "mix must be at least 1" || len(failed.Functions) != 0 || failed.Response != "" {
+		t.Errorf("failed record = %+v", failed)
+	}
+}
+
+func TestWrapWithoutContext(t *testing.T) {
+	var buf bytes.Buffer
+	l := audit.New(nopCloser{&buf})
+	wrapped, err := l.Wrap("vmix_status", "read", func(args cutArguments) (*mcp_golang.ToolResponse, error) {
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(strings.Repeat("あ", 400))), nil
+	})
+	if err != nil {
+		t.Fatalf("Wrap() error = %v", err)
+	}
+	if _, err := wrapped.(func(cutArguments) (*mcp_golang.ToolResponse, error))(cutArguments{Instance: "main"}); err != nil {
+		t.Fatalf("wrapped handler error = %v", err)
+	}
+
+	records, err := audit.Read(&buf)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Read() = %d records, error = %v", len(records), err)
+	}
+	// 長い応答は文字の途中で切らずに省略する
+	if r := records[0].Response; len(r) > 1024+len("...") || !strings.HasSuffix(r, "あ...") {
+		t.Errorf("response of %d bytes = %q...", len(r), r[:12])
+	}
+}
+
+func TestWrapInvalidHandler(t *testing.T) {
+	l := audit.New(nopCloser{&bytes.Buffer{}})
+	for _, handler := range []any{
+		"not a function",
+		func() (*mcp_golang.ToolResponse, error) { return nil, nil },
+		func(cutArguments) *mcp_golang.ToolResponse { return nil },
+		func(cutArguments) (*mcp_golang.ToolResponse, string) { return nil, "" },
+	} {
+		if _, err := l.Wrap("vmix_cut", "read", handler); err == nil {
+			t.Errorf("Wrap(%T) error = nil", handler)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"runtime/debug"
+
+	"github.com/FlowingSPDG/mcp-vmix/config"
+	"gopkg.in/yaml.v3"
+)
+
+// version はビルド時に -ldflags "-X main.version=v1.2.3" で設定される
+var version = ""
+
+func getVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// settingFlag は config.Setting をコマンドラインフラグとして扱う
+type settingFlag struct {
+	setting config.Setting
+	value   string
+	set     bool
+}
+
+func (f *settingFlag) String() string { return f.value }
+
+func (f *settingFlag) Set(v string) error {
+	f.value = v
+	f.set = true
+	return nil
+}
+
+func (f *settingFlag) IsBoolFlag() bool { return f.setting.Bool }
+
+type options struct {
+	cfg         *config.Config
+	configPath  string
+	version     bool
+	printConfig bool
+
+	// command はサブコマンド (replay) 。空の場合はサーバーを起動する
+	command     string
+	commandArgs []string
+}
+
+// parseOptions はコマンドラインフラグ > 環境変数 > 設定ファイル > デフォルト値の優先順位で設定を読み込む
+func parseOptions(args []string, getenv func(string) string) (*options, error) {
+	fs := flag.NewFlagSet("mcp-vmix", flag.ContinueOnError)
+	configPath := fs.String("config", "", "config file path (env VMIX_MCP_CONFIG, default %AppData%/RSLT/vmix-mcp/config.yaml)")
+	showVersion := fs.Bool("version", false, "print the version and exit")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration and exit")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: mcp-vmix [flags]\n       mcp-vmix [flags] replay [replay flags] audit.jsonl\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	flags := make([]*settingFlag, len(config.Settings))
+	for i, s := range config.Settings {
+		flags[i] = &settingFlag{setting: s}
+		fs.Var(flags[i], s.Flag, fmt.Sprintf("%s (env %s)", s.Usage, s.Env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	opts := &options{version: *showVersion, printConfig: *printConfig}
+	if fs.NArg() > 0 {
+		switch fs.Arg(0) {
+		case "replay":
+			opts.command, opts.commandArgs = fs.Arg(0), fs.Args()[1:]
+		default:
+			return nil, fmt.Errorf("unexpected arguments: %v", fs.Args())
+		}
+	}
+	if opts.version {
+		return opts, nil
+	}
+
+	// 設定ファイルの読み込み
+	opts.configPath = *configPath
+	if opts.configPath == "" {
+		opts.configPath = getenv("VMIX_MCP_CONFIG")
+	}
+	if opts.configPath == "" {
+		p, err := config.GetConfigFilePath()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config file path: %w", err)
+		}
+		opts.configPath = p
+	}
+	cfg, err := config.Load(opts.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// 環境変数、フラグの順に上書きする
+	for _, s := range config.Settings {
+		if v := getenv(s.Env); v != "" {
+			if err := s.Set(cfg, v); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", s.Env, err)
+			}
+		}
+	}
+	for _, f := range flags {
+		if f.set {
+			if err := f.setting.Set(cfg, f.value); err != nil {
+				return nil, fmt.Errorf("invalid -%s: %w", f.setting.Flag, err)
+			}
+		}
+	}
+	if err := cfg.Normalize(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	opts.cfg = cfg
+	return opts, nil
+}
+
+// writeConfig は有効な設定をYAMLで書き出す。トークンとパスワードは伏せる
+func writeConfig(w io.Writer, cfg *config.Config) error {
+	masked := *cfg
+	if masked.Transport.Token != "" {
+		masked.Transport.Token = "********"
+	}
+	masked.Instances = make([]config.Instance, len(cfg.Instances))
+	for i, in := range cfg.Instances {
+		if in.Password != "" {
+			in.Password = "********"
+		}
+		masked.Instances[i] = in
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(masked); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	return enc.Close()
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = `mode: preview-only
+transport:
+  token: file-token
+instances:
+  - name: studio
+    host: 192.168.0.10
+    password: file-password
+  - name: backup
+    host: 192.168.0.11
+`
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseOptions(t *testing.T) {
+	cases := []struct {
+		name          string
+		config        string
+		args          []string
+		env           map[string]string
+		wantMode      string
+		wantToken     string
+		wantInstances []string
+		wantDefault   string
+	}{
+		{
+			name:          "config file",
+			config:        testConfig,
+			wantMode:      "preview-only",
+			wantToken:     "file-token",
+			wantInstances: []string{"studio", "backup"},
+			wantDefault:   "studio",
+		},
+		{
+			name:          "environment overrides the config file",
+			config:        testConfig,
+			env:           map[string]string{"VMIX_MCP_MODE": "full", "VMIX_MCP_TOKEN": "env-token", "VMIX_MCP_INSTANCES": "a=10.0.0.1:8088,b=10.0.0.2"},
+			wantMode:      "full",
+			wantToken:     "env-token",
+			wantInstances: []string{"a", "b"},
+			wantDefault:   "a",
+		},
+		{
+			name:          "flags override the environment",
+			config:        testConfig,
+			args:          []string{"-mode", "read-only", "-token", "flag-token", "-instances", "c=10.0.0.3", "-default-instance", "c"},
+			env:           map[string]string{"VMIX_MCP_MODE": "full", "VMIX_MCP_TOKEN": "env-token", "VMIX_MCP_INSTANCES": "a=10.0.0.1"},
+			wantMode:      "read-only",
+			wantToken:     "flag-token",
+			wantInstances: []string{"c"},
+			wantDefault:   "c",
+		},
+		{
+			name:          "default instance from the environment",
+			config:        testConfig,
+			env:           map[string]string{"VMIX_MCP_DEFAULT_INSTANCE": "backup"},
+			wantMode:      "preview-only",
+			wantToken:     "file-token",
+			wantInstances: []string{"studio", "backup"},
+			wantDefault:   "backup",
+		},
+		{
+			name:          "flag mode overrides readOnly of the config file",
+			config:        "readOnly: true\n",
+			args:          []string{"-mode", "full"},
+			wantMode:      "full",
+			wantInstances: []string{"default"},
+			wantDefault:   "default",
+		},
+		{
+			name:          "flag mode overrides read-only of the environment",
+			config:        "readOnly: true\n",
+			args:          []string{"-mode", "preview-only"},
+			env:           map[string]string{"VMIX_MCP_READ_ONLY": "true"},
+			wantMode:      "preview-only",
+			wantInstances: []string{"default"},
+			wantDefault:   "default",
+		},
+		{
+			name:          "environment mode overrides readOnly of the config file",
+			config:        "readOnly: true\n",
+			env:           map[string]string{"VMIX_MCP_MODE": "full"},
+			wantMode:      "full",
+			wantInstances: []string{"default"},
+			wantDefault:   "default",
+		},
+		{
+			name:          "flag read-only overrides the environment mode",
+			config:        testConfig,
+			args:          []string{"-read-only"},
+			env:           map[string]string{"VMIX_MCP_MODE": "full"},
+			wantMode:      "read-only",
+			wantToken:     "file-token",
+			wantInstances: []string{"studio", "backup"},
+			wantDefault:   "studio",
+		},
+		{
+			name:          "flag read-only=false clears readOnly of the config file",
+			config:        "readOnly: true\n",
+			args:          []string{"-read-only=false"},
+			wantMode:      "full",
+			wantInstances: []string{"default"},
+			wantDefault:   "default",
+		},
+		{
+			name:          "missing config file",
+			config:        "",
+			wantMode:      "full",
+			wantInstances: []string{"default"},
+			wantDefault:   "default",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "missing.yaml")
+			if tc.config != "" {
+				path = writeTestConfig(t, tc.config)
+			}
+			env := map[string]string{"VMIX_MCP_CONFIG": path}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+
+			opts, err := parseOptions(tc.args, func(key string) string { return env[key] })
+			if err != nil {
+				t.Fatalf("parseOptions() error = %v", err)
+			}
+			if opts.configPath != path {
+				t.Errorf("configPath = %s, want %s", opts.configPath, path)
+			}
+			cfg := opts.cfg
+			if cfg.Mode != tc.wantMode {
+				t.Errorf("Mode = %s, want %s", cfg.Mode, tc.wantMode)
+			}
+			if cfg.Transport.Token != tc.wantToken {
+				t.Errorf("Token = %q, want %q", cfg.Transport.Token, tc.wantToken)
+			}
+			var names []string
+			var def string
+			for _, in := range cfg.Instances {
+				names = append(names, in.Name)
+				if in.Default {
+					def = in.Name
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(tc.wantInstances, ",") {
+				t.Errorf("Instances = %v, want %v", names, tc.wantInstances)
+			}
+			if def != tc.wantDefault {
+				t.Errorf("default instance = %s, want %s", def, tc.wantDefault)
+			}
+		})
+	}
+}
+
+func TestParseOptionsConfigFlag(t *testing.T) {
+	path := writeTestConfig(t, testConfig)
+	env := map[string]string{"VMIX_MCP_CONFIG": filepath.Join(t.TempDir(), "other.yaml")}
+	opts, err := parseOptions([]string{"-config", path}, func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatalf("parseOptions() error = %v", err)
+	}
+	if opts.configPath != path || opts.cfg.Transport.Token != "file-token" {
+		t.Errorf("configPath = %s, token = %q, want the -config file", opts.configPath, opts.cfg.Transport.Token)
+	}
+}
+
+func TestParseOptionsErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want string
+	}{
+		{name: "invalid environment", env: map[string]string{"VMIX_MCP_READ_ONLY": "maybe"}, want: "VMIX_MCP_READ_ONLY"},
+		{name: "invalid flag", args: []string{"-instances", "studio"}, want: "-instances"},
+		{name: "unknown mode", args: []string{"-mode", "safe"}, want: "invalid configuration"},
+		{name: "unknown default instance", args: []string{"-default-instance", "backup"}, want: "unknown instance"},
+		{name: "unexpected argument", args: []string{"serve"}, want: "unexpected arguments"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := map[string]string{"VMIX_MCP_CONFIG": filepath.Join(t.TempDir(), "missing.yaml")}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+			_, err := parseOptions(tc.args, func(key string) string { return env[key] })
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("parseOptions() error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	env := map[string]string{
+		"VMIX_MCP_CONFIG": writeTestConfig(t, testConfig),
+		"VMIX_MCP_TOKEN":  "env-token",
+	}
+	opts, err := parseOptions([]string{"-print-config"}, func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatalf("parseOptions() error = %v", err)
+	}
+	if !opts.printConfig {
+		t.Fatal("printConfig = false with -print-config")
+	}
+
+	var buf bytes.Buffer
+	if err := writeConfig(&buf, opts.cfg); err != nil {
+		t.Fatalf("writeConfig() error = %v", err)
+	}
+	got := buf.String()
+	for _, secret := range []string{"env-token", "file-token", "file-password"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("printed config contains %q:\n%s", secret, got)
+		}
+	}
+	if strings.Count(got, "********") != 2 {
+		t.Errorf("printed config does not mask the token and the password:\n%s", got)
+	}
+	if !strings.Contains(got, "mode: preview-only") || !strings.Contains(got, "host: 192.168.0.10") {
+		t.Errorf("printed config lacks the effective settings:\n%s", got)
+	}
+
+	// 伏せるのは出力だけで、設定そのものは変えない
+	if opts.cfg.Transport.Token != "env-token" || opts.cfg.Instances[0].Password != "file-password" {
+		t.Errorf("writeConfig() changed the config: token = %q, password = %q", opts.cfg.Transport.Token, opts.cfg.Instances[0].Password)
+	}
+}
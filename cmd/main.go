@@ -2,164 +2,571 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 
 	mcpvmix "github.com/FlowingSPDG/mcp-vmix"
+	"github.com/FlowingSPDG/mcp-vmix/audit"
+	"github.com/FlowingSPDG/mcp-vmix/config"
 	"github.com/FlowingSPDG/mcp-vmix/logger"
+	"github.com/FlowingSPDG/mcp-vmix/mcpext"
+	"github.com/FlowingSPDG/mcp-vmix/mcphttp"
+	"github.com/FlowingSPDG/mcp-vmix/mcplog"
+	"github.com/FlowingSPDG/mcp-vmix/policy"
 	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport"
 	"github.com/metoro-io/mcp-golang/transport/stdio"
 )
 
 var log logger.Logger
 
+// toolRegistrar は設定で許可されたツールのみを登録し、モードで許可されない呼び出しを拒否する
+type toolRegistrar struct {
+	server *mcp_golang.Server
+	cfg    *config.Config
+	policy *policy.Policy
+	audit  *audit.Log // nil の場合は監査ログを書かない
+}
+
+func (r *toolRegistrar) register(name string, class policy.Class, description string, handler any) error {
+	if !r.cfg.ToolAllowed(name) {
+		log.Info("Tool is not in the allowlist, skipped", "tool", name)
+		return nil
+	}
+	// ツール名はここで一度だけロガーに付け、ハンドラはコンテキストから取り出す
+	handler, err := logger.WrapHandler(log.With("tool", name), handler)
+	if err != nil {
+		return err
+	}
+	guarded, err := r.policy.Guard(name, class, handler)
+	if err != nil {
+		return err
+	}
+	// 拒否された呼び出しも監査ログに残すため、ポリシーの外側で記録する
+	if r.audit != nil {
+		if guarded, err = r.audit.Wrap(name, class.String(), guarded); err != nil {
+			return err
+		}
+	}
+	return r.server.RegisterTool(name, r.policy.Describe(class, description), guarded)
+}
+
+// newLogger は設定からロガーを作成する。負の値はローテーション・保持の無効化を表す
+func newLogger(cfg config.Log, handlers ...slog.Handler) (logger.Logger, error) {
+	path := cfg.Path
+	if path == "" {
+		p, err := logger.GetLogFilePath()
+		if err != nil {
+			return nil, err
+		}
+		path = p
+	}
+	level, err := logger.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	return logger.New(logger.Options{
+		Path:       path,
+		Level:      level,
+		Format:     logger.Format(cfg.Format),
+		MaxSize:    int64(max(cfg.MaxSizeMB, 0)) * 1024 * 1024,
+		MaxAgeDays: max(cfg.MaxAgeDays, 0),
+		MaxBackups: max(cfg.MaxBackups, 0),
+		Stderr:     cfg.Stderr,
+		Handlers:   handlers,
+	})
+}
+
+// newTransport は設定で選択されたトランスポートを作成する
+func newTransport(cfg config.Transport) transport.Transport {
+	if cfg.Type == "http" {
+		return mcphttp.New(mcphttp.Options{
+			Addr:     cfg.Addr,
+			Token:    cfg.Token,
+			CertFile: cfg.TLSCert,
+			KeyFile:  cfg.TLSKey,
+		})
+	}
+	return stdio.NewStdioServerTransport()
+}
+
 func main() {
+	// 設定の読み込み
+	opts, err := parseOptions(os.Args[1:], os.Getenv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	if opts.version {
+		fmt.Println(getVersion())
+		return
+	}
+	cfg := opts.cfg
+	if opts.printConfig {
+		if err := writeConfig(os.Stdout, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to print config: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	// ロガーの初期化
-	logPath, err := logger.GetLogFilePath()
+	// ロガーの初期化。ログはMCPクライアントにも転送する
+	clientLevel, err := mcplog.ParseLevel(cfg.Log.ClientLevel)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get log file path: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Failed to parse client log level: %v\n", err)
 		return
 	}
-
-	log, err = logger.NewFileLogger(logPath)
+	forwarder := mcplog.New("mcp-vmix", clientLevel)
+	log, err = newLogger(cfg.Log, forwarder)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create logger: %v\n", err)
 		return
 	}
 	defer log.Close()
 
-	log.Info("Starting vMix MCP server...")
-	server := mcp_golang.NewServer(stdio.NewStdioServerTransport())
+	if opts.command == "replay" {
+		if err := runReplay(ctx, log, cfg, opts.commandArgs); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			log.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	log.Info("Starting vMix MCP server...", "version", getVersion())
+	log.Info("Loaded vMix instances", "count", len(cfg.Instances), "config", opts.configPath)
+	transport := mcpext.Wrap(newTransport(cfg.Transport))
+	defer transport.Close()
+	forwarder.Register(transport)
+	server := mcp_golang.NewServer(transport)
+
+	// 安全モードの設定
+	toolPolicy := policy.New(policy.Mode(cfg.Mode))
+	toolPolicy.OnReject = func(tool string, class policy.Class, err error) {
+		log.Warn("Rejected tool call", "tool", tool, "class", class.String(), "error", err)
+	}
+	log.Info("Running in safe mode", "mode", cfg.Mode)
+	tools := &toolRegistrar{server: server, cfg: cfg, policy: toolPolicy}
+
+	// 監査ログの初期化
+	if !cfg.Audit.Disabled {
+		auditPath := cfg.Audit.Path
+		if auditPath == "" {
+			auditPath, err = audit.GetAuditFilePath()
+			if err != nil {
+				log.Error("Failed to get audit log path", "error", err)
+				return
+			}
+		}
+		auditLog, err := audit.Open(auditPath)
+		if err != nil {
+			log.Error("Failed to open audit log", "error", err)
+			return
+		}
+		defer auditLog.Close()
+		auditLog.Session = func(ctx context.Context) string {
+			id, _ := mcphttp.SessionID(ctx)
+			return id
+		}
+		tools.audit = auditLog
+		log.Info("Writing audit log", "path", auditPath, "run", auditLog.Run())
+	}
 
 	// MCPvMixインスタンスの作成
-	vmixInstance := mcpvmix.NewMCPvMix(log)
+	vmixInstance := mcpvmix.NewMCPvMix(ctx, log, cfg)
 
 	// ツールの登録
-	if err := server.RegisterTool("vmix_fetch", "Connect to a vMix instance.", vmixInstance.FetchVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_fetch tool: %v", err))
+	if err := tools.register("vmix_fetch", policy.Read, "Connect to a vMix instance and fetch its state. Use format json for the complete state and the filters to limit the inputs returned.", vmixInstance.FetchVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_fetch", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_list_instances", policy.Read, "List the named vMix instances this server can control and which one is selected.", vmixInstance.ListInstances); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_list_instances", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_select_instance", policy.Read, "Select the vMix instance used by tools called without instance/ip/port.", vmixInstance.SelectInstance); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_select_instance", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_events", policy.Read, "Get the current tally and latest activator events (input on air, recording started, audio levels...) pushed by the vMix TCP API. The instance needs tcp enabled in the config.", vmixInstance.EventsVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_events", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_cut", policy.Program, "Perform a cut shortcut on a vMix instance.", vmixInstance.CutVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_cut", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_fade", policy.Program, "Perform a Fade shortcut function on a vMix instance", vmixInstance.FadeVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_fade", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_cut", "Perform a cut shortcut on a vMix instance.", vmixInstance.CutVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_cut tool: %v", err))
+	if err := tools.register("vmix_fade_to_black", policy.Destructive, "Perform Fade To Black on a vMix instance. If the response asks for confirmation, tell the user what it describes and call again with confirmationToken only after they agree.", vmixInstance.FadeToBlackVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_fade_to_black", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_fade", "Perform a Fade shortcut function on a vMix instance", vmixInstance.FadeVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_fade tool: %v", err))
+	if err := tools.register("vmix_preview_input", policy.Preview, "Put an input in preview without changing the program. Use a transition tool to take it to program.", vmixInstance.PreviewInput); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_preview_input", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_fade_to_black", "Perform Fade To Black on a vMix instance", vmixInstance.FadeToBlackVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_fade_to_black tool: %v", err))
+	if err := tools.register("vmix_preview_next", policy.Preview, "Put the next input in preview.", vmixInstance.PreviewNext); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_preview_next", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_start_recording", "Start recording on a vMix instance", vmixInstance.StartRecordingVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_start_recording tool: %v", err))
+	if err := tools.register("vmix_preview_previous", policy.Preview, "Put the previous input in preview.", vmixInstance.PreviewPrevious); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_preview_previous", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_stop_recording", "Stop recording on a vMix instance", vmixInstance.StopRecordingVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_stop_recording tool: %v", err))
+	if err := tools.register("vmix_transition", policy.Program, "Take preview to program with one of the four transition buttons, using the effect and duration set on the button.", vmixInstance.TransitionVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_transition", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_start_streaming", "Start streaming on a vMix instance", vmixInstance.StartStreamingVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_start_streaming tool: %v", err))
+	if err := tools.register("vmix_stinger", policy.Program, "Take preview (or an input) to program with stinger 1~4.", vmixInstance.StingerVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_stinger", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_stop_streaming", "Stop streaming on a vMix instance", vmixInstance.StopStreamingVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_stop_streaming tool: %v", err))
+	if err := tools.register("vmix_transition_effect", policy.Program, "Take preview (or an input) to program with a transition effect such as Merge, Wipe or Zoom and an optional duration.", vmixInstance.TransitionEffectVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_transition_effect", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_start_external", "Start external output on a vMix instance", vmixInstance.StartExternalVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_start_external tool: %v", err))
+	if err := tools.register("vmix_mix_status", policy.Read, "Get which input is in program and which is in preview, for every mix or one mix.", vmixInstance.MixStatus); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_mix_status", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_stop_external", "Stop external output on a vMix instance", vmixInstance.StopExternalVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_stop_external tool: %v", err))
+	if err := tools.register("vmix_overlay", policy.Program, "Bring an input in or out of overlay channel 1~4 (e.g. a lower third). Returns which input is on each overlay channel afterwards.", vmixInstance.OverlayVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_overlay", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_start_multicorder", "Start MultiCorder on a vMix instance", vmixInstance.StartMulticorderVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_start_multicorder tool: %v", err))
+	if err := tools.register("vmix_preview_overlay", policy.Preview, "Toggle an input on overlay channel 1~4 of the preview only, to check it before it goes on air.", vmixInstance.PreviewOverlayVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_preview_overlay", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_stop_multicorder", "Stop MultiCorder on a vMix instance", vmixInstance.StopMulticorderVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_stop_multicorder tool: %v", err))
+	if err := tools.register("vmix_overlay_all_off", policy.Program, "Turn all overlay channels off immediately.", vmixInstance.OverlayAllOffVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_overlay_all_off", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_start_playlist", "Start playlist on a vMix instance", vmixInstance.StartPlaylistVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_start_playlist tool: %v", err))
+	if err := tools.register("vmix_overlay_status", policy.Read, "Get which input is on each overlay channel 1~4.", vmixInstance.OverlayStatus); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_overlay_status", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_stop_playlist", "Stop playlist on a vMix instance", vmixInstance.StopPlaylistVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_stop_playlist tool: %v", err))
+	if err := tools.register("vmix_title_fields", policy.Read, "List the text and image fields of a title (GT) input with their current values.", vmixInstance.TitleFields); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_title_fields", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_fullscreen", "Toggle fullscreen on a vMix instance", vmixInstance.FullscreenVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_fullscreen tool: %v", err))
+	if err := tools.register("vmix_set_title_fields", policy.Program, "Update text, text colour, visibility or image source of one or more fields of a title (GT) input in one call, e.g. names, scores and tickers.", vmixInstance.SetTitleFields); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_set_title_fields", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_snapshot", "Take a screenshot of the current vMix instance", vmixInstance.SnapShotVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_snapshot tool: %v", err))
+	if err := tools.register("vmix_audio_input", policy.Program, "Change the audio of an input: volume (optionally faded), mute, solo, bus routing (M and A~G), audio follows video, gain and balance. Returns the audio status afterwards.", vmixInstance.AudioInputVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_audio_input", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_snapshot_input", "Take a screenshot of a specific input on a vMix instance", vmixInstance.SnapShotInputVMix); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_snapshot_input tool: %v", err))
+	if err := tools.register("vmix_audio_bus", policy.Program, "Change volume, mute, solo or send to master of the master bus or bus A~G. Returns the audio status afterwards.", vmixInstance.AudioBusVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_audio_bus", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_check_screenshot", "Check screenshot of the current vMix instance", vmixInstance.CheckScreenshot); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_check_screenshot tool: %v", err))
+	if err := tools.register("vmix_audio_status", policy.Read, "Get volume, mute, solo, meters (dBFS) of master and buses, and volume, routing, gain, balance and meters of the inputs with audio.", vmixInstance.AudioStatus); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_audio_status", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_check_screenshot_input", "Check screenshot of a specific input on a vMix instance", vmixInstance.CheckScreenshotInput); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_check_screenshot_input tool: %v", err))
+	if err := tools.register("vmix_audio_alerts", policy.Read, "Get the active and recently ended alerts of the background audio monitor: inputs, buses or master which stayed silent (e.g. a dead mic) or kept clipping. The server must be started with -audio-monitor.", vmixInstance.AudioAlerts); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_audio_alerts", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_get_shortcut_url", "Get shortcut URL for a vMix instance. This is useful for getting the URL of a shortcut function for vMix users.", vmixInstance.GetShortcutURL); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_get_shortcut_url tool: %v", err))
+	if err := tools.register("vmix_replay_mark", policy.Preview, "Mark a replay event: in starts it, out ends it, inOut creates an event of the last seconds and cancel drops it. Needs vMix 4K or Pro.", vmixInstance.ReplayMark); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_replay_mark", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_add_blank", "Add blank inputs to a vMix instance. Input will be appended last input slice, so input number must be current number of inputs +1 . This is useful for adding blank inputs to a vMix instance.", vmixInstance.AddBlank); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_add_blank tool: %v", err))
+	if err := tools.register("vmix_replay_play", policy.Program, "Play the last, selected, all or an indexed replay event of the current event list, optionally cutting the replay input to the output.", vmixInstance.ReplayPlay); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_replay_play", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_make_scene", "Make a complicated composit scene on a vMix instance. This is used to make a new scene with multiple layers. It is always recommended to use this for Blank Input.", vmixInstance.MakeScene); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_make_scene tool: %v", err))
+	if err := tools.register("vmix_replay_speed", policy.Program, "Set or change the replay playback speed (-1~1, e.g. 0.5 for half speed slow motion).", vmixInstance.ReplaySpeed); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_replay_speed", "error", err)
 		return
 	}
 
-	if err := server.RegisterTool("vmix_adjust_layers", "Adjust layers of a vMix instance. This is used to adjust the layers of a vMix instance. It is always recommended to use this for Blank Input.", vmixInstance.AdjustLayers); err != nil {
-		log.Error(fmt.Sprintf("Failed to register vmix_adjust_layers tool: %v", err))
+	if err := tools.register("vmix_replay_select_events", policy.Preview, "Select the replay event list 1~20.", vmixInstance.ReplaySelectEvents); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_replay_select_events", "error", err)
 		return
 	}
 
-	log.Info("Starting MCP server...")
+	if err := tools.register("vmix_replay_camera", policy.Program, "Switch the replay (or only channel A or B) to camera 1~8.", vmixInstance.ReplayCamera); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_replay_camera", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_replay_start_recording", policy.Program, "Start recording the replay buffer.", vmixInstance.ReplayStartRecording); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_replay_start_recording", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_replay_stop_recording", policy.Destructive, "Stop recording the replay buffer. No new replay events can be marked until it is started again.", vmixInstance.ReplayStopRecording); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_replay_stop_recording", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_replay_status", policy.Read, "Get live/recording state, channel mode, selected event list, camera, speed and timecode of each replay channel.", vmixInstance.ReplayStatus); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_replay_status", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_replay_events", policy.Read, "List the replay event lists selected on each channel of the replay inputs with the camera of the channel. The vMix API does not report the individual events in a list, their cameras or tags, so tell the user when they ask for those.", vmixInstance.ReplayEvents); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_replay_events", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_ptz_move", policy.Program, "Pan/tilt a PTZ camera input in a direction at a speed 0~1, move it home or stop it. With durationMs the move is stopped after that time, otherwise it continues until stop.", vmixInstance.PTZMove); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_ptz_move", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_ptz_zoom", policy.Program, "Zoom a PTZ camera input in or out at a speed 0~1, or stop zooming. With durationMs the zoom is stopped after that time.", vmixInstance.PTZZoom); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_ptz_zoom", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_ptz_focus", policy.Program, "Switch a PTZ camera input to auto or manual focus, or focus near/far at a speed 0~1. With durationMs the focus is stopped after that time.", vmixInstance.PTZFocus); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_ptz_focus", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_ptz_create_virtual_input", policy.Preview, "Save the current position of a PTZ camera input as a new PTZ virtual input and return the new input.", vmixInstance.PTZCreateVirtualInput); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_ptz_create_virtual_input", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_ptz_move_to_virtual_input", policy.Program, "Move the camera of a PTZ virtual input to its saved position, e.g. to frame the speaker on camera 2 before cutting to it.", vmixInstance.PTZMoveToVirtualInput); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_ptz_move_to_virtual_input", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_ptz_inputs", policy.Read, "List the PTZ virtual inputs (saved camera positions) with their number, title and key.", vmixInstance.PTZInputs); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_ptz_inputs", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_add_input", policy.Preview, "Add an input of any type (Video, Image, Photos, Audio, Title, Browser, NDI, SRT, Colour, VirtualSet) from a path, URL or name, optionally naming it. Returns the number and key of the new input.", vmixInstance.AddInput); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_add_input", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_duplicate_input", policy.Preview, "Duplicate an input, optionally naming the copy. Returns the number and key of the copy.", vmixInstance.DuplicateInput); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_duplicate_input", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_remove_input", policy.Destructive, "Remove an input. Inputs on air are refused unless force is set. Inputs after it are renumbered.", vmixInstance.RemoveInput); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_remove_input", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_rename_input", policy.Preview, "Rename an input. Returns its number and key.", vmixInstance.RenameInput); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_rename_input", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_move_input", policy.Program, "Move an input to another input number. Returns its new number and key.", vmixInstance.MoveInput); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_move_input", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_reset_input", policy.Program, "Reset the position, crop and effects of an input.", vmixInstance.ResetInput); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_reset_input", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_playback", policy.Program, "Play, pause, toggle or restart a video or audio input. Returns its position and time remaining.", vmixInstance.PlaybackVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_playback", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_seek", policy.Program, "Cue a video or audio input to a position in milliseconds, or to some milliseconds before its end.", vmixInstance.SeekVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_seek", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_playback_rate", policy.Program, "Set the playback rate of a video input, e.g. 0.5 for slow motion. 1 is normal speed.", vmixInstance.PlaybackRateVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_playback_rate", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_loop", policy.Program, "Turn loop of a video or audio input on or off.", vmixInstance.LoopVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_loop", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_mark", policy.Program, "Set the mark in or out point of a video or audio input at its current position, or clear them.", vmixInstance.MarkVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_mark", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_time_remaining", policy.Read, "Get state, position, duration and time remaining of video and audio inputs. Inputs on air which end soon are flagged, e.g. to warn before a VT ends.", vmixInstance.TimeRemaining); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_time_remaining", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_start_recording", policy.Program, "Start recording on a vMix instance", vmixInstance.StartRecordingVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_start_recording", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_stop_recording", policy.Destructive, "Stop recording on a vMix instance. If the response asks for confirmation, tell the user what it describes and call again with confirmationToken only after they agree.", vmixInstance.StopRecordingVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_stop_recording", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_start_streaming", policy.Program, "Start streaming on a vMix instance", vmixInstance.StartStreamingVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_start_streaming", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_stop_streaming", policy.Destructive, "Stop streaming on a vMix instance. If the response asks for confirmation, tell the user what it describes and call again with confirmationToken only after they agree.", vmixInstance.StopStreamingVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_stop_streaming", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_start_external", policy.Program, "Start external output on a vMix instance", vmixInstance.StartExternalVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_start_external", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_stop_external", policy.Destructive, "Stop external output on a vMix instance", vmixInstance.StopExternalVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_stop_external", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_start_multicorder", policy.Program, "Start MultiCorder on a vMix instance", vmixInstance.StartMulticorderVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_start_multicorder", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_stop_multicorder", policy.Destructive, "Stop MultiCorder on a vMix instance", vmixInstance.StopMulticorderVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_stop_multicorder", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_start_playlist", policy.Program, "Start playlist on a vMix instance", vmixInstance.StartPlaylistVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_start_playlist", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_stop_playlist", policy.Destructive, "Stop playlist on a vMix instance", vmixInstance.StopPlaylistVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_stop_playlist", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_fullscreen", policy.Program, "Toggle fullscreen on a vMix instance", vmixInstance.FullscreenVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_fullscreen", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_snapshot", policy.Preview, "Take a screenshot of the current vMix instance", vmixInstance.SnapShotVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_snapshot", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_snapshot_input", policy.Preview, "Take a screenshot of a specific input on a vMix instance", vmixInstance.SnapShotInputVMix); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_snapshot_input", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_check_screenshot", policy.Read, "Check screenshot of the current vMix instance", vmixInstance.CheckScreenshot); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_check_screenshot", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_check_screenshot_input", policy.Read, "Check screenshot of a specific input on a vMix instance", vmixInstance.CheckScreenshotInput); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_check_screenshot_input", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_get_shortcut_url", policy.Read, "Get shortcut URL for a vMix instance. This is useful for getting the URL of a shortcut function for vMix users.", vmixInstance.GetShortcutURL); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_get_shortcut_url", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_add_blank", policy.Preview, "Add blank (black or transparent) colour inputs to a vMix instance one by one, optionally naming each. Returns the number and key of every new input in order, e.g. to build scenes on them.", vmixInstance.AddBlank); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_add_blank", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_make_scene", policy.Program, "Make a complicated composit scene on a vMix instance. This is used to make a new scene with multiple layers. It is always recommended to use this for Blank Input.", vmixInstance.MakeScene); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_make_scene", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_adjust_layers", policy.Program, "Adjust layers of a vMix instance. This is used to adjust the layers of a vMix instance. It is always recommended to use this for Blank Input.", vmixInstance.AdjustLayers); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_adjust_layers", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_function", policy.Destructive, "Execute any vMix shortcut function, e.g. SetText, OverlayInput1In or ReplayMarkIn. Use this when no dedicated tool exists. The call is validated against the catalogue of vMix functions and its parameters before it is sent. Functions missing from the catalogue are sent with a warning.", vmixInstance.VmixFunction); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_function", "error", err)
+		return
+	}
+
+	if err := tools.register("vmix_search_functions", policy.Read, "Search the catalogue of vMix shortcut functions by words and category. Returns the parameters, value format and minimum vMix version/edition of each function. Use it before vmix_function or vmix_get_shortcut_url.", vmixInstance.SearchFunctions); err != nil {
+		log.Error("Failed to register tool", "tool", "vmix_search_functions", "error", err)
+		return
+	}
+
+	// クライアント毎の選択インスタンスはセッション終了時に破棄する
+	transport.OnSessionClosed(vmixInstance.ForgetSession)
+
+	// リソースの登録
+	if err := vmixInstance.RegisterResources(ctx, server, transport); err != nil {
+		log.Error("Failed to register resources", "error", err)
+		return
+	}
+
+	log.Info("Starting MCP server...", "transport", cfg.Transport.Type)
 	if err := server.Serve(); err != nil {
-		log.Error(fmt.Sprintf("Failed to start MCP server: %v", err))
+		log.Error("Failed to start MCP server", "error", err)
 		return
 	}
+	if cfg.Transport.Type == "http" {
+		log.Info("Listening", "addr", cfg.Transport.Addr)
+	}
 
 	<-ctx.Done()
 }
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	mcpvmix "github.com/FlowingSPDG/mcp-vmix"
+	"github.com/FlowingSPDG/mcp-vmix/audit"
+	"github.com/FlowingSPDG/mcp-vmix/config"
+	"github.com/FlowingSPDG/mcp-vmix/logger"
+	"github.com/FlowingSPDG/mcp-vmix/policy"
+)
+
+// replayStep は再送する1つのvMix関数
+type replayStep struct {
+	tool string
+	// class はツールの安全区分。区分のない古い記録は destructive として扱う
+	class policy.Class
+	audit.FunctionCall
+}
+
+// recordedInstance は関数が送られたインスタンス。ip指定の呼び出しは host:port
+func recordedInstance(fc audit.FunctionCall) string {
+	if fc.Instance != "" {
+		return fc.Instance
+	}
+	return net.JoinHostPort(fc.Host, strconv.Itoa(fc.Port))
+}
+
+// functionSink は再送先。-send がない場合は送らずに表示するだけ
+type functionSink func(ctx context.Context, step replayStep) error
+
+type replayOptions struct {
+	path          string
+	from          string
+	instance      string
+	send          bool
+	realtime      bool
+	run           string
+	session       string
+	includeFailed bool
+}
+
+func parseReplayOptions(args []string) (*replayOptions, error) {
+	fs := flag.NewFlagSet("mcp-vmix replay", flag.ContinueOnError)
+	opts := &replayOptions{}
+	fs.StringVar(&opts.from, "from", "", "replay only functions recorded on this instance (name, or host:port for calls by ip). Required when the log has several instances")
+	fs.StringVar(&opts.instance, "instance", "", "name of the vMix instance to send to (default: the default instance of the configuration)")
+	fs.BoolVar(&opts.send, "send", false, "send the functions to vMix. Without it they are only printed")
+	fs.BoolVar(&opts.realtime, "realtime", false, "wait between functions as long as when they were recorded")
+	fs.StringVar(&opts.run, "run", "", "replay only records of this server run")
+	fs.StringVar(&opts.session, "session", "", "replay only records of this MCP session")
+	fs.BoolVar(&opts.includeFailed, "include-failed", false, "also send functions which failed when they were recorded")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: mcp-vmix [flags] replay [replay flags] audit.jsonl\n\nPrint the vMix functions recorded in an audit log in order, and re-send them with -send.\n\nReplay flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return nil, fmt.Errorf("replay needs exactly one audit log file")
+	}
+	opts.path = fs.Arg(0)
+	return opts, nil
+}
+
+// replaySteps は記録から再送する関数を記録順に取り出す
+func replaySteps(records []audit.Record, opts *replayOptions) []replayStep {
+	var steps []replayStep
+	for _, rec := range records {
+		if opts.run != "" && rec.Run != opts.run {
+			continue
+		}
+		if opts.session != "" && rec.Session != opts.session {
+			continue
+		}
+		class, err := policy.ParseClass(rec.Class)
+		if err != nil {
+			class = policy.Destructive
+		}
+		for _, fc := range rec.Functions {
+			if fc.Error != "" && !opts.includeFailed {
+				continue
+			}
+			if opts.from != "" && recordedInstance(fc) != opts.from {
+				continue
+			}
+			steps = append(steps, replayStep{tool: rec.Tool, class: class, FunctionCall: fc})
+		}
+	}
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Time.Before(steps[j].Time) })
+	return steps
+}
+
+func formatQuery(query map[string]string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := make([]string, len(keys))
+	for i, k := range keys {
+		params[i] = fmt.Sprintf("%s=%s", k, query[k])
+	}
+	return strings.Join(params, " ")
+}
+
+// recordedInstances は手順が記録されたインスタンスを名前順に返す
+func recordedInstances(steps []replayStep) []string {
+	seen := map[string]struct{}{}
+	var instances []string
+	for _, step := range steps {
+		in := recordedInstance(step.FunctionCall)
+		if _, ok := seen[in]; !ok {
+			seen[in] = struct{}{}
+			instances = append(instances, in)
+		}
+	}
+	sort.Strings(instances)
+	return instances
+}
+
+// allowedSteps はモードで許可されない手順を除く。除いた手順は w に表示する
+func allowedSteps(w io.Writer, steps []replayStep, p *policy.Policy) []replayStep {
+	allowed := make([]replayStep, 0, len(steps))
+	for _, step := range steps {
+		if err := p.Check(step.tool, step.class); err != nil {
+			fmt.Fprintf(w, "skip %s %s (%s): %v\n", step.Function, formatQuery(step.Query), step.tool, err)
+			continue
+		}
+		allowed = append(allowed, step)
+	}
+	return allowed
+}
+
+func dryRunSink(w io.Writer) functionSink {
+	return func(_ context.Context, step replayStep) error {
+		_, err := fmt.Fprintf(w, "would send %s %s (%s)\n", step.Function, formatQuery(step.Query), step.tool)
+		return err
+	}
+}
+
+func vmixSink(sender *mcpvmix.FunctionSender, instance string) functionSink {
+	return func(ctx context.Context, step replayStep) error {
+		return sender.Send(ctx, instance, step.Function, step.Query)
+	}
+}
+
+// replay は関数を順番に送る。最初の失敗で止める
+func replay(ctx context.Context, w io.Writer, steps []replayStep, sink functionSink, realtime bool) error {
+	for i, step := range steps {
+		if realtime && i > 0 {
+			select {
+			case <-time.After(step.Time.Sub(steps[i-1].Time)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		fmt.Fprintf(w, "[%d/%d] %s %s %s\n", i+1, len(steps), step.Time.Format(time.RFC3339), step.tool, step.Function)
+		if err := sink(ctx, step); err != nil {
+			return fmt.Errorf("failed to send %s (%d/%d): %w", step.Function, i+1, len(steps), err)
+		}
+	}
+	return nil
+}
+
+// runReplay は replay サブコマンドを実行する
+func runReplay(ctx context.Context, log logger.Logger, cfg *config.Config, args []string) error {
+	opts, err := parseReplayOptions(args)
+	if err != nil {
+		return err
+	}
+	if opts.instance != "" {
+		if _, ok := lookupInstance(cfg, opts.instance); !ok {
+			return fmt.Errorf("unknown vMix instance: %s", opts.instance)
+		}
+	}
+
+	records, err := audit.ReadFile(opts.path)
+	if err != nil {
+		return err
+	}
+	steps := replaySteps(records, opts)
+	if len(steps) == 0 {
+		return fmt.Errorf("no vMix functions to replay in %s", opts.path)
+	}
+	// 複数のインスタンスの記録を1つの送信先にまとめて送らない
+	if instances := recordedInstances(steps); len(instances) > 1 {
+		return fmt.Errorf("the functions were recorded on several vMix instances (%s), choose one with -from", strings.Join(instances, ", "))
+	}
+	steps = allowedSteps(os.Stdout, steps, policy.New(policy.Mode(cfg.Mode)))
+	if len(steps) == 0 {
+		return fmt.Errorf("no vMix functions are allowed in %s mode", cfg.Mode)
+	}
+
+	sink := dryRunSink(os.Stdout)
+	if opts.send {
+		target := cfg.DefaultInstance()
+		if opts.instance != "" {
+			target, _ = lookupInstance(cfg, opts.instance)
+		}
+		fmt.Fprintf(os.Stdout, "Replaying %d functions to vMix instance %s (%s:%d)\n", len(steps), target.Name, target.Host, target.Port)
+		sink = vmixSink(mcpvmix.NewFunctionSender(log, cfg), target.Name)
+	}
+	return replay(ctx, os.Stdout, steps, sink, opts.realtime)
+}
+
+func lookupInstance(cfg *config.Config, name string) (config.Instance, bool) {
+	for _, in := range cfg.Instances {
+		if in.Name == name {
+			return in, true
+		}
+	}
+	return config.Instance{}, false
+}
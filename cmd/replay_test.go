@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FlowingSPDG/mcp-vmix/audit"
+	"github.com/FlowingSPDG/mcp-vmix/config"
+	"github.com/FlowingSPDG/mcp-vmix/logger"
+	"github.com/FlowingSPDG/mcp-vmix/policy"
+)
+
+var recordTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func functionCall(seconds int, function, instance string) audit.FunctionCall {
+	return audit.FunctionCall{
+		Time:      recordTime.Add(time.Duration(seconds) * time.Second),
+		Function:  function,
+		Instance:  instance,
+		Host:      "127.0.0.1",
+		Port:      8088,
+		Transport: "http",
+	}
+}
+
+func testRecords() []audit.Record {
+	failed := functionCall(2, "Fade", "main")
+	failed.Error = "connection refused"
+	byIP := functionCall(5, "Cut", "")
+	return []audit.Record{
+		{Run: "r1", Session: "s1", Tool: "vmix_cut", Class: "program-affecting", Functions: []audit.FunctionCall{functionCall(3, "Cut", "main")}},
+		{Run: "r1", Session: "s1", Tool: "vmix_fade", Class: "program-affecting", Functions: []audit.FunctionCall{failed}},
+		{Run: "r1", Session: "s2", Tool: "vmix_preview_input", Class: "preview-only", Functions: []audit.FunctionCall{functionCall(1, "PreviewInput", "main")}},
+		{Run: "r2", Session: "s3", Tool: "vmix_stop_streaming", Functions: []audit.FunctionCall{functionCall(4, "StopStreaming", "backup")}},
+		{Run: "r2", Session: "s3", Tool: "vmix_cut", Class: "program-affecting", Functions: []audit.FunctionCall{byIP}},
+		{Run: "r2", Tool: "vmix_status", Class: "read"},
+	}
+}
+
+func stepFunctions(steps []replayStep) string {
+	functions := make([]string, len(steps))
+	for i, step := range steps {
+		functions[i] = step.Function
+	}
+	return strings.Join(functions, ",")
+}
+
+func TestReplaySteps(t *testing.T) {
+	cases := []struct {
+		name string
+		opts replayOptions
+		want string
+	}{
+		{name: "all in recorded order", want: "PreviewInput,Cut,StopStreaming,Cut"},
+		{name: "include failed", opts: replayOptions{includeFailed: true}, want: "PreviewInput,Fade,Cut,StopStreaming,Cut"},
+		{name: "run", opts: replayOptions{run: "r1"}, want: "PreviewInput,Cut"},
+		{name: "session", opts: replayOptions{session: "s1", includeFailed: true}, want: "Fade,Cut"},
+		{name: "from instance", opts: replayOptions{from: "backup"}, want: "StopStreaming"},
+		{name: "from ip", opts: replayOptions{from: "127.0.0.1:8088"}, want: "Cut"},
+		{name: "nothing", opts: replayOptions{run: "r3"}, want: ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := stepFunctions(replaySteps(testRecords(), &tc.opts)); got != tc.want {
+				t.Errorf("replaySteps() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReplayStepsClass(t *testing.T) {
+	steps := replaySteps(testRecords(), &replayOptions{})
+	want := []policy.Class{policy.Preview, policy.Program, policy.Destructive, policy.Program}
+	for i, step := range steps {
+		if step.class != want[i] {
+			t.Errorf("class of %s (%s) = %s, want %s", step.Function, step.tool, step.class, want[i])
+		}
+	}
+}
+
+func TestAllowedSteps(t *testing.T) {
+	cases := []struct {
+		mode     policy.Mode
+		want     string
+		wantSkip int
+	}{
+		{mode: policy.ModeFull, want: "PreviewInput,Cut,StopStreaming,Cut"},
+		// 区分のない記録は destructive として扱い、full 以外では送らない
+		{mode: policy.ModePreviewOnly, want: "PreviewInput", wantSkip: 3},
+		{mode: policy.ModeReadOnly, want: "", wantSkip: 4},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			var buf bytes.Buffer
+			got := allowedSteps(&buf, replaySteps(testRecords(), &replayOptions{}), policy.New(tc.mode))
+			if stepFunctions(got) != tc.want {
+				t.Errorf("allowedSteps() = %s, want %s", stepFunctions(got), tc.want)
+			}
+			if n := strings.Count(buf.String(), "skip "); n != tc.wantSkip {
+				t.Errorf("printed %d skipped steps, want %d:\n%s", n, tc.wantSkip, buf.String())
+			}
+		})
+	}
+}
+
+func TestRecordedInstances(t *testing.T) {
+	got := recordedInstances(replaySteps(testRecords(), &replayOptions{}))
+	if want := "127.0.0.1:8088,backup,main"; strings.Join(got, ",") != want {
+		t.Errorf("recordedInstances() = %v, want %s", got, want)
+	}
+}
+
+func TestReplayRealtime(t *testing.T) {
+	steps := replaySteps(testRecords(), &replayOptions{run: "r1"})
+	var sent []string
+	sink := func(_ context.Context, step replayStep) error {
+		sent = append(sent, step.Function)
+		return nil
+	}
+
+	// 記録の間隔 (2秒) を待つ途中でキャンセルされる
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var buf bytes.Buffer
+	if err := replay(ctx, &buf, steps, sink, true); err != context.DeadlineExceeded {
+		t.Errorf("replay() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if strings.Join(sent, ",") != "PreviewInput" {
+		t.Errorf("sent %v before the cancel, want PreviewInput", sent)
+	}
+}
+
+// fakeVmix counts the functions sent to it.
+type fakeVmix struct {
+	mu        sync.Mutex
+	functions []string
+}
+
+func (f *fakeVmix) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if fn := r.URL.Query().Get("Function"); fn != "" {
+		f.mu.Lock()
+		f.functions = append(f.functions, fn)
+		f.mu.Unlock()
+		w.Write([]byte("Function completed successfully."))
+		return
+	}
+	w.Write([]byte(`<vmix><version>27.0.0.49</version><edition>4K</edition></vmix>`))
+}
+
+func (f *fakeVmix) sent() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.functions...)
+}
+
+func writeAuditLog(t *testing.T, records []audit.Record) string {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunReplay(t *testing.T) {
+	fake := &fakeVmix{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	p, _ := strconv.Atoi(port)
+	cfg := config.Default()
+	cfg.Instances = []config.Instance{{Name: "main", Host: host, Port: p, Default: true}}
+	log, _ := logger.New(logger.Options{})
+	path := writeAuditLog(t, testRecords())
+
+	cases := []struct {
+		name     string
+		mode     policy.Mode
+		args     []string
+		wantErr  string
+		wantSent string
+	}{
+		{name: "several instances need -from", mode: policy.ModeFull, args: []string{path}, wantErr: "choose one with -from"},
+		{name: "dry run sends nothing", mode: policy.ModeFull, args: []string{"-from", "main", path}},
+		{name: "send", mode: policy.ModeFull, args: []string{"-from", "main", "-send", path}, wantSent: "PreviewInput,Cut"},
+		{name: "send in preview-only mode", mode: policy.ModePreviewOnly, args: []string{"-from", "main", "-send", path}, wantSent: "PreviewInput"},
+		{name: "nothing allowed", mode: policy.ModeReadOnly, args: []string{"-from", "main", "-send", path}, wantErr: "no vMix functions are allowed"},
+		{name: "nothing recorded", mode: policy.ModeFull, args: []string{"-from", "other", path}, wantErr: "no vMix functions to replay"},
+		{name: "unknown instance", mode: policy.ModeFull, args: []string{"-instance", "other", path}, wantErr: "unknown vMix instance"},
+		{name: "no file", mode: policy.ModeFull, args: []string{"-from", "main"}, wantErr: "exactly one audit log file"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			before := len(fake.sent())
+			cfg.Mode = string(tc.mode)
+			err := runReplay(context.Background(), log, cfg, tc.args)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("runReplay() error = %v, want %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("runReplay() error = %v", err)
+			}
+			if got := strings.Join(fake.sent()[before:], ","); got != tc.wantSent {
+				t.Errorf("sent %q, want %q", got, tc.wantSent)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/FlowingSPDG/mcp-vmix/policy"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultHost    = "127.0.0.1"
+	DefaultPort    = 8088
+	DefaultTCPPort = 8099
+
+	DefaultLogLevel      = "info"
+	DefaultLogFormat     = "text"
+	DefaultLogMaxSizeMB  = 10
+	DefaultLogMaxAgeDays = 14
+	DefaultLogMaxBackups = 10
+	DefaultClientLevel   = "warning"
+	DefaultTransport     = "stdio"
+	DefaultHTTPAddr      = "127.0.0.1:8080"
+	DefaultMode          = string(policy.ModeFull)
+
+	DefaultAudioMonitorIntervalMs = 500
+	DefaultSilenceThresholdDB     = -60
+	DefaultSilenceSeconds         = 10
+	DefaultClipThresholdDB        = -1
+	DefaultClipSeconds            = 1
+)
+
+// Instance is a named vMix instance.
+type Instance struct {
+	Name     string `json:"name" yaml:"name"`
+	Host     string `json:"host" yaml:"host"`
+	Port     int    `json:"port" yaml:"port"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Default  bool   `json:"default,omitempty" yaml:"default,omitempty"`
+
+	// TCP enables the TCP API for shortcut functions and activator/tally events.
+	TCP     bool `json:"tcp,omitempty" yaml:"tcp,omitempty"`
+	TCPPort int  `json:"tcpPort,omitempty" yaml:"tcpPort,omitempty"`
+}
+
+// Log configures the log file.
+type Log struct {
+	Level  string `json:"level,omitempty" yaml:"level,omitempty"`   // debug, info, warn or error
+	Path   string `json:"path,omitempty" yaml:"path,omitempty"`     // empty writes logs/vmix-mcp.log under the config directory
+	Format string `json:"format,omitempty" yaml:"format,omitempty"` // text or json
+
+	// MaxSizeMB rotates the file when it grows over this size. -1 disables rotation.
+	MaxSizeMB int `json:"maxSizeMB,omitempty" yaml:"maxSizeMB,omitempty"`
+	// MaxAgeDays removes rotated files older than this. -1 keeps them regardless of age.
+	MaxAgeDays int `json:"maxAgeDays,omitempty" yaml:"maxAgeDays,omitempty"`
+	// MaxBackups is how many rotated files are kept. -1 keeps all.
+	MaxBackups int `json:"maxBackups,omitempty" yaml:"maxBackups,omitempty"`
+	// Stderr mirrors the log to stderr.
+	Stderr bool `json:"stderr,omitempty" yaml:"stderr,omitempty"`
+	// ClientLevel is the level of messages sent to the MCP client until it calls logging/setLevel.
+	// debug, info, notice, warning, error, critical, alert, emergency or off.
+	ClientLevel string `json:"clientLevel,omitempty" yaml:"clientLevel,omitempty"`
+}
+
+// Transport configures how MCP clients connect.
+type Transport struct {
+	Type    string `json:"type,omitempty" yaml:"type,omitempty"` // stdio or http
+	Addr    string `json:"addr,omitempty" yaml:"addr,omitempty"`
+	Token   string `json:"token,omitempty" yaml:"token,omitempty"`
+	TLSCert string `json:"tlsCert,omitempty" yaml:"tlsCert,omitempty"`
+	TLSKey  string `json:"tlsKey,omitempty" yaml:"tlsKey,omitempty"`
+}
+
+// Audit configures the audit log of tool calls and the vMix functions they sent.
+type Audit struct {
+	Path     string `json:"path,omitempty" yaml:"path,omitempty"` // empty writes audit.jsonl under the config directory
+	Disabled bool   `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+}
+
+// AudioMonitor configures the background monitor which raises alerts for sustained silence or clipping of audio meters.
+// The monitor polls the XML state of every instance, so it only runs when it is enabled.
+type AudioMonitor struct {
+	Enabled bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// IntervalMs is how often the meters are sampled.
+	IntervalMs int `json:"intervalMs,omitempty" yaml:"intervalMs,omitempty"`
+	// SilenceThresholdDB is the level in dBFS below which a source is silent. 0 uses the default.
+	SilenceThresholdDB float64 `json:"silenceThresholdDb,omitempty" yaml:"silenceThresholdDb,omitempty"`
+	// SilenceSeconds is how long a source must stay silent before an alert is raised.
+	SilenceSeconds float64 `json:"silenceSeconds,omitempty" yaml:"silenceSeconds,omitempty"`
+	// ClipThresholdDB is the level in dBFS at or above which a source is clipping. 0 uses the default.
+	ClipThresholdDB float64 `json:"clipThresholdDb,omitempty" yaml:"clipThresholdDb,omitempty"`
+	// ClipSeconds is how long a source must keep clipping before an alert is raised. -1 raises it at the first sample.
+	ClipSeconds float64 `json:"clipSeconds,omitempty" yaml:"clipSeconds,omitempty"`
+}
+
+// Config is the content of the mcp-vmix configuration file.
+type Config struct {
+	Log       Log       `json:"log" yaml:"log"`
+	Transport Transport `json:"transport" yaml:"transport"`
+	Audit     Audit     `json:"audit" yaml:"audit"`
+
+	AudioMonitor AudioMonitor `json:"audioMonitor" yaml:"audioMonitor"`
+
+	// Mode is the safety mode: full, preview-only or read-only. Calls of tools the mode does not allow are rejected.
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	// ReadOnly is a shorthand of mode: read-only.
+	ReadOnly bool `json:"readOnly,omitempty" yaml:"readOnly,omitempty"`
+	// Confirm makes stop streaming, stop recording and fade to black return a confirmation token first.
+	// The action is performed only when the tool is called again with the token.
+	Confirm bool `json:"confirm,omitempty" yaml:"confirm,omitempty"`
+	// Tools is the allowlist of tool names. Glob patterns such as "vmix_*_recording" are accepted. Empty allows all tools.
+	Tools []string `json:"tools,omitempty" yaml:"tools,omitempty"`
+	// ScreenshotDir is where vMix saves screenshots for vmix_check_screenshot. vMix and mcp-vmix must both be able to access it.
+	ScreenshotDir string `json:"screenshotDir,omitempty" yaml:"screenshotDir,omitempty"`
+
+	Instances []Instance `json:"instances" yaml:"instances"`
+}
+
+// GetConfigFilePath は%appdata%/RSLT/vmix-mcp/config.yaml のパスを返します
+func GetConfigFilePath() (string, error) {
+	appData, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(appData, "RSLT", "vmix-mcp", "config.yaml"), nil
+}
+
+// Default returns a configuration with a single local vMix instance.
+func Default() *Config {
+	return &Config{
+		Log: Log{
+			Level:       DefaultLogLevel,
+			Format:      DefaultLogFormat,
+			MaxSizeMB:   DefaultLogMaxSizeMB,
+			MaxAgeDays:  DefaultLogMaxAgeDays,
+			MaxBackups:  DefaultLogMaxBackups,
+			ClientLevel: DefaultClientLevel,
+		},
+		Transport: Transport{Type: DefaultTransport, Addr: DefaultHTTPAddr},
+		AudioMonitor: AudioMonitor{
+			IntervalMs:         DefaultAudioMonitorIntervalMs,
+			SilenceThresholdDB: DefaultSilenceThresholdDB,
+			SilenceSeconds:     DefaultSilenceSeconds,
+			ClipThresholdDB:    DefaultClipThresholdDB,
+			ClipSeconds:        DefaultClipSeconds,
+		},
+		Mode: DefaultMode,
+		Instances: []Instance{
+			{Name: "default", Host: DefaultHost, Port: DefaultPort, Default: true},
+		},
+	}
+}
+
+// Load reads a YAML or JSON configuration file. The format is chosen by the file extension.
+// If the file does not exist, Default() is returned.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Default(), nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := &Config{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(b, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	default:
+		if err := yaml.Unmarshal(b, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.Normalize(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Normalize fills default values, validates settings and instance names and picks a default instance.
+// Call it again after overriding values.
+func (c *Config) Normalize() error {
+	def := Default()
+	if c.Log.Level == "" {
+		c.Log.Level = def.Log.Level
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("unknown log level: %s", c.Log.Level)
+	}
+	if c.Log.Format == "" {
+		c.Log.Format = def.Log.Format
+	}
+	switch c.Log.Format {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unknown log format: %s", c.Log.Format)
+	}
+	if c.Log.ClientLevel == "" {
+		c.Log.ClientLevel = def.Log.ClientLevel
+	}
+	switch c.Log.ClientLevel {
+	case "debug", "info", "notice", "warning", "warn", "error", "critical", "alert", "emergency", "off":
+	default:
+		return fmt.Errorf("unknown client log level: %s", c.Log.ClientLevel)
+	}
+	if c.Log.MaxSizeMB == 0 {
+		c.Log.MaxSizeMB = def.Log.MaxSizeMB
+	}
+	if c.Log.MaxAgeDays == 0 {
+		c.Log.MaxAgeDays = def.Log.MaxAgeDays
+	}
+	if c.Log.MaxBackups == 0 {
+		c.Log.MaxBackups = def.Log.MaxBackups
+	}
+
+	if c.Transport.Type == "" {
+		c.Transport.Type = def.Transport.Type
+	}
+	if c.Transport.Addr == "" {
+		c.Transport.Addr = def.Transport.Addr
+	}
+	switch c.Transport.Type {
+	case "stdio", "http":
+	default:
+		return fmt.Errorf("unknown transport: %s", c.Transport.Type)
+	}
+	if (c.Transport.TLSCert == "") != (c.Transport.TLSKey == "") {
+		return fmt.Errorf("both tlsCert and tlsKey are required for TLS")
+	}
+
+	if c.AudioMonitor.IntervalMs == 0 {
+		c.AudioMonitor.IntervalMs = def.AudioMonitor.IntervalMs
+	}
+	if c.AudioMonitor.SilenceThresholdDB == 0 {
+		c.AudioMonitor.SilenceThresholdDB = def.AudioMonitor.SilenceThresholdDB
+	}
+	if c.AudioMonitor.SilenceSeconds == 0 {
+		c.AudioMonitor.SilenceSeconds = def.AudioMonitor.SilenceSeconds
+	}
+	if c.AudioMonitor.ClipThresholdDB == 0 {
+		c.AudioMonitor.ClipThresholdDB = def.AudioMonitor.ClipThresholdDB
+	}
+	if c.AudioMonitor.ClipSeconds == 0 {
+		c.AudioMonitor.ClipSeconds = def.AudioMonitor.ClipSeconds
+	}
+	switch {
+	case c.AudioMonitor.IntervalMs < 50:
+		return fmt.Errorf("audio monitor interval must be at least 50 ms: %d", c.AudioMonitor.IntervalMs)
+	case c.AudioMonitor.SilenceThresholdDB >= c.AudioMonitor.ClipThresholdDB:
+		return fmt.Errorf("silence threshold (%g dBFS) must be below the clip threshold (%g dBFS)", c.AudioMonitor.SilenceThresholdDB, c.AudioMonitor.ClipThresholdDB)
+	case c.AudioMonitor.SilenceSeconds < 0:
+		return fmt.Errorf("silence seconds must not be negative: %g", c.AudioMonitor.SilenceSeconds)
+	case c.AudioMonitor.ClipSeconds < 0 && c.AudioMonitor.ClipSeconds != -1:
+		return fmt.Errorf("clip seconds must be -1 or positive: %g", c.AudioMonitor.ClipSeconds)
+	}
+
+	// readOnly は設定ファイルの層で Mode に解決し、環境変数やフラグの -mode で上書きできるようにする
+	if c.ReadOnly {
+		c.Mode = string(policy.ModeReadOnly)
+		c.ReadOnly = false
+	}
+	if c.Mode == "" {
+		c.Mode = def.Mode
+	}
+	mode, err := policy.ParseMode(c.Mode)
+	if err != nil {
+		return err
+	}
+	c.Mode = string(mode)
+
+	for _, pattern := range c.Tools {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+	}
+
+	if len(c.Instances) == 0 {
+		c.Instances = def.Instances
+		return nil
+	}
+
+	names := map[string]struct{}{}
+	defaults := 0
+	for i := range c.Instances {
+		in := &c.Instances[i]
+		if in.Name == "" {
+			return fmt.Errorf("instance #%d has no name", i+1)
+		}
+		if _, ok := names[in.Name]; ok {
+			return fmt.Errorf("duplicated instance name: %s", in.Name)
+		}
+		names[in.Name] = struct{}{}
+
+		if in.Host == "" {
+			in.Host = DefaultHost
+		}
+		if in.Port == 0 {
+			in.Port = DefaultPort
+		}
+		if in.TCP && in.TCPPort == 0 {
+			in.TCPPort = DefaultTCPPort
+		}
+		if in.Default {
+			defaults++
+		}
+	}
+
+	switch defaults {
+	case 0:
+		c.Instances[0].Default = true
+	case 1:
+	default:
+		return fmt.Errorf("only one instance can be default")
+	}
+	return nil
+}
+
+// ToolAllowed reports whether the tool is in the allowlist.
+func (c *Config) ToolAllowed(name string) bool {
+	if len(c.Tools) == 0 {
+		return true
+	}
+	for _, pattern := range c.Tools {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultInstance returns the instance marked as default.
+func (c *Config) DefaultInstance() Instance {
+	for _, in := range c.Instances {
+		if in.Default {
+			return in
+		}
+	}
+	return c.Instances[0]
+}
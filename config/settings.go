@@ -0,0 +1,285 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/FlowingSPDG/mcp-vmix/policy"
+)
+
+// Setting is a configuration value which can be overridden by an environment variable and a command-line flag.
+// Command-line flags take precedence over environment variables, which take precedence over the configuration file.
+type Setting struct {
+	Flag  string
+	Env   string
+	Usage string
+	Bool  bool // the flag does not need a value
+	Set   func(c *Config, value string) error
+}
+
+// Settings lists every setting which can be overridden.
+var Settings = []Setting{
+	{
+		Flag:  "log-level",
+		Env:   "VMIX_MCP_LOG_LEVEL",
+		Usage: "log level: debug, info, warn or error",
+		Set:   func(c *Config, v string) error { c.Log.Level = strings.ToLower(v); return nil },
+	},
+	{
+		Flag:  "log-path",
+		Env:   "VMIX_MCP_LOG_PATH",
+		Usage: "log file path. empty writes logs/vmix-mcp.log under the config directory",
+		Set:   func(c *Config, v string) error { c.Log.Path = v; return nil },
+	},
+	{
+		Flag:  "log-format",
+		Env:   "VMIX_MCP_LOG_FORMAT",
+		Usage: "log format: text or json",
+		Set:   func(c *Config, v string) error { c.Log.Format = strings.ToLower(v); return nil },
+	},
+	{
+		Flag:  "log-max-size",
+		Env:   "VMIX_MCP_LOG_MAX_SIZE",
+		Usage: "rotate the log file when it grows over this size in MB. -1 disables rotation",
+		Set:   func(c *Config, v string) error { return setInt(&c.Log.MaxSizeMB, v) },
+	},
+	{
+		Flag:  "log-max-age",
+		Env:   "VMIX_MCP_LOG_MAX_AGE",
+		Usage: "remove rotated log files older than this many days. -1 keeps them",
+		Set:   func(c *Config, v string) error { return setInt(&c.Log.MaxAgeDays, v) },
+	},
+	{
+		Flag:  "log-max-backups",
+		Env:   "VMIX_MCP_LOG_MAX_BACKUPS",
+		Usage: "number of rotated log files to keep. -1 keeps all",
+		Set:   func(c *Config, v string) error { return setInt(&c.Log.MaxBackups, v) },
+	},
+	{
+		Flag:  "log-stderr",
+		Env:   "VMIX_MCP_LOG_STDERR",
+		Usage: "mirror the log to stderr",
+		Bool:  true,
+		Set:   func(c *Config, v string) error { return setBool(&c.Log.Stderr, v) },
+	},
+	{
+		Flag:  "client-log-level",
+		Env:   "VMIX_MCP_CLIENT_LOG_LEVEL",
+		Usage: "level of log messages sent to the MCP client until it selects one: debug, info, notice, warning, error or off",
+		Set:   func(c *Config, v string) error { c.Log.ClientLevel = strings.ToLower(v); return nil },
+	},
+	{
+		Flag:  "audit-path",
+		Env:   "VMIX_MCP_AUDIT_PATH",
+		Usage: "audit log file path (JSON lines). empty writes audit.jsonl under the config directory",
+		Set:   func(c *Config, v string) error { c.Audit.Path = v; return nil },
+	},
+	{
+		Flag:  "no-audit",
+		Env:   "VMIX_MCP_NO_AUDIT",
+		Usage: "disable the audit log",
+		Bool:  true,
+		Set:   func(c *Config, v string) error { return setBool(&c.Audit.Disabled, v) },
+	},
+	{
+		Flag:  "transport",
+		Env:   "VMIX_MCP_TRANSPORT",
+		Usage: "MCP transport: stdio or http. http serves Streamable HTTP at /mcp and HTTP+SSE at /sse",
+		Set:   func(c *Config, v string) error { c.Transport.Type = strings.ToLower(v); return nil },
+	},
+	{
+		Flag:  "addr",
+		Env:   "VMIX_MCP_ADDR",
+		Usage: "bind address of the http transport (default " + DefaultHTTPAddr + ")",
+		Set:   func(c *Config, v string) error { c.Transport.Addr = v; return nil },
+	},
+	{
+		Flag:  "token",
+		Env:   "VMIX_MCP_TOKEN",
+		Usage: "bearer token required from http clients. empty disables authentication",
+		Set:   func(c *Config, v string) error { c.Transport.Token = v; return nil },
+	},
+	{
+		Flag:  "tls-cert",
+		Env:   "VMIX_MCP_TLS_CERT",
+		Usage: "TLS certificate file of the http transport",
+		Set:   func(c *Config, v string) error { c.Transport.TLSCert = v; return nil },
+	},
+	{
+		Flag:  "tls-key",
+		Env:   "VMIX_MCP_TLS_KEY",
+		Usage: "TLS key file of the http transport",
+		Set:   func(c *Config, v string) error { c.Transport.TLSKey = v; return nil },
+	},
+	{
+		Flag:  "mode",
+		Env:   "VMIX_MCP_MODE",
+		Usage: "safety mode: full, preview-only (no program/output changes) or read-only",
+		Set:   func(c *Config, v string) error { c.Mode = strings.ToLower(v); return nil },
+	},
+	{
+		Flag:  "read-only",
+		Env:   "VMIX_MCP_READ_ONLY",
+		Usage: "shorthand of -mode read-only",
+		Bool:  true,
+		Set: func(c *Config, v string) error {
+			var readOnly bool
+			if err := setBool(&readOnly, v); err != nil {
+				return err
+			}
+			// ReadOnly ではなく Mode に反映し、同じ層の -mode と後から指定した方を優先する
+			switch {
+			case readOnly:
+				c.Mode = string(policy.ModeReadOnly)
+			case c.Mode == string(policy.ModeReadOnly):
+				c.Mode = ""
+			}
+			return nil
+		},
+	},
+	{
+		Flag:  "confirm",
+		Env:   "VMIX_MCP_CONFIRM",
+		Usage: "require a confirmation token for stop streaming, stop recording and fade to black",
+		Bool:  true,
+		Set:   func(c *Config, v string) error { return setBool(&c.Confirm, v) },
+	},
+	{
+		Flag:  "tools",
+		Env:   "VMIX_MCP_TOOLS",
+		Usage: "comma separated allowlist of tool names. glob patterns such as vmix_*_recording are accepted",
+		Set:   func(c *Config, v string) error { c.Tools = splitList(v); return nil },
+	},
+	{
+		Flag:  "screenshot-dir",
+		Env:   "VMIX_MCP_SCREENSHOT_DIR",
+		Usage: "directory where vMix saves screenshots for vmix_check_screenshot (default: the temp directory)",
+		Set:   func(c *Config, v string) error { c.ScreenshotDir = v; return nil },
+	},
+	{
+		Flag:  "audio-monitor",
+		Env:   "VMIX_MCP_AUDIO_MONITOR",
+		Usage: "enable the silence/clipping monitor of audio meters, which polls the state of every instance",
+		Bool:  true,
+		Set:   func(c *Config, v string) error { return setBool(&c.AudioMonitor.Enabled, v) },
+	},
+	{
+		Flag:  "audio-monitor-interval",
+		Env:   "VMIX_MCP_AUDIO_MONITOR_INTERVAL",
+		Usage: fmt.Sprintf("how often audio meters are sampled in milliseconds (default %d)", DefaultAudioMonitorIntervalMs),
+		Set:   func(c *Config, v string) error { return setInt(&c.AudioMonitor.IntervalMs, v) },
+	},
+	{
+		Flag:  "silence-threshold",
+		Env:   "VMIX_MCP_SILENCE_THRESHOLD",
+		Usage: fmt.Sprintf("level in dBFS below which an audio source is silent (default %d)", DefaultSilenceThresholdDB),
+		Set:   func(c *Config, v string) error { return setFloat(&c.AudioMonitor.SilenceThresholdDB, v) },
+	},
+	{
+		Flag:  "silence-seconds",
+		Env:   "VMIX_MCP_SILENCE_SECONDS",
+		Usage: fmt.Sprintf("seconds of silence before an alert is raised (default %d)", DefaultSilenceSeconds),
+		Set:   func(c *Config, v string) error { return setFloat(&c.AudioMonitor.SilenceSeconds, v) },
+	},
+	{
+		Flag:  "clip-threshold",
+		Env:   "VMIX_MCP_CLIP_THRESHOLD",
+		Usage: fmt.Sprintf("level in dBFS at or above which an audio source is clipping (default %d)", DefaultClipThresholdDB),
+		Set:   func(c *Config, v string) error { return setFloat(&c.AudioMonitor.ClipThresholdDB, v) },
+	},
+	{
+		Flag:  "clip-seconds",
+		Env:   "VMIX_MCP_CLIP_SECONDS",
+		Usage: fmt.Sprintf("seconds of clipping before an alert is raised. -1 alerts at the first sample (default %d)", DefaultClipSeconds),
+		Set:   func(c *Config, v string) error { return setFloat(&c.AudioMonitor.ClipSeconds, v) },
+	},
+	{
+		Flag:  "instances",
+		Env:   "VMIX_MCP_INSTANCES",
+		Usage: "comma separated vMix instances as name=host[:port]. replaces the instances of the config file",
+		Set: func(c *Config, v string) error {
+			instances, err := ParseInstances(v)
+			if err != nil {
+				return err
+			}
+			c.Instances = instances
+			return nil
+		},
+	},
+	{
+		Flag:  "default-instance",
+		Env:   "VMIX_MCP_DEFAULT_INSTANCE",
+		Usage: "name of the vMix instance used when a tool does not specify one",
+		Set: func(c *Config, v string) error {
+			found := false
+			for i := range c.Instances {
+				c.Instances[i].Default = c.Instances[i].Name == v
+				found = found || c.Instances[i].Default
+			}
+			if !found {
+				return fmt.Errorf("unknown instance: %s", v)
+			}
+			return nil
+		},
+	},
+}
+
+// ParseInstances parses instances written as "name=host[:port],...".
+func ParseInstances(s string) ([]Instance, error) {
+	var instances []Instance
+	for _, spec := range splitList(s) {
+		name, addr, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || addr == "" {
+			return nil, fmt.Errorf("invalid instance %q, use name=host[:port]", spec)
+		}
+		in := Instance{Name: name, Host: addr}
+		if host, port, err := net.SplitHostPort(addr); err == nil {
+			p, err := strconv.Atoi(port)
+			if err != nil {
+				return nil, fmt.Errorf("invalid port of instance %q: %w", spec, err)
+			}
+			in.Host, in.Port = host, p
+		}
+		instances = append(instances, in)
+	}
+	return instances, nil
+}
+
+func setInt(dst *int, v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", v)
+	}
+	*dst = n
+	return nil
+}
+
+func setFloat(dst *float64, v string) error {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", v)
+	}
+	*dst = f
+	return nil
+}
+
+func setBool(dst *bool, v string) error {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", v)
+	}
+	*dst = b
+	return nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,244 @@
+package mcpvmix
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/samber/lo"
+)
+
+// confirmationTTL is how long a confirmation token can be used.
+const confirmationTTL = 60 * time.Second
+
+// confirmationStore keeps one-time tokens of destructive actions waiting for confirmation.
+type confirmationStore struct {
+	mu     sync.Mutex
+	tokens map[string]pendingConfirmation
+}
+
+type pendingConfirmation struct {
+	action  string // function and parameters, so a token cannot confirm another action
+	target  string
+	state   string // the vMix state the user confirmed, so a token cannot confirm an action on a changed state
+	expires time.Time
+}
+
+// confirmation describes a destructive action to the user and the state its token is bound to.
+type confirmation struct {
+	tool   string
+	action string
+	// describe explains what the action will do in the state.
+	describe func(s *vmixState) string
+	// state returns the part of the state describe depends on. A token is rejected when it changed.
+	state func(s *vmixState) string
+}
+
+func newConfirmationStore() *confirmationStore {
+	return &confirmationStore{tokens: map[string]pendingConfirmation{}}
+}
+
+// issue returns a new token for action on target in the state.
+func (s *confirmationStore) issue(action string, target vmixTarget, state string) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for t, c := range s.tokens {
+		if now.After(c.expires) {
+			delete(s.tokens, t)
+		}
+	}
+	s.tokens[token] = pendingConfirmation{action: action, target: target.String(), state: state, expires: now.Add(confirmationTTL)}
+	return token, nil
+}
+
+// consume checks the token against the current state and invalidates it.
+func (s *confirmationStore) consume(token, action string, target vmixTarget, state string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.tokens[token]
+	if !ok {
+		return fmt.Errorf("unknown confirmation token %q. Call the tool without a token to get a new one", token)
+	}
+	delete(s.tokens, token)
+	if time.Now().After(c.expires) {
+		return fmt.Errorf("confirmation token %q has expired. Call the tool without a token to get a new one", token)
+	}
+	if c.action != action || c.target != target.String() {
+		return fmt.Errorf("confirmation token %q was issued for %s on %s", token, c.action, c.target)
+	}
+	if c.state != state {
+		return fmt.Errorf("vMix changed since confirmation token %q was issued (%s, now %s). Call the tool without a token and confirm the new state", token, c.state, state)
+	}
+	return nil
+}
+
+// confirm implements the two-phase call of destructive tools.
+// It returns a response asking for confirmation when the caller has to call the tool again, or nil when the action can be performed.
+// The state is read again when a token is given, so the action is not performed if vMix changed since the user agreed.
+func (m *mcpVmix) confirm(ctx context.Context, c confirmation, token string, target vmixTarget) (*mcp_golang.ToolResponse, error) {
+	if !m.confirmDestructive {
+		return nil, nil
+	}
+
+	state, err := m.pool.refresh(target)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	if token != "" {
+		if err := m.confirmations.consume(token, c.action, target, c.state(state)); err != nil {
+			errMsg := fmt.Sprintf("Failed to confirm %s: %v", c.action, err)
+			m.log(ctx).Error(errMsg, "instance", target)
+			return nil, fmt.Errorf(errMsg)
+		}
+		m.log(ctx).Info("Confirmed destructive action", "instance", target, "action", c.action)
+		return nil, nil
+	}
+
+	token, err = m.confirmations.issue(c.action, target, c.state(state))
+	if err != nil {
+		m.log(ctx).Error(err.Error(), "instance", target)
+		return nil, err
+	}
+
+	m.log(ctx).Info("Waiting for confirmation", "instance", target, "action", c.action)
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf(
+		"Confirmation required on %s: %s To proceed, call %s again with the same arguments and confirmationToken %q within %d seconds.",
+		target, c.describe(state), c.tool, token, int(confirmationTTL.Seconds()),
+	))), nil
+}
+
+// flagState formats a flag of the vMix state for confirmation errors.
+func flagState(name string, on bool) string {
+	return fmt.Sprintf("%s %s", name, lo.Ternary(on, "on", "off"))
+}
+
+// formatSeconds formats seconds as hh:mm:ss.
+func formatSeconds(seconds int) string {
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// liveStreams returns the stream channels which are live. vMix older than 26 only reports whether any stream is live.
+func (s *vmixState) liveStreams() ([]int, bool) {
+	channels := []*bool{s.Streaming.Channel1, s.Streaming.Channel2, s.Streaming.Channel3, s.Streaming.Channel4, s.Streaming.Channel5}
+	known := false
+	var live []int
+	for i, on := range channels {
+		if on == nil {
+			continue
+		}
+		known = true
+		if *on {
+			live = append(live, i+1)
+		}
+	}
+	return live, known
+}
+
+// stopStreamingConfirmation is bound to whether the stream is live.
+// liveFor returns how long a stream channel has been live, see streamClock.liveFor.
+func stopStreamingConfirmation(stream int, liveFor func(channel int) string) confirmation {
+	return confirmation{
+		tool:   "vmix_stop_streaming",
+		action: fmt.Sprintf("StopStreaming %d", stream),
+		describe: func(s *vmixState) string {
+			live, known := s.liveStreams()
+			switch {
+			case !s.Streaming.On:
+				return "No stream is live, this will do nothing."
+			case !known:
+				return fmt.Sprintf("Streaming is live%s, this will end stream %d for viewers.", liveFor(0), stream)
+			}
+			for _, n := range live {
+				if n == stream {
+					others := len(live) - 1
+					if others == 0 {
+						return fmt.Sprintf("Stream %d is live%s and is the only live stream, this will end it for viewers.", stream, liveFor(stream))
+					}
+					return fmt.Sprintf("Stream %d is live%s, this will end it for viewers. %d other stream(s) stay live.", stream, liveFor(stream), others)
+				}
+			}
+			return fmt.Sprintf("Stream %d is not live, this will do nothing. Live streams: %v.", stream, live)
+		},
+		state: func(s *vmixState) string {
+			live, known := s.liveStreams()
+			if !known {
+				return flagState("streaming", s.Streaming.On)
+			}
+			return flagState(fmt.Sprintf("stream %d", stream), s.Streaming.On && lo.Contains(live, stream))
+		},
+	}
+}
+
+// stopRecordingConfirmation is bound to whether recording is running.
+var stopRecordingConfirmation = confirmation{
+	tool:     "vmix_stop_recording",
+	action:   "StopRecording",
+	describe: describeStopRecording,
+	state:    func(s *vmixState) string { return flagState("recording", s.Recording.On) },
+}
+
+// fadeToBlackConfirmation is bound to whether fade to black is active, since the call toggles it.
+var fadeToBlackConfirmation = confirmation{
+	tool:     "vmix_fade_to_black",
+	action:   "FadeToBlack",
+	describe: describeFadeToBlack,
+	state:    func(s *vmixState) string { return flagState("fade to black", s.FadeToBlack) },
+}
+
+func describeStopRecording(s *vmixState) string {
+	if !s.Recording.On {
+		return "Recording is not running, this will do nothing."
+	}
+	if s.Recording.Duration != nil {
+		return fmt.Sprintf("Recording has been running for %s, this will stop it.", formatSeconds(*s.Recording.Duration))
+	}
+	return "Recording is running, this will stop it."
+}
+
+func describeFadeToBlack(s *vmixState) string {
+	if s.FadeToBlack {
+		return "Fade to black is active, this will fade the program output back in."
+	}
+
+	var b strings.Builder
+	b.WriteString("Program is showing ")
+	if in, ok := s.input(fmt.Sprint(s.Active)); ok {
+		fmt.Fprintf(&b, "input %d %q", in.Number, in.Title)
+	} else {
+		fmt.Fprintf(&b, "input %d", s.Active)
+	}
+	var outputs []string
+	if s.Streaming.On {
+		outputs = append(outputs, "streaming")
+	}
+	if s.Recording.On {
+		outputs = append(outputs, "recording")
+	}
+	if s.External {
+		outputs = append(outputs, "external output")
+	}
+	switch len(outputs) {
+	case 0:
+	case 1:
+		fmt.Fprintf(&b, " and %s is on", outputs[0])
+	default:
+		fmt.Fprintf(&b, " and %s are on", strings.Join(outputs, ", "))
+	}
+	b.WriteString(", this will fade the program output to black for viewers.")
+	return b.String()
+}
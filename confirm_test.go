@@ -0,0 +1,65 @@
+package mcpvmix
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+func TestConfirmationState(t *testing.T) {
+	target := vmixTarget{Name: "main", Host: "127.0.0.1", Port: 8088}
+	store := newConfirmationStore()
+	live := &vmixState{Streaming: stateStreaming{On: true, Channel1: lo.ToPtr(true), Channel2: lo.ToPtr(false)}}
+	stopped := &vmixState{Streaming: stateStreaming{On: false, Channel1: lo.ToPtr(false), Channel2: lo.ToPtr(false)}}
+	c := stopStreamingConfirmation(1, func(int) string { return "" })
+
+	token, err := store.issue(c.action, target, c.state(live))
+	if err != nil {
+		t.Fatalf("issue() error = %v", err)
+	}
+	if err := store.consume(token, c.action, target, c.state(stopped)); err == nil || !strings.Contains(err.Error(), "changed") {
+		t.Errorf("consume() after the stream ended error = %v, want a changed state error", err)
+	}
+	if err := store.consume(token, c.action, target, c.state(live)); err == nil {
+		t.Error("consume() of a used token succeeded")
+	}
+
+	token, err = store.issue(c.action, target, c.state(live))
+	if err != nil {
+		t.Fatalf("issue() error = %v", err)
+	}
+	// Another stream going live does not matter for stream 1.
+	live.Streaming.Channel2 = lo.ToPtr(true)
+	if err := store.consume(token, c.action, target, c.state(live)); err != nil {
+		t.Errorf("consume() in the same state error = %v", err)
+	}
+}
+
+func TestStreamClock(t *testing.T) {
+	c := newStreamClock()
+	now := time.Now()
+	state := func(ch1, ch2 bool) *vmixState {
+		return &vmixState{Streaming: stateStreaming{On: ch1 || ch2, Channel1: lo.ToPtr(ch1), Channel2: lo.ToPtr(ch2)}}
+	}
+
+	// Stream 1 was live before the first read, stream 2 goes live later.
+	c.update("main", state(true, false), now.Add(-time.Hour))
+	c.update("main", state(true, true), now.Add(-90*time.Second))
+
+	if got := c.liveFor("main", 1); !strings.HasPrefix(got, " for at least 01:00:") {
+		t.Errorf("liveFor(1) = %q, want at least an hour", got)
+	}
+	if got := c.liveFor("main", 2); !strings.HasPrefix(got, " for 00:01:3") {
+		t.Errorf("liveFor(2) = %q, want 90 seconds", got)
+	}
+
+	c.update("main", state(true, false), now)
+	if got := c.liveFor("main", 2); got != "" {
+		t.Errorf("liveFor(2) after it ended = %q, want empty", got)
+	}
+	if got := c.liveFor("other", 1); got != "" {
+		t.Errorf("liveFor() of an unknown instance = %q, want empty", got)
+	}
+}
@@ -0,0 +1,218 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	vmixtcp "github.com/FlowingSPDG/vmix-go/tcp"
+
+	"github.com/FlowingSPDG/mcp-vmix/logger"
+	"github.com/FlowingSPDG/mcp-vmix/tcpapi"
+)
+
+const (
+	tcpDialTimeout    = 3 * time.Second
+	tcpRequestTimeout = 5 * time.Second
+	// maxRecentEvents is how many activator events are kept per instance.
+	maxRecentEvents = 200
+)
+
+// vmixEvent is an activator or tally event received from an instance.
+type vmixEvent struct {
+	Time   time.Time
+	Target vmixTarget
+	tcpapi.Event
+}
+
+// eventHub keeps one TCP API connection per instance which uses the TCP API.
+// Connections subscribe to TALLY and ACTS so state changes are pushed instead of polled.
+type eventHub struct {
+	logger logger.Logger
+
+	mu        sync.Mutex
+	sessions  map[string]*tcpSession
+	listeners map[int]func(vmixEvent)
+	nextID    int
+	closed    bool // no connections are made after close
+}
+
+type tcpSession struct {
+	client *tcpapi.Client
+	tally  []vmixtcp.TallyStatus
+	recent []vmixEvent
+}
+
+func newEventHub(logger logger.Logger) *eventHub {
+	return &eventHub{
+		logger:    logger,
+		sessions:  map[string]*tcpSession{},
+		listeners: map[int]func(vmixEvent){},
+	}
+}
+
+func tcpKey(target vmixTarget) string {
+	return net.JoinHostPort(target.Host, strconv.Itoa(target.TCPPort))
+}
+
+// listen registers a handler called for every event of every instance. The returned function removes it.
+func (h *eventHub) listen(handler func(vmixEvent)) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id := h.nextID
+	h.nextID++
+	h.listeners[id] = handler
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.listeners, id)
+	}
+}
+
+// client returns the TCP API connection for the target, connecting and subscribing if needed.
+func (h *eventHub) client(target vmixTarget) (*tcpapi.Client, error) {
+	if target.TCPPort == 0 {
+		return nil, fmt.Errorf("TCP API is not enabled for vMix instance %s", target)
+	}
+	key := tcpKey(target)
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil, fmt.Errorf("the connections to vMix TCP API are closed")
+	}
+	if s, ok := h.sessions[key]; ok {
+		h.mu.Unlock()
+		return s.client, nil
+	}
+	h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), tcpDialTimeout)
+	defer cancel()
+	client, err := tcpapi.Dial(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		client.Close()
+		return nil, fmt.Errorf("the connections to vMix TCP API are closed")
+	}
+	if s, ok := h.sessions[key]; ok {
+		// Someone else connected while dialing.
+		h.mu.Unlock()
+		client.Close()
+		return s.client, nil
+	}
+	h.sessions[key] = &tcpSession{client: client}
+	h.mu.Unlock()
+
+	client.OnEvent(func(ev tcpapi.Event) {
+		h.dispatch(target, key, ev)
+	})
+	go func() {
+		<-client.Done()
+		h.mu.Lock()
+		if s, ok := h.sessions[key]; ok && s.client == client {
+			delete(h.sessions, key)
+		}
+		closed := h.closed
+		h.mu.Unlock()
+		if closed {
+			h.logger.Debug("Closed vMix TCP API connection", "instance", target, "addr", key)
+			return
+		}
+		h.logger.Warn("Disconnected from vMix TCP API", "instance", target, "addr", key, "error", client.Err())
+	}()
+
+	for _, event := range []string{tcpapi.EventTally, tcpapi.EventActs} {
+		ctx, cancel := context.WithTimeout(context.Background(), tcpRequestTimeout)
+		err := client.Subscribe(ctx, event)
+		cancel()
+		if err != nil {
+			h.logger.Warn("Failed to subscribe vMix TCP API events", "instance", target, "event", event, "error", err)
+		}
+	}
+
+	h.logger.Info("Connected to vMix TCP API", "instance", target, "addr", key)
+	return client, nil
+}
+
+func (h *eventHub) dispatch(target vmixTarget, key string, ev tcpapi.Event) {
+	event := vmixEvent{Time: time.Now(), Target: target, Event: ev}
+
+	h.mu.Lock()
+	if s, ok := h.sessions[key]; ok {
+		if ev.Type == tcpapi.EventTally {
+			s.tally = ev.Tally
+		}
+		s.recent = append(s.recent, event)
+		if len(s.recent) > maxRecentEvents {
+			s.recent = s.recent[len(s.recent)-maxRecentEvents:]
+		}
+	}
+	listeners := make([]func(vmixEvent), 0, len(h.listeners))
+	for _, l := range h.listeners {
+		listeners = append(listeners, l)
+	}
+	h.mu.Unlock()
+
+	for _, l := range listeners {
+		l(event)
+	}
+}
+
+// watch keeps TCP API connections of the targets alive so events keep flowing, until ctx is done.
+// The connections are closed when it returns.
+func (h *eventHub) watch(ctx context.Context, targets []vmixTarget, interval time.Duration) {
+	defer h.close()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, target := range targets {
+			if _, err := h.client(target); err != nil {
+				h.logger.Debug("Failed to connect vMix TCP API", "instance", target, "error", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// close closes every TCP API connection and stops new ones from being made.
+func (h *eventHub) close() {
+	h.mu.Lock()
+	h.closed = true
+	clients := make([]*tcpapi.Client, 0, len(h.sessions))
+	for _, s := range h.sessions {
+		clients = append(clients, s.client)
+	}
+	h.mu.Unlock()
+
+	for _, c := range clients {
+		c.Close()
+	}
+}
+
+// recent returns up to limit latest events and the last tally of the target.
+func (h *eventHub) recent(target vmixTarget, limit int) ([]vmixEvent, []vmixtcp.TallyStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.sessions[tcpKey(target)]
+	if !ok {
+		return nil, nil
+	}
+	events := s.recent
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return append([]vmixEvent(nil), events...), append([]vmixtcp.TallyStatus(nil), s.tally...)
+}
@@ -0,0 +1,54 @@
+package mcpvmix
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/FlowingSPDG/mcp-vmix/logger"
+	"github.com/FlowingSPDG/mcp-vmix/tcpapi/tcpapitest"
+)
+
+func TestEventHubWatchClosesClients(t *testing.T) {
+	srv, err := tcpapitest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start fake server: %v", err)
+	}
+	defer srv.Close()
+	host, port, _ := net.SplitHostPort(srv.Addr())
+	tcpPort, _ := strconv.Atoi(port)
+	target := vmixTarget{Name: "main", Host: host, Port: 8088, TCPPort: tcpPort}
+
+	log, _ := logger.New(logger.Options{})
+	h := newEventHub(log)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		h.watch(ctx, []vmixTarget{target}, time.Hour)
+		close(done)
+	}()
+
+	var session *tcpSession
+	for deadline := time.Now().Add(time.Second); session == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("watch() did not connect")
+		}
+		time.Sleep(10 * time.Millisecond)
+		h.mu.Lock()
+		session = h.sessions[tcpKey(target)]
+		h.mu.Unlock()
+	}
+
+	cancel()
+	<-done
+	select {
+	case <-session.client.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the client is still open after watch() returned")
+	}
+	if _, err := h.client(target); err == nil {
+		t.Error("client() connected after the hub was closed")
+	}
+}
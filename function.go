@@ -0,0 +1,210 @@
+package mcpvmix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/FlowingSPDG/mcp-vmix/functions"
+)
+
+// confirmedFunctions are functions which can stop what viewers see. They have dedicated tools which ask for confirmation,
+// so vmix_function does not send them while confirmation is enabled.
+var confirmedFunctions = map[string]string{
+	"FadeToBlack":        "vmix_fade_to_black",
+	"StopRecording":      "vmix_stop_recording",
+	"StartStopRecording": "vmix_stop_recording",
+	"StopStreaming":      "vmix_stop_streaming",
+	"StartStopStreaming": "vmix_stop_streaming",
+}
+
+// VmixFunction implements MCPvMix.
+func (m *mcpVmix) VmixFunction(ctx context.Context, arguments VmixFunctionArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	catalogue, err := functions.Default()
+	if err != nil {
+		m.log(ctx).Error(err.Error(), "instance", target)
+		return nil, err
+	}
+	call := functions.Call{
+		Function:      arguments.Function,
+		Input:         arguments.Input,
+		Value:         arguments.Value,
+		Duration:      arguments.Duration,
+		Mix:           arguments.Mix,
+		SelectedName:  arguments.SelectedName,
+		SelectedIndex: arguments.SelectedIndex,
+	}
+	function, err := catalogue.Validate(call)
+	// カタログにない関数は新しいvMixの関数かもしれないので警告して送る
+	var warning string
+	if errors.Is(err, functions.ErrUnknownFunction) {
+		warning = fmt.Sprintf("Warning: %v (it is not in the function catalogue, so only its parameters were checked)", err)
+		m.log(ctx).Warn("Sending a vMix function which is not in the catalogue", "instance", target, "function", function.Name)
+		err = nil
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("Invalid vMix function call, nothing was sent to vMix: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	// 状態が取得できない場合は送信時のエラーに任せる
+	if state, err := m.pool.state(target); err == nil {
+		err := function.Supports(state.Version, state.Edition)
+		if err == nil && call.Mix != nil {
+			err = state.checkMix(*call.Mix)
+		}
+		if err != nil {
+			errMsg := fmt.Sprintf("Unsupported vMix function call, nothing was sent to vMix: %v", err)
+			m.log(ctx).Error(errMsg, "instance", target)
+			return nil, fmt.Errorf(errMsg)
+		}
+	}
+	if tool, ok := confirmedFunctions[function.Name]; ok && m.confirmDestructive {
+		errMsg := fmt.Sprintf("%s needs confirmation, use %s instead. Nothing was sent to vMix", function.Name, tool)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	query := function.Query(call)
+	m.log(ctx).Info("Attempting to send vMix function", "instance", target, "function", function.Name, "input", call.Input, "query", formatQuery(query))
+
+	if err := m.sendFunction(ctx, target, function.Name, query); err != nil {
+		errMsg := fmt.Sprintf("Failed to send %s: %v", function.Name, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	m.log(ctx).Info("Successfully sent vMix function", "instance", target, "function", function.Name, "input", call.Input)
+	text := fmt.Sprintf("Sent %s(%s) to %s", function.Name, formatQuery(query), target)
+	if warning != "" {
+		text += "\n" + warning
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+}
+
+// sendCatalogueFunction validates call against the catalogue and the edition/version of the target, and sends it.
+// action describes the call in errors and logs, e.g. "zoom camera 2 in".
+func (m *mcpVmix) sendCatalogueFunction(ctx context.Context, target vmixTarget, action string, call functions.Call) error {
+	catalogue, err := functions.Default()
+	if err != nil {
+		m.log(ctx).Error(err.Error(), "instance", target)
+		return err
+	}
+	function, err := catalogue.Validate(call)
+	if err == nil {
+		// 状態が取得できない場合は送信時のエラーに任せる
+		if state, stateErr := m.pool.state(target); stateErr == nil {
+			err = function.Supports(state.Version, state.Edition)
+		}
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to %s, nothing was sent to vMix: %v", action, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return fmt.Errorf(errMsg)
+	}
+
+	log := m.log(ctx).With("instance", target, "function", function.Name, "input", call.Input)
+	log.Info("Attempting to send vMix function", "action", action)
+	if err := m.sendFunction(ctx, target, function.Name, function.Query(call)); err != nil {
+		errMsg := fmt.Sprintf("Failed to %s: %v", action, err)
+		log.Error(errMsg)
+		return fmt.Errorf(errMsg)
+	}
+	log.Info("Successfully sent vMix function", "action", action)
+	return nil
+}
+
+// formatQuery formats query as Key=Value pairs sorted by key.
+func formatQuery(query map[string]string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%s", k, query[k])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// defaultSearchLimit is how many functions vmix_search_functions returns when no limit is given.
+const defaultSearchLimit = 20
+
+// SearchFunctions implements MCPvMix.
+func (m *mcpVmix) SearchFunctions(ctx context.Context, arguments SearchFunctionsArguments) (*mcp_golang.ToolResponse, error) {
+	catalogue, err := functions.Default()
+	if err != nil {
+		m.log(ctx).Error(err.Error())
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "vMix function catalogue version %d (vMix %s)\n", catalogue.Version, catalogue.VMix)
+
+	if arguments.Query == "" && arguments.Category == "" {
+		b.WriteString("Categories:\n")
+		for _, category := range catalogue.Categories() {
+			fmt.Fprintf(&b, "- %s (%d functions)\n", category, len(catalogue.Search("", category, 0)))
+		}
+		b.WriteString("Pass a query or a category to list functions.")
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(b.String())), nil
+	}
+
+	limit := arguments.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	all := catalogue.Search(arguments.Query, arguments.Category, 0)
+	found := all[:min(limit, len(all))]
+	m.log(ctx).Info("Found vMix functions", "query", arguments.Query, "category", arguments.Category, "count", len(all))
+
+	if len(found) == 0 {
+		fmt.Fprintf(&b, "No functions match %q. Categories: %s", arguments.Query, strings.Join(catalogue.Categories(), ", "))
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(b.String())), nil
+	}
+	fmt.Fprintf(&b, "Showing %d of %d matching functions. Optional parameters end with ?.\n\n", len(found), len(all))
+	for _, f := range found {
+		b.WriteString(describeFunction(f))
+		b.WriteString("\n")
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(b.String())), nil
+}
+
+// describeFunction formats the signature, description, value format and requirements of f.
+func describeFunction(f functions.Function) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s [%s]\n  %s\n", f.Signature(), f.Category, f.Description)
+	if v := f.Value; v != nil {
+		format := string(v.Type)
+		switch {
+		case len(v.Values) > 0:
+			format = strings.Join(v.Values, "|")
+		case v.Min != nil && v.Max != nil:
+			format = fmt.Sprintf("%s %g to %g", v.Type, *v.Min, *v.Max)
+		case v.Min != nil:
+			format = fmt.Sprintf("%s >= %g", v.Type, *v.Min)
+		}
+		fmt.Fprintf(&b, "  Value: %s. %s\n", format, v.Description)
+	}
+	var requires []string
+	if f.Since != "" {
+		requires = append(requires, fmt.Sprintf("vMix %s or later", f.Since))
+	}
+	if f.Edition != "" {
+		requires = append(requires, fmt.Sprintf("%s edition or higher", f.Edition))
+	}
+	if len(requires) > 0 {
+		fmt.Fprintf(&b, "  Requires: %s\n", strings.Join(requires, ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,411 @@
+// Package functions is the catalogue of vMix shortcut functions.
+// Calls are validated against it before they are sent, so a model can use functions without a dedicated tool
+// and still gets a useful error instead of a silently ignored request.
+package functions
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed catalogue.yaml
+var catalogueYAML []byte
+
+// Usage is whether a function takes a parameter. Empty means the parameter is not accepted.
+type Usage string
+
+const (
+	Required Usage = "required"
+	Optional Usage = "optional"
+)
+
+// ValueType is the type of the Value parameter.
+type ValueType string
+
+const (
+	TypeString ValueType = "string"
+	TypeInt    ValueType = "int"
+	TypeFloat  ValueType = "float"
+	TypeEnum   ValueType = "enum"
+)
+
+// Value describes the Value parameter of a function.
+type Value struct {
+	Type        ValueType `yaml:"type" json:"type"`
+	Description string    `yaml:"description" json:"description"`
+	Required    bool      `yaml:"required,omitempty" json:"required,omitempty"`
+	Values      []string  `yaml:"values,omitempty" json:"values,omitempty"` // enum only
+	Min         *float64  `yaml:"min,omitempty" json:"min,omitempty"`
+	Max         *float64  `yaml:"max,omitempty" json:"max,omitempty"`
+}
+
+// Function is a vMix shortcut function and the parameters it takes.
+type Function struct {
+	Name        string `yaml:"name" json:"name"`
+	Category    string `yaml:"category" json:"category"`
+	Description string `yaml:"description" json:"description"`
+	Input       Usage  `yaml:"input,omitempty" json:"input,omitempty"`
+	Value       *Value `yaml:"value,omitempty" json:"value,omitempty"`
+	Duration    Usage  `yaml:"duration,omitempty" json:"duration,omitempty"`
+	Mix         Usage  `yaml:"mix,omitempty" json:"mix,omitempty"`
+	// Selected is SelectedName or SelectedIndex of a title field.
+	Selected Usage `yaml:"selected,omitempty" json:"selected,omitempty"`
+
+	// Since is the first vMix version with the function, e.g. "24". Empty means any version.
+	Since string `yaml:"since,omitempty" json:"since,omitempty"`
+	// Edition is the lowest vMix edition with the function, e.g. "4K". Empty means any edition.
+	Edition string `yaml:"edition,omitempty" json:"edition,omitempty"`
+}
+
+// Catalogue is a list of functions looked up by name, case insensitive.
+type Catalogue struct {
+	// Version is the revision of the catalogue. It is bumped when functions are added or changed.
+	Version int `yaml:"version" json:"version"`
+	// VMix is the vMix version the catalogue was written for.
+	VMix      string     `yaml:"vmix" json:"vmix"`
+	Functions []Function `yaml:"functions" json:"functions"`
+
+	byName map[string]int
+}
+
+// Parse parses and checks a YAML catalogue.
+func Parse(data []byte) (*Catalogue, error) {
+	c := &Catalogue{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse function catalogue: %w", err)
+	}
+	c.byName = make(map[string]int, len(c.Functions))
+	for i, f := range c.Functions {
+		if err := f.check(); err != nil {
+			return nil, fmt.Errorf("invalid function %q in catalogue: %w", f.Name, err)
+		}
+		key := strings.ToLower(f.Name)
+		if _, ok := c.byName[key]; ok {
+			return nil, fmt.Errorf("duplicate function %q in catalogue", f.Name)
+		}
+		c.byName[key] = i
+	}
+	return c, nil
+}
+
+var loadDefault = sync.OnceValues(func() (*Catalogue, error) {
+	return Parse(catalogueYAML)
+})
+
+// Default returns the embedded catalogue.
+func Default() (*Catalogue, error) {
+	return loadDefault()
+}
+
+func (f Function) check() error {
+	if f.Name == "" {
+		return fmt.Errorf("name is empty")
+	}
+	for name, u := range map[string]Usage{"input": f.Input, "duration": f.Duration, "mix": f.Mix, "selected": f.Selected} {
+		if u != "" && u != Required && u != Optional {
+			return fmt.Errorf("unknown usage of %s: %s", name, u)
+		}
+	}
+	if f.Since != "" {
+		if _, ok := parseVersion(f.Since); !ok {
+			return fmt.Errorf("invalid since: %s", f.Since)
+		}
+	}
+	if f.Edition != "" && editionRank(f.Edition) < 0 {
+		return fmt.Errorf("unknown edition: %s", f.Edition)
+	}
+	if f.Value == nil {
+		return nil
+	}
+	switch f.Value.Type {
+	case TypeString, TypeInt, TypeFloat:
+	case TypeEnum:
+		if len(f.Value.Values) == 0 {
+			return fmt.Errorf("enum value has no values")
+		}
+	default:
+		return fmt.Errorf("unknown value type: %s", f.Value.Type)
+	}
+	return nil
+}
+
+// Lookup returns the function named name, case insensitive.
+func (c *Catalogue) Lookup(name string) (Function, bool) {
+	i, ok := c.byName[strings.ToLower(name)]
+	if !ok {
+		return Function{}, false
+	}
+	return c.Functions[i], true
+}
+
+// Suggest returns up to n function names similar to name, for error messages.
+func (c *Catalogue) Suggest(name string, n int) []string {
+	name = strings.ToLower(name)
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	for _, f := range c.Functions {
+		lower := strings.ToLower(f.Name)
+		d := levenshtein(name, lower)
+		if name != "" && strings.Contains(lower, name) {
+			d = 0
+		}
+		if d <= max(2, len(name)/3) {
+			candidates = append(candidates, candidate{f.Name, d})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int { return a.distance - b.distance })
+	names := make([]string, 0, n)
+	for _, c := range candidates[:min(n, len(candidates))] {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// Call is a function call with the parameters of the vMix API. Nil and empty parameters are not sent.
+type Call struct {
+	Function string
+	Input    string
+	Value    string
+	Duration *int
+	// Mix is counted like the vMix UI: 1 is the main mix and 2-16 are Mix 2-16.
+	// Query converts it to the Mix parameter of the API, which counts from 0.
+	Mix           *int
+	SelectedName  string
+	SelectedIndex *int
+}
+
+// MaxMix is the highest mix of vMix. Mix 1 is the main output.
+const MaxMix = 16
+
+// ErrUnknownFunction is returned by Validate when the function is not in the catalogue.
+// The catalogue can lag behind vMix, so callers may still send such a call after warning about it.
+var ErrUnknownFunction = errors.New("unknown vMix function")
+
+// unknownFunction is the function Validate returns for a name which is not in the catalogue.
+// It takes every parameter, so only the parameters themselves are checked.
+func unknownFunction(name string) Function {
+	return Function{
+		Name:     name,
+		Input:    Optional,
+		Value:    &Value{Type: TypeString},
+		Duration: Optional,
+		Mix:      Optional,
+		Selected: Optional,
+	}
+}
+
+// Validate checks call against the catalogue and returns the function it calls.
+// When the function is not in the catalogue the error wraps ErrUnknownFunction, and the returned function is usable
+// if the parameters of call are valid.
+func (c *Catalogue) Validate(call Call) (Function, error) {
+	if call.Function == "" {
+		return Function{}, fmt.Errorf("function name is empty")
+	}
+	f, known := c.Lookup(call.Function)
+	if !known {
+		f = unknownFunction(call.Function)
+	}
+
+	var errs []string
+	usage := func(param string, u Usage, set bool) {
+		switch {
+		case u == "" && set:
+			errs = append(errs, fmt.Sprintf("%s does not take %s", f.Name, param))
+		case u == Required && !set:
+			errs = append(errs, fmt.Sprintf("%s requires %s", f.Name, param))
+		}
+	}
+	usage("Input", f.Input, call.Input != "")
+	usage("Duration", f.Duration, call.Duration != nil)
+	usage("Mix", f.Mix, call.Mix != nil)
+	usage("SelectedName or SelectedIndex", f.Selected, call.SelectedName != "" || call.SelectedIndex != nil)
+
+	if call.Duration != nil && *call.Duration < 0 {
+		errs = append(errs, "Duration must not be negative")
+	}
+	if call.Mix != nil && (*call.Mix < 1 || *call.Mix > MaxMix) {
+		errs = append(errs, fmt.Sprintf("Mix must be between 1 and %d", MaxMix))
+	}
+	if call.SelectedName != "" && call.SelectedIndex != nil {
+		errs = append(errs, "pass either SelectedName or SelectedIndex, not both")
+	}
+	if call.SelectedIndex != nil && *call.SelectedIndex < 0 {
+		errs = append(errs, "SelectedIndex must not be negative")
+	}
+
+	switch {
+	case f.Value == nil:
+		if call.Value != "" {
+			errs = append(errs, fmt.Sprintf("%s does not take Value", f.Name))
+		}
+	case call.Value == "":
+		if f.Value.Required {
+			errs = append(errs, fmt.Sprintf("%s requires Value: %s", f.Name, f.Value.Description))
+		}
+	default:
+		if err := f.Value.check(call.Value); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid Value for %s: %v", f.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return f, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	if !known {
+		if suggestions := c.Suggest(call.Function, 5); len(suggestions) > 0 {
+			return f, fmt.Errorf("%w %q. Did you mean %s?", ErrUnknownFunction, call.Function, strings.Join(suggestions, ", "))
+		}
+		return f, fmt.Errorf("%w %q", ErrUnknownFunction, call.Function)
+	}
+	return f, nil
+}
+
+func (v *Value) check(s string) error {
+	var n float64
+	switch v.Type {
+	case TypeInt:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer (%s)", s, v.Description)
+		}
+		n = float64(i)
+	case TypeFloat:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number (%s)", s, v.Description)
+		}
+		n = f
+	case TypeEnum:
+		for _, e := range v.Values {
+			if strings.EqualFold(e, s) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", s, strings.Join(v.Values, ", "))
+	default:
+		return nil
+	}
+	if (v.Min != nil && n < *v.Min) || (v.Max != nil && n > *v.Max) {
+		return fmt.Errorf("%s is out of range (%s)", s, v.Description)
+	}
+	return nil
+}
+
+// Query returns the query parameters of call for the vMix API, without Function.
+func (f Function) Query(call Call) map[string]string {
+	query := map[string]string{}
+	if call.Input != "" {
+		query["Input"] = call.Input
+	}
+	if call.Value != "" {
+		query["Value"] = call.Value
+	}
+	if call.Duration != nil {
+		query["Duration"] = strconv.Itoa(*call.Duration)
+	}
+	if call.Mix != nil {
+		query["Mix"] = strconv.Itoa(*call.Mix - 1)
+	}
+	if call.SelectedName != "" {
+		query["SelectedName"] = call.SelectedName
+	}
+	if call.SelectedIndex != nil {
+		query["SelectedIndex"] = strconv.Itoa(*call.SelectedIndex)
+	}
+	return query
+}
+
+// Signature returns how to call f in one line, e.g. "SetText(Input, SelectedName|SelectedIndex?, Value?)".
+func (f Function) Signature() string {
+	var params []string
+	add := func(name string, u Usage) {
+		switch u {
+		case Required:
+			params = append(params, name)
+		case Optional:
+			params = append(params, name+"?")
+		}
+	}
+	add("Input", f.Input)
+	add("SelectedName|SelectedIndex", f.Selected)
+	if f.Value != nil {
+		if f.Value.Required {
+			add("Value", Required)
+		} else {
+			add("Value", Optional)
+		}
+	}
+	add("Duration", f.Duration)
+	add("Mix", f.Mix)
+	return fmt.Sprintf("%s(%s)", f.Name, strings.Join(params, ", "))
+}
+
+// levenshtein returns the edit distance of a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// ParseQuery converts the query parameters of a shortcut URL to a call of function.
+// Keys are case insensitive. Keys which are not parameters of vMix functions are rejected.
+// Mix is counted from 1 like Call.Mix, not like the API.
+func ParseQuery(function string, query map[string]string) (Call, error) {
+	call := Call{Function: function}
+	integer := func(key, s string) (*int, error) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer: %q", key, s)
+		}
+		return &n, nil
+	}
+	var err error
+	for key, value := range query {
+		switch strings.ToLower(key) {
+		case "function":
+			return Call{}, fmt.Errorf("pass the function name as the function, not as a query parameter")
+		case "input":
+			call.Input = value
+		case "value":
+			call.Value = value
+		case "duration":
+			call.Duration, err = integer("Duration", value)
+		case "mix":
+			call.Mix, err = integer("Mix", value)
+		case "selectedname":
+			call.SelectedName = value
+		case "selectedindex":
+			call.SelectedIndex, err = integer("SelectedIndex", value)
+		default:
+			return Call{}, fmt.Errorf("unknown query parameter %q. vMix functions take Input, Value, Duration, Mix, SelectedName and SelectedIndex", key)
+		}
+		if err != nil {
+			return Call{}, err
+		}
+	}
+	return call, nil
+}
@@ -0,0 +1,199 @@
+package functions_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/samber/lo"
+
+	"github.com/FlowingSPDG/mcp-vmix/functions"
+)
+
+func TestDefault(t *testing.T) {
+	c, err := functions.Default()
+	if err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+	for _, name := range []string{"Cut", "SetText", "NextPicture", "PreviousPicture", "SetColour", "MultiViewOverlay", "MultiViewOverlayOn", "MultiViewOverlayOff", "SetMultiViewOverlay"} {
+		if _, ok := c.Lookup(name); !ok {
+			t.Errorf("the catalogue has no %s", name)
+		}
+	}
+	if f, ok := c.Lookup("setTEXT"); !ok || f.Name != "SetText" {
+		t.Errorf("Lookup(setTEXT) = %q, %t, want SetText", f.Name, ok)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			yaml: "version: 1\nfunctions:\n- name: Cut\n  input: optional\n- name: SetText\n  input: required\n  value: {type: string, required: true}\n",
+		},
+		{name: "not yaml", yaml: "functions: [", wantErr: "failed to parse"},
+		{name: "no name", yaml: "functions:\n- category: Input\n", wantErr: "name is empty"},
+		{name: "duplicate", yaml: "functions:\n- name: Cut\n- name: cut\n", wantErr: "duplicate"},
+		{name: "unknown usage", yaml: "functions:\n- name: Cut\n  input: sometimes\n", wantErr: "unknown usage of input"},
+		{name: "unknown value type", yaml: "functions:\n- name: SetText\n  value: {type: colour}\n", wantErr: "unknown value type"},
+		{name: "enum without values", yaml: "functions:\n- name: AudioBus\n  value: {type: enum}\n", wantErr: "no values"},
+		{name: "invalid since", yaml: "functions:\n- name: Cut\n  since: latest\n", wantErr: "invalid since"},
+		{name: "unknown edition", yaml: "functions:\n- name: Cut\n  edition: Ultra\n", wantErr: "unknown edition"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := functions.Parse([]byte(tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				if _, ok := c.Lookup("settext"); !ok {
+					t.Error("Lookup(settext) found nothing")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c, err := functions.Default()
+	if err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		call    functions.Call
+		want    string
+		wantErr string
+	}{
+		{name: "no parameters", call: functions.Call{Function: "FadeToBlack"}, want: "FadeToBlack"},
+		{name: "case insensitive", call: functions.Call{Function: "cut", Input: "2"}, want: "Cut"},
+		{name: "mix", call: functions.Call{Function: "Cut", Input: "2", Mix: lo.ToPtr(1)}, want: "Cut"},
+		{name: "selected", call: functions.Call{Function: "SetText", Input: "Lower Third", SelectedName: "Headline.Text", Value: "Hello"}, want: "SetText"},
+		{name: "enum", call: functions.Call{Function: "AudioBusOn", Input: "1", Value: "a"}, want: "AudioBusOn"},
+		{name: "empty name", call: functions.Call{}, wantErr: "empty"},
+		{name: "missing input", call: functions.Call{Function: "CutDirect"}, wantErr: "CutDirect requires Input"},
+		{name: "unexpected input", call: functions.Call{Function: "FadeToBlack", Input: "1"}, wantErr: "FadeToBlack does not take Input"},
+		{name: "missing value", call: functions.Call{Function: "SetColour", Input: "1"}, wantErr: "requires Value"},
+		{name: "unexpected value", call: functions.Call{Function: "Cut", Value: "1"}, wantErr: "does not take Value"},
+		{name: "enum out of values", call: functions.Call{Function: "AudioBusOn", Input: "1", Value: "H"}, wantErr: "is not one of"},
+		{name: "int out of range", call: functions.Call{Function: "LayerOn", Input: "1", Value: "11"}, wantErr: "out of range"},
+		{name: "not an int", call: functions.Call{Function: "LayerOn", Input: "1", Value: "one"}, wantErr: "not an integer"},
+		{name: "negative duration", call: functions.Call{Function: "Fade", Duration: lo.ToPtr(-1)}, wantErr: "Duration must not be negative"},
+		{name: "mix 0", call: functions.Call{Function: "Cut", Mix: lo.ToPtr(0)}, wantErr: "Mix must be between 1 and 16"},
+		{name: "mix out of range", call: functions.Call{Function: "Cut", Mix: lo.ToPtr(17)}, wantErr: "Mix must be between 1 and 16"},
+		{name: "both selected", call: functions.Call{Function: "SetText", Input: "1", Value: "a", SelectedName: "a", SelectedIndex: lo.ToPtr(0)}, wantErr: "not both"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := c.Validate(tt.call)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if f.Name != tt.want {
+				t.Errorf("Validate() = %s, want %s", f.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	f := functions.Function{Name: "Cut", Input: functions.Optional, Mix: functions.Optional}
+	tests := []struct {
+		call functions.Call
+		want map[string]string
+	}{
+		{call: functions.Call{Function: "Cut"}, want: map[string]string{}},
+		{call: functions.Call{Function: "Cut", Input: "2", Mix: lo.ToPtr(1)}, want: map[string]string{"Input": "2", "Mix": "0"}},
+		{call: functions.Call{Function: "Cut", Mix: lo.ToPtr(3)}, want: map[string]string{"Mix": "2"}},
+	}
+	for _, tt := range tests {
+		if got := f.Query(tt.call); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Query(%+v) = %v, want %v", tt.call, got, tt.want)
+		}
+	}
+}
+
+func TestValidateUnknown(t *testing.T) {
+	c, err := functions.Default()
+	if err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+
+	call := functions.Call{Function: "SetMultiViewOverlayNew", Input: "1", Value: "2"}
+	f, err := c.Validate(call)
+	if !errors.Is(err, functions.ErrUnknownFunction) {
+		t.Fatalf("Validate() error = %v, want ErrUnknownFunction", err)
+	}
+	if !strings.Contains(err.Error(), "Did you mean") {
+		t.Errorf("Validate() error = %v, want suggestions", err)
+	}
+	if f.Name != call.Function {
+		t.Errorf("Validate() = %s, want %s", f.Name, call.Function)
+	}
+	if got, want := f.Query(call), map[string]string{"Input": "1", "Value": "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Query() = %v, want %v", got, want)
+	}
+
+	// The parameters of unknown functions are still checked.
+	_, err = c.Validate(functions.Call{Function: "SomethingNew", Duration: lo.ToPtr(-1)})
+	if err == nil || errors.Is(err, functions.ErrUnknownFunction) {
+		t.Errorf("Validate() error = %v, want a parameter error", err)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   map[string]string
+		want    functions.Call
+		wantErr string
+	}{
+		{name: "empty", query: nil, want: functions.Call{Function: "Cut"}},
+		{
+			name:  "all parameters",
+			query: map[string]string{"Input": "Camera 1", "Value": "10", "Duration": "500", "Mix": "2", "SelectedName": "Headline.Text"},
+			want:  functions.Call{Function: "Cut", Input: "Camera 1", Value: "10", Duration: lo.ToPtr(500), Mix: lo.ToPtr(2), SelectedName: "Headline.Text"},
+		},
+		{
+			name:  "case insensitive keys",
+			query: map[string]string{"input": "1", "SELECTEDINDEX": "3"},
+			want:  functions.Call{Function: "Cut", Input: "1", SelectedIndex: lo.ToPtr(3)},
+		},
+		{name: "function key", query: map[string]string{"Function": "Fade"}, wantErr: "function name"},
+		{name: "unknown key", query: map[string]string{"Layer": "1"}, wantErr: "unknown query parameter"},
+		{name: "not an integer", query: map[string]string{"Duration": "fast"}, wantErr: "Duration must be an integer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := functions.ParseQuery("Cut", tt.query)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseQuery() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
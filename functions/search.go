@@ -0,0 +1,69 @@
+package functions
+
+import (
+	"slices"
+	"strings"
+)
+
+// Categories returns the categories in catalogue order.
+func (c *Catalogue) Categories() []string {
+	var categories []string
+	for _, f := range c.Functions {
+		if !slices.Contains(categories, f.Category) {
+			categories = append(categories, f.Category)
+		}
+	}
+	return categories
+}
+
+// Search returns up to limit functions matching every word of query, best matches first.
+// Names score higher than descriptions. An empty query matches every function of the category.
+// category is case insensitive and empty matches all categories. limit 0 returns all matches.
+func (c *Catalogue) Search(query, category string, limit int) []Function {
+	words := strings.Fields(strings.ToLower(query))
+	type match struct {
+		f     Function
+		score int
+	}
+	var matches []match
+	for _, f := range c.Functions {
+		if category != "" && !strings.EqualFold(f.Category, category) {
+			continue
+		}
+		name := strings.ToLower(f.Name)
+		text := strings.ToLower(f.Category + " " + f.Description)
+		if f.Value != nil {
+			text += " " + strings.ToLower(f.Value.Description)
+		}
+		score := 0
+		for _, w := range words {
+			switch {
+			case name == w:
+				score += 100
+			case strings.HasPrefix(name, w):
+				score += 20
+			case strings.Contains(name, w):
+				score += 10
+			case strings.Contains(text, w):
+				score++
+			default:
+				score = -1
+			}
+			if score < 0 {
+				break
+			}
+		}
+		if score >= 0 {
+			matches = append(matches, match{f, score})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b match) int { return b.score - a.score })
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	functions := make([]Function, len(matches))
+	for i, m := range matches {
+		functions[i] = m.f
+	}
+	return functions
+}
@@ -0,0 +1,52 @@
+package functions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// editions are the vMix editions from the lowest. Trial and unknown editions have every feature.
+var editions = []string{"Basic", "Basic HD", "SD", "HD", "4K", "Pro"}
+
+func editionRank(edition string) int {
+	for i, e := range editions {
+		if strings.EqualFold(strings.ReplaceAll(e, " ", ""), strings.ReplaceAll(edition, " ", "")) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseVersion parses the major and minor number of a vMix version such as 27.0.0.49.
+func parseVersion(v string) ([2]int, bool) {
+	var version [2]int
+	parts := strings.SplitN(v, ".", 3)
+	for i := 0; i < len(parts) && i < 2; i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return version, false
+		}
+		version[i] = n
+	}
+	return version, true
+}
+
+// Supports returns an error if the function is not available on a vMix of version and edition, as reported in its XML state.
+// Empty or unknown versions and editions are assumed to support it.
+func (f Function) Supports(version, edition string) error {
+	if f.Since != "" {
+		have, ok := parseVersion(version)
+		need, _ := parseVersion(f.Since)
+		if ok && version != "" && (have[0] < need[0] || have[0] == need[0] && have[1] < need[1]) {
+			return fmt.Errorf("%s needs vMix %s or later, the instance runs %s", f.Name, f.Since, version)
+		}
+	}
+	if f.Edition != "" {
+		have := editionRank(edition)
+		if have >= 0 && have < editionRank(f.Edition) {
+			return fmt.Errorf("%s needs vMix %s edition or higher, the instance is %s", f.Name, f.Edition, edition)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const stateFetchTimeout = 5 * time.Second
+
+// vmixHTTPClient sends the requests to the vMix HTTP API.
+// It is separate from http.DefaultClient so the credentials of vMix never leak into other HTTP traffic of the process.
+var vmixHTTPClient = &http.Client{
+	Transport: http.DefaultTransport.(*http.Transport).Clone(),
+}
+
+// httpAPIClient sends shortcut functions to the vMix HTTP API of one target.
+// vmixhttp.Client cannot tell a rejected function from a lost connection, so the request is built here.
+type httpAPIClient struct {
+	endpoint string // http://host:port/api
+	username string
+	password string
+}
+
+func newHTTPAPIClient(target vmixTarget) *httpAPIClient {
+	return &httpAPIClient{
+		endpoint: fmt.Sprintf("http://%s:%d/api", target.Host, target.Port),
+		username: target.Username,
+		password: target.Password,
+	}
+}
+
+// apiStatusError is returned when vMix answered a function with an error status, e.g. for an unknown input.
+// vMix itself is reachable, so the pooled client stays usable.
+type apiStatusError struct {
+	Function string
+	Status   string
+	Body     string
+}
+
+func (e *apiStatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("vMix rejected %s: %s", e.Function, e.Status)
+	}
+	return fmt.Sprintf("vMix rejected %s: %s: %s", e.Function, e.Status, e.Body)
+}
+
+func (c *httpAPIClient) get(ctx context.Context, query url.Values) (*http.Response, error) {
+	u := c.endpoint
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	return vmixHTTPClient.Do(req)
+}
+
+// sendFunction sends /api?Function=function&Key=Value...
+func (c *httpAPIClient) sendFunction(ctx context.Context, function string, params map[string]string) error {
+	q := url.Values{}
+	q.Set("Function", function)
+	for k, v := range params {
+		q.Set(k, v)
+	}
+
+	resp, err := c.get(ctx, q)
+	if err != nil {
+		return fmt.Errorf("failed to send function: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &apiStatusError{Function: function, Status: resp.Status, Body: strings.TrimSpace(string(body))}
+	}
+	return nil
+}
+
+// fetchState downloads and parses the XML state from the vMix HTTP API.
+func (c *httpAPIClient) fetchState() (*vmixState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), stateFetchTimeout)
+	defer cancel()
+
+	resp, err := c.get(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect vmix: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vMix returned %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	return parseState(body)
+}
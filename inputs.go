@@ -0,0 +1,281 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/samber/lo"
+
+	"github.com/FlowingSPDG/mcp-vmix/functions"
+)
+
+const (
+	// inputWaitTimeout is how long to wait for vMix to list inputs added by a function.
+	inputWaitTimeout = 5 * time.Second
+	// inputWaitInterval is how often the state is read while waiting.
+	inputWaitInterval = 100 * time.Millisecond
+)
+
+// inputKeys returns the keys of the inputs of the state.
+func (s *vmixState) inputKeys() map[string]struct{} {
+	return lo.SliceToMap(s.Inputs, func(in stateInput) (string, struct{}) { return in.Key, struct{}{} })
+}
+
+// addedInputs returns the inputs whose key is not in before, in number order.
+func (s *vmixState) addedInputs(before map[string]struct{}) []stateInput {
+	added := lo.Filter(s.Inputs, func(in stateInput, _ int) bool { return !lo.HasKey(before, in.Key) })
+	slices.SortFunc(added, func(a, b stateInput) int { return a.Number - b.Number })
+	return added
+}
+
+// waitAddedInputs reads the state until want inputs which are not in before are listed, or inputWaitTimeout passes.
+// vMix adds inputs asynchronously, so they are not listed as soon as the function returns.
+// Fewer inputs than want are returned on timeout.
+func (m *mcpVmix) waitAddedInputs(ctx context.Context, target vmixTarget, before map[string]struct{}, want int) ([]stateInput, error) {
+	deadline := time.Now().Add(inputWaitTimeout)
+	for {
+		state, err := m.pool.refresh(target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the inputs: %w", err)
+		}
+		added := state.addedInputs(before)
+		if len(added) >= want || time.Now().After(deadline) {
+			return added, nil
+		}
+		select {
+		case <-ctx.Done():
+			return added, ctx.Err()
+		case <-time.After(inputWaitInterval):
+		}
+	}
+}
+
+// inputTypes maps the types of vmix_add_input to the type names of AddInput.
+var inputTypes = map[string]string{
+	"Video":      "Video",
+	"Image":      "Image",
+	"Photos":     "Photos",
+	"Audio":      "AudioFile",
+	"Title":      "Title",
+	"Browser":    "Browser",
+	"NDI":        "NDI",
+	"SRT":        "SRT",
+	"Colour":     "Colour",
+	"VirtualSet": "VirtualSet",
+}
+
+// addInputValue validates the source of an input type and returns the Value of AddInput.
+func addInputValue(inputType, source string) (string, error) {
+	vmixType, ok := lo.FindKeyBy(inputTypes, func(t, _ string) bool { return strings.EqualFold(t, inputType) })
+	if !ok {
+		types := lo.Keys(inputTypes)
+		slices.Sort(types)
+		return "", fmt.Errorf("unknown input type: %s. Use one of %s", inputType, strings.Join(types, ", "))
+	}
+	vmixType = inputTypes[vmixType]
+	source = strings.TrimSpace(source)
+	lower := strings.ToLower(source)
+	switch {
+	case source == "":
+		return "", fmt.Errorf("source is required for %s inputs", inputType)
+	case vmixType == "Browser" && !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://"):
+		return "", fmt.Errorf("source of a Browser input must be an http or https URL: %s", source)
+	case vmixType == "SRT" && !strings.HasPrefix(lower, "srt://"):
+		return "", fmt.Errorf("source of an SRT input must be an srt:// URL: %s", source)
+	case vmixType == "Colour" && !colourPattern.MatchString(source):
+		return "", fmt.Errorf("source of a Colour input must be a colour name or #RRGGBB: %s", source)
+	}
+	return vmixType + "|" + source, nil
+}
+
+// describeInput describes an input with its number and key, which tools can be called with afterwards.
+func describeInput(in stateInput) string {
+	return fmt.Sprintf("Input %d %s (key %s, type %s)", in.Number, strings.TrimSpace(in.Title), in.Key, in.Type)
+}
+
+// addInput sends a function which adds one input and returns the new input found by comparing the inputs.
+// With name the new input is renamed by its key. Inputs added by others meanwhile can be mistaken for it.
+func (m *mcpVmix) addInput(ctx context.Context, target vmixTarget, action string, call functions.Call, name string) (stateInput, error) {
+	state, err := m.pool.refresh(target)
+	if err != nil {
+		return stateInput{}, fmt.Errorf("failed to connect to vMix instance: %w", err)
+	}
+	before := state.inputKeys()
+
+	if err := m.sendCatalogueFunction(ctx, target, action, call); err != nil {
+		return stateInput{}, err
+	}
+	added, err := m.waitAddedInputs(ctx, target, before, 1)
+	if err != nil {
+		return stateInput{}, err
+	}
+	if len(added) == 0 {
+		return stateInput{}, fmt.Errorf("vMix accepted %s but no new input appeared within %s. Check the source", call.Function, inputWaitTimeout)
+	}
+	if len(added) > 1 {
+		m.log(ctx).Warn("Several inputs appeared, using the first one", "instance", target, "function", call.Function, "count", len(added), "input", added[0].Key)
+	}
+	in := added[0]
+
+	if name != "" {
+		if err := m.sendCatalogueFunction(ctx, target, fmt.Sprintf("rename input %s to %s", in.Key, name), functions.Call{Function: "SetInputName", Input: in.Key, Value: name}); err != nil {
+			return in, err
+		}
+		in.Title = name
+	}
+	return in, nil
+}
+
+// waitInput reads the state until the input with key satisfies done, or inputWaitTimeout passes, and returns it.
+// done may be nil to read the state once.
+func (m *mcpVmix) waitInput(ctx context.Context, target vmixTarget, key string, done func(stateInput) bool) (stateInput, error) {
+	deadline := time.Now().Add(inputWaitTimeout)
+	for {
+		state, err := m.pool.refresh(target)
+		if err != nil {
+			return stateInput{}, fmt.Errorf("failed to read the inputs: %w", err)
+		}
+		in, ok := state.input(key)
+		if !ok {
+			return stateInput{}, fmt.Errorf("input %s is no longer listed", key)
+		}
+		if done == nil || done(*in) || time.Now().After(deadline) {
+			return *in, nil
+		}
+		select {
+		case <-ctx.Done():
+			return *in, ctx.Err()
+		case <-time.After(inputWaitInterval):
+		}
+	}
+}
+
+// existingInput returns the input from the latest state.
+func (m *mcpVmix) existingInput(target vmixTarget, input string) (*vmixState, *stateInput, error) {
+	state, err := m.pool.refresh(target)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to vMix instance: %w", err)
+	}
+	in, ok := state.input(input)
+	if !ok {
+		return nil, nil, fmt.Errorf("input %s not found", input)
+	}
+	return state, in, nil
+}
+
+// AddInput implements MCPvMix.
+func (m *mcpVmix) AddInput(ctx context.Context, arguments AddInputArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	action := fmt.Sprintf("add %s input %s", arguments.Type, arguments.Source)
+	value, err := addInputValue(arguments.Type, arguments.Source)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to %s, nothing was sent to vMix: %v", action, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	in, err := m.addInput(ctx, target, action, functions.Call{Function: "AddInput", Value: value}, arguments.Name)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to %s: %v", action, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Added " + describeInput(in))), nil
+}
+
+// DuplicateInput implements MCPvMix.
+func (m *mcpVmix) DuplicateInput(ctx context.Context, arguments DuplicateInputArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	action := fmt.Sprintf("duplicate input %s", arguments.Input)
+	_, source, err := m.existingInput(target, arguments.Input)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to %s, nothing was sent to vMix: %v", action, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	in, err := m.addInput(ctx, target, action, functions.Call{Function: "DuplicateInput", Input: source.Key}, arguments.Name)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to %s: %v", action, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Duplicated input %d %s as %s", source.Number, strings.TrimSpace(source.Title), describeInput(in)))), nil
+}
+
+// RemoveInput implements MCPvMix.
+func (m *mcpVmix) RemoveInput(ctx context.Context, arguments RemoveInputArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	action := fmt.Sprintf("remove input %s", arguments.Input)
+	state, in, err := m.existingInput(target, arguments.Input)
+	if err == nil && !arguments.Force && state.onAir()[in.Key] {
+		err = fmt.Errorf("input %d %s is on air. Take it off air first or pass force", in.Number, strings.TrimSpace(in.Title))
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to %s, nothing was sent to vMix: %v", action, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	removed := *in
+	if err := m.sendCatalogueFunction(ctx, target, action, functions.Call{Function: "RemoveInput", Input: removed.Key}); err != nil {
+		return nil, err
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Removed %s. Inputs after it moved up by one.", describeInput(removed)))), nil
+}
+
+// RenameInput implements MCPvMix.
+func (m *mcpVmix) RenameInput(ctx context.Context, arguments RenameInputArguments) (*mcp_golang.ToolResponse, error) {
+	renamed := func(in stateInput) bool { return in.Title == arguments.Name }
+	return m.changeInput(ctx, arguments.BaseVMixArguments, arguments.Input, fmt.Sprintf("rename input %s to %s", arguments.Input, arguments.Name), "SetInputName", arguments.Name, "Renamed", renamed)
+}
+
+// MoveInput implements MCPvMix.
+func (m *mcpVmix) MoveInput(ctx context.Context, arguments MoveInputArguments) (*mcp_golang.ToolResponse, error) {
+	moved := func(in stateInput) bool { return in.Number == arguments.Number }
+	return m.changeInput(ctx, arguments.BaseVMixArguments, arguments.Input, fmt.Sprintf("move input %s to number %d", arguments.Input, arguments.Number), "MoveInput", strconv.Itoa(arguments.Number), "Moved", moved)
+}
+
+// ResetInput implements MCPvMix.
+func (m *mcpVmix) ResetInput(ctx context.Context, arguments InputArguments) (*mcp_golang.ToolResponse, error) {
+	return m.changeInput(ctx, arguments.BaseVMixArguments, arguments.Input, fmt.Sprintf("reset input %s", arguments.Input), "ResetInput", "", "Reset", nil)
+}
+
+// changeInput sends a function which changes an existing input by its key and reports the input once changed reports true.
+func (m *mcpVmix) changeInput(ctx context.Context, base BaseVMixArguments, input, action, function, value, done string, changed func(stateInput) bool) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+
+	_, in, err := m.existingInput(target, input)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to %s, nothing was sent to vMix: %v", action, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	if err := m.sendCatalogueFunction(ctx, target, action, functions.Call{Function: function, Input: in.Key, Value: value}); err != nil {
+		return nil, err
+	}
+	after, err := m.waitInput(ctx, target, in.Key, changed)
+	if err != nil {
+		errMsg := fmt.Sprintf("Sent %s but failed to read the input afterwards: %v", function, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("%s input, now %s", done, describeInput(after)))), nil
+}
@@ -0,0 +1,60 @@
+package mcpvmix
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddInputValue(t *testing.T) {
+	cases := []struct {
+		name      string
+		inputType string
+		source    string
+		want      string
+		wantErr   string
+	}{
+		{name: "video", inputType: "Video", source: `C:\clips\intro.mp4`, want: `Video|C:\clips\intro.mp4`},
+		{name: "audio is AudioFile", inputType: "Audio", source: `C:\music\bed.mp3`, want: `AudioFile|C:\music\bed.mp3`},
+		{name: "type is case insensitive", inputType: "virtualset", source: `C:\sets\studio.vmix`, want: `VirtualSet|C:\sets\studio.vmix`},
+		{name: "source is trimmed", inputType: "NDI", source: "  CAMERA (Studio)  ", want: "NDI|CAMERA (Studio)"},
+		{name: "browser https", inputType: "Browser", source: "https://example.com/scores", want: "Browser|https://example.com/scores"},
+		{name: "browser http in capitals", inputType: "Browser", source: "HTTP://192.168.0.5:8080/", want: "Browser|HTTP://192.168.0.5:8080/"},
+		{name: "srt", inputType: "SRT", source: "srt://192.168.0.5:9000?mode=caller", want: "SRT|srt://192.168.0.5:9000?mode=caller"},
+		{name: "colour name", inputType: "Colour", source: "Red", want: "Colour|Red"},
+		{name: "colour hex", inputType: "Colour", source: "#00FF00", want: "Colour|#00FF00"},
+		{name: "browser without scheme", inputType: "Browser", source: "example.com", wantErr: "must be an http or https URL"},
+		{name: "browser with another scheme", inputType: "Browser", source: "file:///C:/page.html", wantErr: "must be an http or https URL"},
+		{name: "srt over http", inputType: "SRT", source: "http://192.168.0.5:9000", wantErr: "must be an srt:// URL"},
+		{name: "srt without scheme", inputType: "SRT", source: "192.168.0.5:9000", wantErr: "must be an srt:// URL"},
+		{name: "colour with a short hex", inputType: "Colour", source: "#0F0", wantErr: "must be a colour name or #RRGGBB"},
+		{name: "colour with spaces", inputType: "Colour", source: "light blue", wantErr: "must be a colour name or #RRGGBB"},
+		{name: "empty source", inputType: "Video", source: "  ", wantErr: "source is required for Video inputs"},
+		{name: "unknown type", inputType: "Camera", source: "0", wantErr: "unknown input type: Camera. Use one of Audio, Browser, Colour, Image, NDI, Photos, SRT, Title, Video, VirtualSet"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := addInputValue(tc.inputType, tc.source)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("addInputValue() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("addInputValue() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("addInputValue() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddedInputs(t *testing.T) {
+	before := (&vmixState{Inputs: []stateInput{{Number: 1, Key: "a"}, {Number: 2, Key: "b"}}}).inputKeys()
+	after := &vmixState{Inputs: []stateInput{{Number: 1, Key: "a"}, {Number: 4, Key: "d"}, {Number: 2, Key: "b"}, {Number: 3, Key: "c"}}}
+	added := after.addedInputs(before)
+	if len(added) != 2 || added[0].Key != "c" || added[1].Key != "d" {
+		t.Errorf("addedInputs() = %+v, want c and d in number order", added)
+	}
+}
@@ -0,0 +1,179 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/samber/lo"
+
+	"github.com/FlowingSPDG/mcp-vmix/config"
+	"github.com/FlowingSPDG/mcp-vmix/mcphttp"
+)
+
+// vmixTarget is a resolved vMix destination for a single tool call.
+type vmixTarget struct {
+	Name    string // instance name. empty when the destination was given only by ip/port.
+	Host    string
+	Port    int
+	TCPPort int // TCP API port. 0 when the TCP API is not used.
+	// TCPOnly is set when the tool call asked for the TCP API. Failures are returned instead of falling back to HTTP.
+	TCPOnly bool
+
+	// Username and Password are the basic authentication credentials of the vMix HTTP API.
+	Username string
+	Password string
+}
+
+// LogValue implements slog.LogValuer, so loggers write the instance name and address but never the credentials.
+func (t vmixTarget) LogValue() slog.Value {
+	return slog.StringValue(t.String())
+}
+
+func (t vmixTarget) String() string {
+	if t.Name == "" {
+		return fmt.Sprintf("%s:%d", t.Host, t.Port)
+	}
+	return fmt.Sprintf("%s (%s:%d)", t.Name, t.Host, t.Port)
+}
+
+// instanceRegistry holds named vMix instances and the one selected by each client.
+type instanceRegistry struct {
+	mu          sync.RWMutex
+	instances   []config.Instance
+	defaultName string
+	selected    map[string]string // instance names by client session ID
+}
+
+func newInstanceRegistry(cfg *config.Config) *instanceRegistry {
+	return &instanceRegistry{
+		instances:   cfg.Instances,
+		defaultName: cfg.DefaultInstance().Name,
+		selected:    map[string]string{},
+	}
+}
+
+// clientSession returns the ID of the MCP client session calling in ctx. It is "" for stdio, which has one client.
+func clientSession(ctx context.Context) string {
+	id, _ := mcphttp.SessionID(ctx)
+	return id
+}
+
+func (r *instanceRegistry) list() []config.Instance {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]config.Instance(nil), r.instances...)
+}
+
+func (r *instanceRegistry) lookup(name string) (config.Instance, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, in := range r.instances {
+		if in.Name == name {
+			return in, true
+		}
+	}
+	return config.Instance{}, false
+}
+
+// selectedName returns the instance selected by the client session, or the default instance.
+func (r *instanceRegistry) selectedName(session string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if name, ok := r.selected[session]; ok {
+		return name
+	}
+	return r.defaultName
+}
+
+// selectInstance changes the instance used by tool calls of the client session without instance/ip/port.
+// Other clients keep their own selection.
+func (r *instanceRegistry) selectInstance(session, name string) (config.Instance, error) {
+	in, ok := r.lookup(name)
+	if !ok {
+		return config.Instance{}, fmt.Errorf("unknown vMix instance: %s", name)
+	}
+	r.mu.Lock()
+	r.selected[session] = name
+	r.mu.Unlock()
+	return in, nil
+}
+
+// forgetSession drops the selection of a client session which ended.
+func (r *instanceRegistry) forgetSession(session string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.selected, session)
+}
+
+// resolve picks the vMix destination for the arguments.
+// An explicit instance name wins over the one selected by the client session, and ip/port override the host/port of the instance.
+func (r *instanceRegistry) resolve(session string, arguments BaseVMixArguments) (vmixTarget, error) {
+	var target vmixTarget
+	switch {
+	case arguments.Instance != "":
+		in, ok := r.lookup(arguments.Instance)
+		if !ok {
+			return vmixTarget{}, fmt.Errorf("unknown vMix instance: %s", arguments.Instance)
+		}
+		target = instanceTarget(in)
+	case arguments.IP != "":
+		port := lo.Ternary(arguments.Port != 0, arguments.Port, config.DefaultPort)
+		// ip/port of a configured instance use its credentials and TCP API
+		if in, ok := r.lookupAddr(arguments.IP, port); ok {
+			target = instanceTarget(in)
+			break
+		}
+		target = vmixTarget{Host: arguments.IP, Port: config.DefaultPort}
+	default:
+		in, ok := r.lookup(r.selectedName(session))
+		if !ok {
+			return vmixTarget{}, fmt.Errorf("no vMix instance is selected")
+		}
+		target = instanceTarget(in)
+	}
+
+	// The credentials of the instance are kept, but its TCP API port is not for the other host.
+	if arguments.IP != "" && arguments.IP != target.Host {
+		target.Host = arguments.IP
+		target.TCPPort = 0
+	}
+	if arguments.Port != 0 {
+		target.Port = arguments.Port
+	}
+
+	switch arguments.Transport {
+	case "", "auto":
+	case "tcp":
+		if target.TCPPort == 0 {
+			target.TCPPort = config.DefaultTCPPort
+		}
+		target.TCPOnly = true
+	case "http":
+		target.TCPPort = 0
+	default:
+		return vmixTarget{}, fmt.Errorf("unknown transport %q. Use 'auto', 'tcp' or 'http'", arguments.Transport)
+	}
+	return target, nil
+}
+
+func instanceTarget(in config.Instance) vmixTarget {
+	target := vmixTarget{Name: in.Name, Host: in.Host, Port: in.Port, Username: in.Username, Password: in.Password}
+	if in.TCP {
+		target.TCPPort = in.TCPPort
+	}
+	return target
+}
+
+// lookupAddr finds the instance at host:port.
+func (r *instanceRegistry) lookupAddr(host string, port int) (config.Instance, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, in := range r.instances {
+		if in.Host == host && in.Port == port {
+			return in, true
+		}
+	}
+	return config.Instance{}, false
+}
@@ -0,0 +1,46 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+)
+
+// maxLayers is how many layers an input has.
+const maxLayers = 10
+
+// レイヤー関数は vmix-go と同じクエリを sendFunction で送り、監査ログに残るようにする
+
+func (m *mcpVmix) setLayer(ctx context.Context, target vmixTarget, input string, layer int, layerInput string) error {
+	if layer < 1 || layer > maxLayers {
+		return fmt.Errorf("invalid layer: %d", layer)
+	}
+	return m.sendFunction(ctx, target, "SetLayer", map[string]string{"Input": input, "Value": fmt.Sprintf("%d,%s", layer, layerInput)})
+}
+
+func (m *mcpVmix) setLayerParam(ctx context.Context, target vmixTarget, input string, layer int, param, value string) error {
+	if layer < 1 || layer > maxLayers {
+		return fmt.Errorf("invalid layer: %d", layer)
+	}
+	return m.sendFunction(ctx, target, fmt.Sprintf("SetLayer%d%s", layer, param), map[string]string{"Input": input, "Value": value})
+}
+
+func (m *mcpVmix) setLayerPanX(ctx context.Context, target vmixTarget, input string, layer int, pan float64) error {
+	return m.setLayerParam(ctx, target, input, layer, "PanX", fmt.Sprintf("%.2f", clamp(pan, -2, 2)))
+}
+
+func (m *mcpVmix) setLayerPanY(ctx context.Context, target vmixTarget, input string, layer int, pan float64) error {
+	return m.setLayerParam(ctx, target, input, layer, "PanY", fmt.Sprintf("%.2f", clamp(pan, -2, 2)))
+}
+
+func (m *mcpVmix) setLayerZoom(ctx context.Context, target vmixTarget, input string, layer int, zoom float64) error {
+	return m.setLayerParam(ctx, target, input, layer, "Zoom", fmt.Sprintf("%.2f", clamp(zoom, 0, 5)))
+}
+
+func (m *mcpVmix) setLayerCrop(ctx context.Context, target vmixTarget, input string, layer int, x1, y1, x2, y2 float64) error {
+	value := fmt.Sprintf("%.3f,%.3f,%.3f,%.3f", clamp(x1, 0, 1), clamp(y1, 0, 1), clamp(x2, 0, 1), clamp(y2, 0, 1))
+	return m.setLayerParam(ctx, target, input, layer, "Crop", value)
+}
+
+func clamp(v, lower, upper float64) float64 {
+	return min(max(v, lower), upper)
+}
@@ -1,73 +1,252 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"time"
+	"reflect"
 )
 
-// GetLogFilePath は%appdata%/RSLT/vmix-mcp/logs/にログファイルのパスを生成します
+// GetLogFilePath は%appdata%/RSLT/vmix-mcp/logs/vmix-mcp.log のパスを返します
 func GetLogFilePath() (string, error) {
-	appData, _ := os.UserConfigDir()
-	logDir := filepath.Join(appData, "RSLT", "vmix-mcp", "logs")
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create log directory: %w", err)
+	appData, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
 	}
-
-	timestamp := time.Now().Format("20060102150405")
-	logPath := filepath.Join(logDir, fmt.Sprintf("vmix_mcp_log_%s.log", timestamp))
-	return logPath, nil
+	return filepath.Join(appData, "RSLT", "vmix-mcp", "logs", "vmix-mcp.log"), nil
 }
 
+// Logger writes levelled messages with key/value fields, e.g.
+//
+//	logger.Info("Sent vMix function", "instance", target, "function", "Cut", "duration", time.Since(start))
 type Logger interface {
 	Close() error
-	Info(msg string)
-	Warn(msg string)
-	Error(msg string)
-	Debug(msg string)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+	Debug(msg string, args ...any)
+	// With returns a logger which adds the fields to every message.
+	With(args ...any) Logger
 }
 
-type fileLogger struct {
-	f *os.File
+type contextKey struct{}
+
+// NewContext returns ctx carrying l, e.g. a logger which adds the tool name for the handler of a tool call.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
 }
 
-// Debug implements Logger.
-func (l *fileLogger) Debug(msg string) {
-	if _, err := l.f.WriteString(fmt.Sprintf("[DEBUG] %s\n", msg)); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to write debug log: %v\n", err)
+// FromContext returns the logger of ctx, or fallback when ctx has none.
+func FromContext(ctx context.Context, fallback Logger) Logger {
+	if l, ok := ctx.Value(contextKey{}).(Logger); ok {
+		return l
 	}
+	return fallback
 }
 
-// Error implements Logger.
-func (l *fileLogger) Error(msg string) {
-	if _, err := l.f.WriteString(fmt.Sprintf("[ERROR] %s\n", msg)); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to write error log: %v\n", err)
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// WrapHandler wraps an mcp-golang tool handler taking a context.Context, so the handler gets l with FromContext.
+// The returned handler has the same type as handler, so the input schema of the tool does not change.
+func WrapHandler(l Logger, handler any) (any, error) {
+	v := reflect.ValueOf(handler)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 2 || t.In(0) != contextType || t.NumOut() != 2 || t.Out(1) != errorType {
+		return nil, fmt.Errorf("handler must be a function taking (context.Context, arguments) and returning (response, error)")
 	}
+	return reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		ctx := args[0].Interface().(context.Context)
+		args[0] = reflect.ValueOf(NewContext(ctx, l))
+		return v.Call(args)
+	}).Interface(), nil
 }
 
-// Info implements Logger.
-func (l *fileLogger) Info(msg string) {
-	if _, err := l.f.WriteString(fmt.Sprintf("[INFO] %s\n", msg)); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to write info log: %v\n", err)
+// Level is the minimum level of messages written to the log.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// ParseLevel parses debug, info, warn or error.
+func ParseLevel(s string) (Level, error) {
+	switch s {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level: %s", s)
+}
+
+func (l Level) slogLevel() slog.Level {
+	switch l {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Format is how log records are written.
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// Options configures a logger.
+type Options struct {
+	Path   string // log file. empty writes only to stderr when Stderr is set
+	Level  Level
+	Format Format
+
+	// MaxSize is the size in bytes at which the file is rotated. 0 disables rotation.
+	MaxSize int64
+	// MaxAgeDays removes rotated files older than this. 0 keeps them regardless of age.
+	MaxAgeDays int
+	// MaxBackups is how many rotated files are kept. 0 keeps all.
+	MaxBackups int
+
+	// Stderr mirrors every message to stderr as text.
+	Stderr bool
+
+	// Handlers also receive every message, e.g. to forward it to the MCP client. They filter levels by themselves.
+	Handlers []slog.Handler
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+	closer io.Closer
+}
+
+// New creates a logger backed by log/slog.
+func New(opts Options) (Logger, error) {
+	var handlers []slog.Handler
+	var closer io.Closer = nopCloser{}
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level.slogLevel()}
+
+	if opts.Path != "" {
+		f, err := openRotatingFile(opts.Path, opts.MaxSize, opts.MaxAgeDays, opts.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		closer = f
+		switch opts.Format {
+		case FormatJSON:
+			handlers = append(handlers, slog.NewJSONHandler(f, handlerOpts))
+		case FormatText, "":
+			handlers = append(handlers, slog.NewTextHandler(f, handlerOpts))
+		default:
+			f.Close()
+			return nil, fmt.Errorf("unknown log format: %s", opts.Format)
+		}
+	}
+	if opts.Stderr {
+		handlers = append(handlers, slog.NewTextHandler(os.Stderr, handlerOpts))
+	}
+	handlers = append(handlers, opts.Handlers...)
+
+	var handler slog.Handler
+	switch len(handlers) {
+	case 0:
+		handler = slog.NewTextHandler(io.Discard, handlerOpts)
+	case 1:
+		handler = handlers[0]
+	default:
+		handler = multiHandler(handlers)
 	}
+	return &slogLogger{logger: slog.New(handler), closer: closer}, nil
+}
+
+// Debug implements Logger.
+func (l *slogLogger) Debug(msg string, args ...any) {
+	l.logger.Debug(msg, args...)
+}
+
+// Info implements Logger.
+func (l *slogLogger) Info(msg string, args ...any) {
+	l.logger.Info(msg, args...)
 }
 
 // Warn implements Logger.
-func (l *fileLogger) Warn(msg string) {
-	if _, err := l.f.WriteString(fmt.Sprintf("[WARN] %s\n", msg)); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to write warning log: %v\n", err)
+func (l *slogLogger) Warn(msg string, args ...any) {
+	l.logger.Warn(msg, args...)
+}
+
+// Error implements Logger.
+func (l *slogLogger) Error(msg string, args ...any) {
+	l.logger.Error(msg, args...)
+}
+
+// With implements Logger.
+func (l *slogLogger) With(args ...any) Logger {
+	return &slogLogger{logger: l.logger.With(args...), closer: nopCloser{}}
+}
+
+// Close implements Logger. Loggers returned by With do not close the file.
+func (l *slogLogger) Close() error {
+	return l.closer.Close()
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// multiHandler sends records to every handler which accepts the level.
+type multiHandler []slog.Handler
+
+func (h multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
 	}
+	return false
 }
 
-func NewFileLogger(path string) (Logger, error) {
-	f, err := os.Create(path)
-	if err != nil {
-		return nil, err
+func (h multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, handler := range h {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (h multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(multiHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithAttrs(attrs)
 	}
-	return &fileLogger{f: f}, nil
+	return handlers
 }
 
-func (l *fileLogger) Close() error {
-	return l.f.Close()
+func (h multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(multiHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
 }
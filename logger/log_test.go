@@ -0,0 +1,106 @@
+package logger_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FlowingSPDG/mcp-vmix/logger"
+)
+
+type cutArguments struct {
+	Mix int `json:"mix"`
+}
+
+func TestWrapHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vmix-mcp.log")
+	log, err := logger.New(logger.Options{Path: path, Level: logger.LevelDebug, Format: logger.FormatJSON})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	handler := func(ctx context.Context, args cutArguments) (string, error) {
+		logger.FromContext(ctx, nil).With("instance", "main", "duration", 1500*time.Microsecond).Debug("Sent vMix function", "function", "Cut")
+		return "ok", nil
+	}
+	wrapped, err := logger.WrapHandler(log.With("tool", "vmix_cut"), handler)
+	if err != nil {
+		t.Fatalf("WrapHandler() error = %v", err)
+	}
+	if res, err := wrapped.(func(context.Context, cutArguments) (string, error))(context.Background(), cutArguments{Mix: 1}); err != nil || res != "ok" {
+		t.Fatalf("wrapped handler = %q, %v", res, err)
+	}
+	// 呼び出しの外ではフォールバックのロガーを使う
+	if l := logger.FromContext(context.Background(), log); l != log {
+		t.Error("FromContext() did not return the fallback")
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(b))), &record); err != nil {
+		t.Fatalf("failed to decode the log record %q: %v", b, err)
+	}
+	want := map[string]any{
+		"level":    "DEBUG",
+		"msg":      "Sent vMix function",
+		"tool":     "vmix_cut",
+		"instance": "main",
+		"function": "Cut",
+		"duration": float64(1500 * time.Microsecond),
+	}
+	for k, v := range want {
+		if record[k] != v {
+			t.Errorf("%s = %v, want %v", k, record[k], v)
+		}
+	}
+}
+
+func TestWrapHandlerInvalid(t *testing.T) {
+	log, _ := logger.New(logger.Options{})
+	for _, handler := range []any{
+		func(args cutArguments) (string, error) { return "", nil },
+		func(ctx context.Context, args cutArguments) string { return "" },
+		func(ctx context.Context, args cutArguments) (string, string) { return "", "" },
+		"not a function",
+	} {
+		if _, err := logger.WrapHandler(log, handler); err == nil {
+			t.Errorf("WrapHandler(%T) error = nil", handler)
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vmix-mcp.log")
+	log, err := logger.New(logger.Options{Path: path, Level: logger.LevelWarn})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	log.Debug("debug message")
+	log.Info("info message")
+	log.Warn("warn message")
+	log.Error("error message")
+	log.Close()
+
+	b, _ := os.ReadFile(path)
+	got := string(b)
+	for _, msg := range []string{"debug message", "info message"} {
+		if strings.Contains(got, msg) {
+			t.Errorf("log contains %q below the level", msg)
+		}
+	}
+	for _, msg := range []string{"warn message", "error message"} {
+		if !strings.Contains(got, msg) {
+			t.Errorf("log lacks %q", msg)
+		}
+	}
+}
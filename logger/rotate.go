@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp added to the name of rotated files.
+const backupTimeFormat = "20060102T150405.000"
+
+// legacyLogPattern matches the per-launch files written by older versions. They are removed by the retention too.
+const legacyLogPattern = "vmix_mcp_log_*.log"
+
+// rotatingFile is an append-only log file which is renamed to name-<time>.ext when it grows over maxSize.
+type rotatingFile struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	size int64
+
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+}
+
+func openRotatingFile(path string, maxSize int64, maxAgeDays, maxBackups int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	r := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     time.Duration(maxAgeDays) * 24 * time.Hour,
+		maxBackups: maxBackups,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	r.prune()
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+// Write implements io.Writer. slog handlers write one record per call, so a record is never split across files.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to rotate log file: %v\n", err)
+		}
+	}
+	// ローテーションで開き直せなかった場合は書き込みのたびに開き直す
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file and opens a new one. r.mu must be held.
+// If no file can be opened, r.f is left nil and Write opens r.path again.
+func (r *rotatingFile) rotate() error {
+	// Windows では開いたままのファイルをリネームできないため、先に閉じる
+	err := r.f.Close()
+	r.f = nil
+	if err != nil {
+		if openErr := r.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	ext := filepath.Ext(r.path)
+	backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(r.path, ext), time.Now().Format(backupTimeFormat), ext)
+	if err := os.Rename(r.path, backup); err != nil {
+		// 開き直せないとログが失われるため、リネームに失敗しても同じファイルに追記を続ける
+		if openErr := r.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	go r.prune()
+	return nil
+}
+
+// prune removes rotated files which are older than maxAge or beyond maxBackups.
+func (r *rotatingFile) prune() {
+	if r.maxAge == 0 && r.maxBackups == 0 {
+		return
+	}
+	dir := filepath.Dir(r.path)
+	ext := filepath.Ext(r.path)
+	base := strings.TrimSuffix(filepath.Base(r.path), ext)
+
+	var backups []os.FileInfo
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		name := e.Name()
+		rotated := strings.HasPrefix(name, base+"-") && strings.HasSuffix(name, ext)
+		legacy, _ := filepath.Match(legacyLogPattern, name)
+		if e.IsDir() || !(rotated || legacy) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, info)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].ModTime().After(backups[j].ModTime()) })
+
+	now := time.Now()
+	for i, info := range backups {
+		expired := r.maxAge > 0 && now.Sub(info.ModTime()) > r.maxAge
+		surplus := r.maxBackups > 0 && i >= r.maxBackups
+		if expired || surplus {
+			if err := os.Remove(filepath.Join(dir, info.Name())); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to remove old log file: %v\n", err)
+			}
+		}
+	}
+}
+
+// Close closes the file.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	return r.f.Close()
+}
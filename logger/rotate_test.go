@@ -0,0 +1,194 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func logFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFileRotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vmix-mcp.log")
+	r, err := openRotatingFile(path, 100, 0, 0)
+	if err != nil {
+		t.Fatalf("openRotatingFile() error = %v", err)
+	}
+	defer r.Close()
+
+	line := strings.Repeat("a", 59) + "\n"
+	for i := 0; i < 3; i++ {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		// バックアップ名はミリ秒単位なので、同じ名前にならないようにする
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// 1行目の後は収まり、2行目と3行目の前にローテーションする
+	files := logFiles(t, dir)
+	if len(files) != 3 || files[2] != "vmix-mcp.log" {
+		t.Fatalf("files = %v, want two backups and vmix-mcp.log", files)
+	}
+	for _, name := range files[:2] {
+		if !strings.HasPrefix(name, "vmix-mcp-") || !strings.HasSuffix(name, ".log") {
+			t.Errorf("backup name = %s, want vmix-mcp-<time>.log", name)
+		}
+		if b, _ := os.ReadFile(filepath.Join(dir, name)); string(b) != line {
+			t.Errorf("backup %s = %q, want one line", name, b)
+		}
+	}
+	if b, _ := os.ReadFile(path); string(b) != line {
+		t.Errorf("current file = %q, want the last line", b)
+	}
+}
+
+func TestRotatingFileOversizedRecord(t *testing.T) {
+	dir := t.TempDir()
+	r, err := openRotatingFile(filepath.Join(dir, "vmix-mcp.log"), 10, 0, 0)
+	if err != nil {
+		t.Fatalf("openRotatingFile() error = %v", err)
+	}
+	defer r.Close()
+
+	// 空のファイルはローテーションせず、大きな記録も分割しない
+	record := strings.Repeat("b", 50) + "\n"
+	if n, err := r.Write([]byte(record)); err != nil || n != len(record) {
+		t.Fatalf("Write() = %d, %v", n, err)
+	}
+	if files := logFiles(t, dir); len(files) != 1 {
+		t.Errorf("files = %v, want no backup", files)
+	}
+}
+
+func TestRotatingFileNoRotation(t *testing.T) {
+	dir := t.TempDir()
+	r, err := openRotatingFile(filepath.Join(dir, "vmix-mcp.log"), 0, 0, 0)
+	if err != nil {
+		t.Fatalf("openRotatingFile() error = %v", err)
+	}
+	defer r.Close()
+	for i := 0; i < 100; i++ {
+		r.Write([]byte("line\n"))
+	}
+	if files := logFiles(t, dir); len(files) != 1 {
+		t.Errorf("files = %v, want no backup with maxSize 0", files)
+	}
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	path := filepath.Join(dir, "vmix-mcp.log")
+	r, err := openRotatingFile(path, 10, 0, 0)
+	if err != nil {
+		t.Fatalf("openRotatingFile() error = %v", err)
+	}
+	defer r.Close()
+	if _, err := r.Write([]byte("first line\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// ディレクトリが消えるとリネームも開き直しも失敗する
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("lost\n")); err == nil {
+		t.Error("Write() error = nil without the log directory")
+	}
+
+	// ディレクトリが戻れば閉じたファイルではなく新しいファイルに書く
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("recovered\n")); err != nil {
+		t.Fatalf("Write() after the directory came back error = %v", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "recovered\n" {
+		t.Errorf("log file = %q, want the line written after recovery", b)
+	}
+}
+
+func TestRotatingFilePrune(t *testing.T) {
+	now := time.Now()
+	backups := map[string]time.Duration{
+		"vmix-mcp-20240501T120000.000.log": 1 * time.Hour,
+		"vmix-mcp-20240430T120000.000.log": 30 * time.Hour,
+		"vmix-mcp-20240429T120000.000.log": 60 * time.Hour,
+		"vmix_mcp_log_20240401.log":        90 * time.Hour,
+	}
+	cases := []struct {
+		name       string
+		maxAgeDays int
+		maxBackups int
+		want       []string
+	}{
+		{
+			name: "keep all",
+			want: []string{"vmix-mcp-20240429T120000.000.log", "vmix-mcp-20240430T120000.000.log", "vmix-mcp-20240501T120000.000.log", "vmix_mcp_log_20240401.log"},
+		},
+		{
+			name:       "max backups",
+			maxBackups: 2,
+			want:       []string{"vmix-mcp-20240430T120000.000.log", "vmix-mcp-20240501T120000.000.log"},
+		},
+		{
+			name:       "max age",
+			maxAgeDays: 2,
+			want:       []string{"vmix-mcp-20240430T120000.000.log", "vmix-mcp-20240501T120000.000.log"},
+		},
+		{
+			name:       "max age and max backups",
+			maxAgeDays: 2,
+			maxBackups: 1,
+			want:       []string{"vmix-mcp-20240501T120000.000.log"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, age := range backups {
+				p := filepath.Join(dir, name)
+				if err := os.WriteFile(p, []byte("old\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(p, now.Add(-age), now.Add(-age)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// ローテーションされたファイル以外は消さない
+			for _, name := range []string{"other.log", "vmix-mcp.txt"} {
+				if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r, err := openRotatingFile(filepath.Join(dir, "vmix-mcp.log"), 0, tc.maxAgeDays, tc.maxBackups)
+			if err != nil {
+				t.Fatalf("openRotatingFile() error = %v", err)
+			}
+			defer r.Close()
+
+			want := append([]string{"other.log", "vmix-mcp.log", "vmix-mcp.txt"}, tc.want...)
+			sort.Strings(want)
+			if got := logFiles(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("files = %v, want %v", got, want)
+			}
+		})
+	}
+}
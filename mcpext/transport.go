@@ -0,0 +1,324 @@
+// Package mcpext extends an mcp-golang server transport with features the server does not expose:
+// sending arbitrary notifications, answering additional requests and advertising extra capabilities.
+package mcpext
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// RequestHandler answers a request the mcp-golang server does not know about.
+type RequestHandler func(ctx context.Context, params json.RawMessage) (any, error)
+
+// NotificationHandler is called when the client sends a notification. The notification is still passed to the server.
+type NotificationHandler func(ctx context.Context, params json.RawMessage)
+
+// Sessions is implemented by transports which serve several clients at once, such as mcphttp.
+// Subscriptions are kept per session on them. Other transports serve a single client whose session ID is "".
+type Sessions interface {
+	// SessionID returns the ID of the session which sent the message handled in ctx.
+	SessionID(ctx context.Context) (string, bool)
+	// WithSession returns ctx whose notifications are sent only to the session id.
+	WithSession(ctx context.Context, id string) context.Context
+	// OnSessionClosed calls handler with the ID of every session which ended.
+	OnSessionClosed(handler func(id string))
+}
+
+// Transport wraps a transport.Transport. Pass it to mcp_golang.NewServer instead of the wrapped one.
+type Transport struct {
+	transport.Transport
+	sessions Sessions // nil when the wrapped transport has a single client
+
+	mu            sync.RWMutex
+	handlers      map[string]RequestHandler
+	listeners     map[string][]NotificationHandler
+	capabilities  map[string]map[string]any
+	initializing  map[transport.RequestId]struct{}
+	subscribed    map[string]map[string]struct{} // URIs by session ID
+	onSubscribe   []func(uri string)
+	sessionClosed []func(id string)
+}
+
+// Wrap wraps t.
+func Wrap(t transport.Transport) *Transport {
+	w := &Transport{
+		Transport:    t,
+		handlers:     map[string]RequestHandler{},
+		listeners:    map[string][]NotificationHandler{},
+		capabilities: map[string]map[string]any{},
+		initializing: map[transport.RequestId]struct{}{},
+		subscribed:   map[string]map[string]struct{}{},
+	}
+	if s, ok := t.(Sessions); ok {
+		w.sessions = s
+		s.OnSessionClosed(w.closeSession)
+	}
+	w.HandleRequest("resources/subscribe", w.handleSubscribe)
+	w.HandleRequest("resources/unsubscribe", w.handleUnsubscribe)
+	w.AddCapability("resources", "subscribe", true)
+	w.AddCapability("resources", "listChanged", true)
+	return w
+}
+
+// SessionID returns the ID of the client session which sent the message handled in ctx.
+// It is "" for transports with a single client.
+func (t *Transport) SessionID(ctx context.Context) string {
+	if t.sessions == nil {
+		return ""
+	}
+	id, _ := t.sessions.SessionID(ctx)
+	return id
+}
+
+// OnSessionClosed calls handler with the ID of every client session which ended, so per-client state can be dropped.
+func (t *Transport) OnSessionClosed(handler func(id string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sessionClosed = append(t.sessionClosed, handler)
+}
+
+func (t *Transport) closeSession(id string) {
+	t.mu.Lock()
+	delete(t.subscribed, id)
+	handlers := t.sessionClosed
+	t.mu.Unlock()
+	for _, h := range handlers {
+		h(id)
+	}
+}
+
+// HandleRequest answers requests of method with handler instead of passing them to the server.
+func (t *Transport) HandleRequest(method string, handler RequestHandler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlers[method] = handler
+}
+
+// OnNotification calls handler when the client sends a notification of method, e.g. notifications/initialized.
+func (t *Transport) OnNotification(method string, handler NotificationHandler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.listeners[method] = append(t.listeners[method], handler)
+}
+
+// AddCapability adds capabilities[name][key] = value to the initialize response.
+func (t *Transport) AddCapability(name, key string, value any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.capabilities[name] == nil {
+		t.capabilities[name] = map[string]any{}
+	}
+	if key != "" {
+		t.capabilities[name][key] = value
+	}
+}
+
+// Notify sends a notification to the client.
+func (t *Transport) Notify(ctx context.Context, method string, params any) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification params: %w", err)
+	}
+	return t.Transport.Send(ctx, transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params:  b,
+	}))
+}
+
+// NotifySession sends a notification only to the client session id.
+func (t *Transport) NotifySession(ctx context.Context, id, method string, params any) error {
+	if t.sessions != nil {
+		ctx = t.sessions.WithSession(ctx, id)
+	}
+	return t.Notify(ctx, method, params)
+}
+
+// NotifyResourceUpdated sends notifications/resources/updated to every client which subscribed to uri.
+func (t *Transport) NotifyResourceUpdated(uri string) error {
+	t.mu.RLock()
+	var sessions []string
+	for id, uris := range t.subscribed {
+		if _, ok := uris[uri]; ok {
+			sessions = append(sessions, id)
+		}
+	}
+	t.mu.RUnlock()
+
+	var errs []error
+	for _, id := range sessions {
+		if err := t.NotifySession(context.Background(), id, "notifications/resources/updated", map[string]string{"uri": uri}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Subscribed reports whether any client subscribed to a resource whose URI starts with prefix.
+func (t *Transport) Subscribed(prefix string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, uris := range t.subscribed {
+		for uri := range uris {
+			if strings.HasPrefix(uri, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OnSubscribe calls handler when the client subscribes to a resource.
+func (t *Transport) OnSubscribe(handler func(uri string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onSubscribe = append(t.onSubscribe, handler)
+}
+
+// SetMessageHandler implements transport.Transport.
+func (t *Transport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.Transport.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		if message.Type == transport.BaseMessageTypeJSONRPCNotificationType {
+			t.mu.RLock()
+			listeners := t.listeners[message.JsonRpcNotification.Method]
+			t.mu.RUnlock()
+			for _, l := range listeners {
+				l(ctx, message.JsonRpcNotification.Params)
+			}
+		}
+		if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+			handler(ctx, message)
+			return
+		}
+
+		req := message.JsonRpcRequest
+		t.mu.Lock()
+		if req.Method == "initialize" {
+			t.initializing[req.Id] = struct{}{}
+		}
+		h, ok := t.handlers[req.Method]
+		t.mu.Unlock()
+		if !ok {
+			handler(ctx, message)
+			return
+		}
+
+		go t.answer(ctx, req, h)
+	})
+}
+
+func (t *Transport) answer(ctx context.Context, req *transport.BaseJSONRPCRequest, h RequestHandler) {
+	result, err := h(ctx, req.Params)
+	if err == nil {
+		var b []byte
+		b, err = json.Marshal(result)
+		if err == nil {
+			t.Transport.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      req.Id,
+				Result:  b,
+			}))
+			return
+		}
+	}
+	t.Transport.Send(ctx, transport.NewBaseMessageError(&transport.BaseJSONRPCError{
+		Jsonrpc: "2.0",
+		Id:      req.Id,
+		Error: transport.BaseJSONRPCErrorInner{
+			Code:    -32000,
+			Message: err.Error(),
+		},
+	}))
+}
+
+// Send implements transport.Transport. Capabilities added by AddCapability are merged into the initialize response.
+func (t *Transport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	if message.Type == transport.BaseMessageTypeJSONRPCResponseType {
+		t.mu.Lock()
+		_, ok := t.initializing[message.JsonRpcResponse.Id]
+		delete(t.initializing, message.JsonRpcResponse.Id)
+		t.mu.Unlock()
+		if ok {
+			if err := t.patchInitialize(message.JsonRpcResponse); err != nil {
+				return err
+			}
+		}
+	}
+	return t.Transport.Send(ctx, message)
+}
+
+func (t *Transport) patchInitialize(resp *transport.BaseJSONRPCResponse) error {
+	result := map[string]any{}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return fmt.Errorf("failed to unmarshal initialize result: %w", err)
+	}
+	capabilities, _ := result["capabilities"].(map[string]any)
+	if capabilities == nil {
+		capabilities = map[string]any{}
+	}
+
+	t.mu.RLock()
+	for name, values := range t.capabilities {
+		c, _ := capabilities[name].(map[string]any)
+		if c == nil {
+			c = map[string]any{}
+		}
+		for k, v := range values {
+			c[k] = v
+		}
+		capabilities[name] = c
+	}
+	t.mu.RUnlock()
+
+	result["capabilities"] = capabilities
+	b, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal initialize result: %w", err)
+	}
+	resp.Result = b
+	return nil
+}
+
+type resourceSubscription struct {
+	URI string `json:"uri"`
+}
+
+func (t *Transport) handleSubscribe(ctx context.Context, params json.RawMessage) (any, error) {
+	var p resourceSubscription
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	id := t.SessionID(ctx)
+	t.mu.Lock()
+	if t.subscribed[id] == nil {
+		t.subscribed[id] = map[string]struct{}{}
+	}
+	t.subscribed[id][p.URI] = struct{}{}
+	handlers := t.onSubscribe
+	t.mu.Unlock()
+	for _, h := range handlers {
+		h(p.URI)
+	}
+	return map[string]any{}, nil
+}
+
+func (t *Transport) handleUnsubscribe(ctx context.Context, params json.RawMessage) (any, error) {
+	var p resourceSubscription
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	id := t.SessionID(ctx)
+	t.mu.Lock()
+	delete(t.subscribed[id], p.URI)
+	if len(t.subscribed[id]) == 0 {
+		delete(t.subscribed, id)
+	}
+	t.mu.Unlock()
+	return map[string]any{}, nil
+}
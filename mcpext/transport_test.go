@@ -0,0 +1,153 @@
+package mcpext_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+
+	"github.com/FlowingSPDG/mcp-vmix/mcpext"
+)
+
+type sessionKey struct{}
+
+// fakeTransport records sent messages with the session of their context.
+type fakeTransport struct {
+	mu      sync.Mutex
+	handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+	closed  []func(id string)
+	sent    []sentMessage
+}
+
+type sentMessage struct {
+	session string
+	message *transport.BaseJsonRpcMessage
+}
+
+func (f *fakeTransport) Start(context.Context) error { return nil }
+func (f *fakeTransport) Close() error                { return nil }
+func (f *fakeTransport) SetCloseHandler(func())      {}
+func (f *fakeTransport) SetErrorHandler(func(error)) {}
+
+func (f *fakeTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	f.handler = handler
+}
+
+func (f *fakeTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id, _ := f.SessionID(ctx)
+	f.sent = append(f.sent, sentMessage{session: id, message: message})
+	return nil
+}
+
+func (f *fakeTransport) SessionID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sessionKey{}).(string)
+	return id, ok
+}
+
+func (f *fakeTransport) WithSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, id)
+}
+
+func (f *fakeTransport) OnSessionClosed(handler func(id string)) {
+	f.closed = append(f.closed, handler)
+}
+
+func (f *fakeTransport) closeSession(id string) {
+	for _, h := range f.closed {
+		h(id)
+	}
+}
+
+// messages returns the sent messages and forgets them.
+func (f *fakeTransport) messages() []sentMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sent := f.sent
+	f.sent = nil
+	return sent
+}
+
+// request passes a request of the session to the wrapped handler and returns the messages sent in answer.
+func request(t *testing.T, f *fakeTransport, session, method, params string) []sentMessage {
+	t.Helper()
+	ctx := f.WithSession(context.Background(), session)
+	f.handler(ctx, transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Jsonrpc: "2.0",
+		Id:      1,
+		Method:  method,
+		Params:  json.RawMessage(params),
+	}))
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if sent := f.messages(); len(sent) > 0 {
+			return sent
+		}
+	}
+	t.Fatalf("%s was not answered", method)
+	return nil
+}
+
+func TestSubscriptionsPerSession(t *testing.T) {
+	f := &fakeTransport{}
+	w := mcpext.Wrap(f)
+	w.SetMessageHandler(func(context.Context, *transport.BaseJsonRpcMessage) {
+		t.Error("resources/subscribe reached the server")
+	})
+
+	request(t, f, "a", "resources/subscribe", `{"uri":"vmix://main/state"}`)
+	request(t, f, "b", "resources/subscribe", `{"uri":"vmix://main/tally"}`)
+	if !w.Subscribed("vmix://main/") {
+		t.Fatal("Subscribed() = false after subscribing")
+	}
+
+	if err := w.NotifyResourceUpdated("vmix://main/state"); err != nil {
+		t.Fatalf("NotifyResourceUpdated() error = %v", err)
+	}
+	sent := f.messages()
+	if len(sent) != 1 || sent[0].session != "a" || sent[0].message.JsonRpcNotification.Method != "notifications/resources/updated" {
+		t.Errorf("sent %+v, want one update to session a", sent)
+	}
+
+	// Subscriptions of a closed session are dropped.
+	f.closeSession("a")
+	w.NotifyResourceUpdated("vmix://main/state")
+	if sent := f.messages(); len(sent) != 0 {
+		t.Errorf("sent %+v after the subscribed session closed", sent)
+	}
+
+	request(t, f, "b", "resources/unsubscribe", `{"uri":"vmix://main/tally"}`)
+	if w.Subscribed("vmix://") {
+		t.Error("Subscribed() = true after every subscription ended")
+	}
+}
+
+func TestInitializeCapabilities(t *testing.T) {
+	f := &fakeTransport{}
+	w := mcpext.Wrap(f)
+	w.AddCapability("logging", "", nil)
+	w.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		w.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Jsonrpc: "2.0",
+			Id:      message.JsonRpcRequest.Id,
+			Result:  json.RawMessage(`{"capabilities":{"tools":{}}}`),
+		}))
+	})
+
+	sent := request(t, f, "", "initialize", `{}`)
+	var result struct {
+		Capabilities map[string]map[string]any `json:"capabilities"`
+	}
+	if err := json.Unmarshal(sent[0].message.JsonRpcResponse.Result, &result); err != nil {
+		t.Fatalf("failed to decode the initialize result: %v", err)
+	}
+	if result.Capabilities["tools"] == nil || result.Capabilities["logging"] == nil {
+		t.Errorf("capabilities = %v, want the server's and the added ones", result.Capabilities)
+	}
+	if result.Capabilities["resources"]["subscribe"] != true {
+		t.Errorf("capabilities = %v, want resources.subscribe", result.Capabilities)
+	}
+}
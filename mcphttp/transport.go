@@ -0,0 +1,731 @@
+// Package mcphttp is an MCP server transport over HTTP, so several clients on the LAN can share one long-lived server.
+//
+// It serves the Streamable HTTP transport at /mcp and the older HTTP+SSE transport at /sse and /message.
+// mcp-golang's own HTTP transport can only send responses, which breaks notifications, and serves one client at a time.
+// Request IDs of all clients are replaced with unique IDs before they reach the server and restored in the responses.
+package mcphttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+const (
+	// SessionHeader carries the session ID of the Streamable HTTP transport.
+	SessionHeader = "Mcp-Session-Id"
+
+	// sessionQueueSize is how many server messages are kept for a session whose stream is not read.
+	sessionQueueSize = 64
+	// maxBodySize limits the size of a posted message.
+	maxBodySize = 4 << 20
+	// pingInterval keeps idle event streams open through proxies.
+	pingInterval = 30 * time.Second
+	// defaultSessionTimeout is how long a session without requests or an open event stream is kept.
+	defaultSessionTimeout = 30 * time.Minute
+)
+
+// Options configures the HTTP transport.
+type Options struct {
+	Addr string // bind address such as "127.0.0.1:8080" or ":8080"
+
+	// Token is the bearer token required from clients. Empty disables authentication.
+	Token string
+
+	// TLS is enabled when both CertFile and KeyFile are set.
+	CertFile string
+	KeyFile  string
+
+	// SessionTimeout closes sessions which sent no request and had no open event stream for this long.
+	// Requests with an expired session ID get 404, so the client initializes again. 0 uses 30 minutes.
+	SessionTimeout time.Duration
+}
+
+// TLS reports whether the transport serves HTTPS.
+func (o Options) TLS() bool {
+	return o.CertFile != "" && o.KeyFile != ""
+}
+
+// Transport implements transport.Transport for many HTTP clients.
+type Transport struct {
+	opts   Options
+	server *http.Server
+
+	nextID atomic.Int64
+
+	mu             sync.RWMutex
+	messageHandler func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+	errorHandler   func(error)
+	closeHandler   func()
+	sessionClosed  []func(id string)
+	sessions       map[string]*session
+	pending        map[transport.RequestId]*pendingRequest
+
+	stop     chan struct{} // stops expiring sessions
+	stopOnce sync.Once
+}
+
+type session struct {
+	id string
+	// out carries notifications, and every response for the HTTP+SSE transport.
+	out       chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+	streaming atomic.Bool
+	// lastActive is when the session last sent a request or closed its event stream, in Unix nanoseconds.
+	lastActive atomic.Int64
+}
+
+type pendingRequest struct {
+	session *session
+	id      json.RawMessage // ID sent by the client
+	// reply receives the response for the Streamable HTTP transport. It is nil for HTTP+SSE.
+	reply chan []byte
+}
+
+// New creates a transport. It starts listening when the server calls Start.
+func New(opts Options) *Transport {
+	if opts.SessionTimeout <= 0 {
+		opts.SessionTimeout = defaultSessionTimeout
+	}
+	return &Transport{
+		opts:     opts,
+		sessions: map[string]*session{},
+		pending:  map[transport.RequestId]*pendingRequest{},
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start implements transport.Transport. It returns after the listener is ready.
+func (t *Transport) Start(_ context.Context) error {
+	t.server = &http.Server{
+		Handler:           t.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if t.opts.TLS() {
+		cert, err := tls.LoadX509KeyPair(t.opts.CertFile, t.opts.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		t.server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+
+	ln, err := net.Listen("tcp", t.opts.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", t.opts.Addr, err)
+	}
+
+	go func() {
+		var err error
+		if t.opts.TLS() {
+			err = t.server.ServeTLS(ln, "", "")
+		} else {
+			err = t.server.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.handleError(err)
+		}
+	}()
+	go t.expireSessions()
+	return nil
+}
+
+// Close implements transport.Transport.
+func (t *Transport) Close() error {
+	var err error
+	if t.server != nil {
+		err = t.server.Close()
+	}
+	t.stopOnce.Do(func() { close(t.stop) })
+
+	t.mu.Lock()
+	sessions := t.sessions
+	t.sessions = map[string]*session{}
+	t.pending = map[transport.RequestId]*pendingRequest{}
+	closeHandler := t.closeHandler
+	t.mu.Unlock()
+
+	for _, s := range sessions {
+		s.close()
+	}
+	if closeHandler != nil {
+		closeHandler()
+	}
+	return err
+}
+
+// SetCloseHandler implements transport.Transport.
+func (t *Transport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closeHandler = handler
+}
+
+// SetErrorHandler implements transport.Transport.
+func (t *Transport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errorHandler = handler
+}
+
+// SetMessageHandler implements transport.Transport.
+func (t *Transport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messageHandler = handler
+}
+
+func (t *Transport) handleError(err error) {
+	t.mu.RLock()
+	handler := t.errorHandler
+	t.mu.RUnlock()
+	if handler != nil {
+		handler(err)
+	}
+}
+
+// OnSessionClosed calls handler with the ID of every session which ended, so per-client state can be dropped.
+func (t *Transport) OnSessionClosed(handler func(id string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sessionClosed = append(t.sessionClosed, handler)
+}
+
+// Send implements transport.Transport.
+// Responses go back to the client which sent the request. Notifications go to the session of ctx (see WithSession),
+// or to every session when ctx has none, e.g. for list changes which concern every client.
+func (t *Transport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	switch message.Type {
+	case transport.BaseMessageTypeJSONRPCResponseType, transport.BaseMessageTypeJSONRPCErrorType:
+		var id transport.RequestId
+		if message.Type == transport.BaseMessageTypeJSONRPCErrorType {
+			id = message.JsonRpcError.Id
+		} else {
+			id = message.JsonRpcResponse.Id
+		}
+		t.mu.Lock()
+		p, ok := t.pending[id]
+		delete(t.pending, id)
+		t.mu.Unlock()
+		if !ok {
+			return fmt.Errorf("no pending request for id %d", id)
+		}
+
+		b, err := marshalWithID(message, p.id)
+		if err != nil {
+			return err
+		}
+		if p.reply != nil {
+			p.reply <- b
+			return nil
+		}
+		p.session.send(b, false)
+		return nil
+
+	case transport.BaseMessageTypeJSONRPCNotificationType:
+		b, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("failed to marshal notification: %w", err)
+		}
+		if id, ok := SessionID(ctx); ok {
+			// The session may have ended meanwhile. Nobody is left to receive it then.
+			if s, ok := t.session(id); ok {
+				s.send(b, true)
+			}
+			return nil
+		}
+		t.mu.RLock()
+		defer t.mu.RUnlock()
+		for _, s := range t.sessions {
+			s.send(b, true)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("sending %s messages to HTTP clients is not supported", message.Type)
+	}
+}
+
+// marshalWithID marshals message with the ID sent by the client.
+func marshalWithID(message *transport.BaseJsonRpcMessage, id json.RawMessage) ([]byte, error) {
+	b, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	fields["id"] = id
+	return json.Marshal(fields)
+}
+
+// send queues b for the event stream of the session.
+// Notifications are dropped when nobody reads the stream, responses wait until the session ends.
+func (s *session) send(b []byte, dropIfFull bool) {
+	if dropIfFull {
+		select {
+		case s.out <- b:
+		case <-s.done:
+		default:
+		}
+		return
+	}
+	select {
+	case s.out <- b:
+	case <-s.done:
+	}
+}
+
+func (s *session) close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// touch records activity of the client, which keeps the session from expiring.
+func (s *session) touch() {
+	s.lastActive.Store(time.Now().UnixNano())
+}
+
+// idle reports whether the session had no activity since before deadline. Sessions with an open event stream are never idle.
+func (s *session) idle(deadline time.Time) bool {
+	return !s.streaming.Load() && s.lastActive.Load() < deadline.UnixNano()
+}
+
+func (t *Transport) newSession() (*session, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate session id: %w", err)
+	}
+	s := &session{
+		id:   hex.EncodeToString(b),
+		out:  make(chan []byte, sessionQueueSize),
+		done: make(chan struct{}),
+	}
+	s.touch()
+	t.mu.Lock()
+	t.sessions[s.id] = s
+	t.mu.Unlock()
+	return s, nil
+}
+
+func (t *Transport) session(id string) (*session, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	s, ok := t.sessions[id]
+	return s, ok
+}
+
+func (t *Transport) closeSession(s *session) {
+	t.mu.Lock()
+	delete(t.sessions, s.id)
+	for id, p := range t.pending {
+		if p.session == s {
+			delete(t.pending, id)
+		}
+	}
+	handlers := t.sessionClosed
+	t.mu.Unlock()
+	s.close()
+	for _, h := range handlers {
+		h(s.id)
+	}
+}
+
+// expireSessions closes idle sessions until the transport is closed.
+// Clients which went away without DELETE or closing their stream would otherwise keep their session and its state forever.
+func (t *Transport) expireSessions() {
+	ticker := time.NewTicker(max(t.opts.SessionTimeout/4, 10*time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case now := <-ticker.C:
+			for _, s := range t.idleSessions(now.Add(-t.opts.SessionTimeout)) {
+				t.closeSession(s)
+			}
+		}
+	}
+}
+
+// idleSessions returns the sessions which are idle since deadline and wait for no response.
+func (t *Transport) idleSessions(deadline time.Time) []*session {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	busy := map[*session]bool{}
+	for _, p := range t.pending {
+		busy[p.session] = true
+	}
+	var idle []*session
+	for _, s := range t.sessions {
+		if !busy[s] && s.idle(deadline) {
+			idle = append(idle, s)
+		}
+	}
+	return idle
+}
+
+func (t *Transport) forget(p *pendingRequest) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, q := range t.pending {
+		if q == p {
+			delete(t.pending, id)
+		}
+	}
+}
+
+type sessionKey struct{}
+
+// SessionID returns the ID of the session which sent the message being handled.
+func SessionID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sessionKey{}).(string)
+	return id, ok
+}
+
+// WithSession returns ctx whose notifications are sent only to the session id.
+func WithSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, id)
+}
+
+// SessionID returns the ID of the session which sent the message being handled. It is SessionID for mcpext.Sessions.
+func (t *Transport) SessionID(ctx context.Context) (string, bool) {
+	return SessionID(ctx)
+}
+
+// WithSession returns ctx whose notifications are sent only to the session id. It is WithSession for mcpext.Sessions.
+func (t *Transport) WithSession(ctx context.Context, id string) context.Context {
+	return WithSession(ctx, id)
+}
+
+type envelope struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// dispatch passes one message from a client to the server and returns the pending request if it is a request.
+// Responses from the client are dropped because the server does not send requests.
+func (t *Transport) dispatch(s *session, raw json.RawMessage, streamable bool) (*pendingRequest, error) {
+	var msg envelope
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return nil, fmt.Errorf("invalid JSON-RPC message: %w", err)
+	}
+	if msg.Method == "" {
+		return nil, nil
+	}
+	if len(msg.Params) == 0 {
+		msg.Params = json.RawMessage("{}")
+	}
+
+	t.mu.RLock()
+	handler := t.messageHandler
+	t.mu.RUnlock()
+	if handler == nil {
+		return nil, errors.New("server is not running")
+	}
+	// The request context of the HTTP+SSE transport ends before the response is sent, so the session is used instead.
+	ctx := WithSession(context.Background(), s.id)
+
+	if len(msg.ID) == 0 || string(msg.ID) == "null" {
+		handler(ctx, transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+			Jsonrpc: "2.0",
+			Method:  msg.Method,
+			Params:  t.rewriteCancelled(s, msg.Method, msg.Params),
+		}))
+		return nil, nil
+	}
+
+	id := transport.RequestId(t.nextID.Add(1))
+	p := &pendingRequest{session: s, id: msg.ID}
+	if streamable {
+		p.reply = make(chan []byte, 1)
+	}
+	t.mu.Lock()
+	t.pending[id] = p
+	t.mu.Unlock()
+
+	handler(ctx, transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Jsonrpc: "2.0",
+		Id:      id,
+		Method:  msg.Method,
+		Params:  msg.Params,
+	}))
+	return p, nil
+}
+
+// rewriteCancelled replaces the client request ID of notifications/cancelled with the ID the server knows.
+func (t *Transport) rewriteCancelled(s *session, method string, params json.RawMessage) json.RawMessage {
+	if method != "notifications/cancelled" {
+		return params
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(params, &fields); err != nil {
+		return params
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for id, p := range t.pending {
+		if p.session == s && bytes.Equal(p.id, fields["requestId"]) {
+			fields["requestId"] = json.RawMessage(fmt.Sprint(id))
+			b, err := json.Marshal(fields)
+			if err != nil {
+				return params
+			}
+			return b
+		}
+	}
+	return params
+}
+
+func (t *Transport) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", t.handleStreamable)
+	mux.HandleFunc("/sse", t.handleSSE)
+	mux.HandleFunc("/message", t.handleMessage)
+	return t.authorize(mux)
+}
+
+func (t *Transport) authorize(next http.Handler) http.Handler {
+	if t.opts.Token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(t.opts.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp-vmix"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readMessages reads a posted message or batch of messages.
+func readMessages(r *http.Request) ([]json.RawMessage, bool, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read request body: %w", err)
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, false, fmt.Errorf("invalid JSON-RPC batch: %w", err)
+		}
+		return batch, true, nil
+	}
+	return []json.RawMessage{body}, false, nil
+}
+
+func isInitialize(messages []json.RawMessage) bool {
+	for _, raw := range messages {
+		var msg envelope
+		if json.Unmarshal(raw, &msg) == nil && msg.Method == "initialize" {
+			return true
+		}
+	}
+	return false
+}
+
+// handleStreamable serves the Streamable HTTP transport.
+func (t *Transport) handleStreamable(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		t.handleStreamablePost(w, r)
+	case http.MethodGet:
+		s, ok := t.session(r.Header.Get(SessionHeader))
+		if !ok {
+			http.Error(w, "Unknown session", http.StatusNotFound)
+			return
+		}
+		s.touch()
+		t.stream(w, r, s, "")
+	case http.MethodDelete:
+		s, ok := t.session(r.Header.Get(SessionHeader))
+		if !ok {
+			http.Error(w, "Unknown session", http.StatusNotFound)
+			return
+		}
+		t.closeSession(s)
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (t *Transport) handleStreamablePost(w http.ResponseWriter, r *http.Request) {
+	messages, batch, err := readMessages(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var s *session
+	sid := r.Header.Get(SessionHeader)
+	switch {
+	case sid != "":
+		var ok bool
+		if s, ok = t.session(sid); !ok {
+			http.Error(w, "Unknown session", http.StatusNotFound)
+			return
+		}
+		s.touch()
+	case isInitialize(messages):
+		if s, err = t.newSession(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Missing "+SessionHeader+" header", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set(SessionHeader, s.id)
+
+	var pending []*pendingRequest
+	for _, raw := range messages {
+		p, err := t.dispatch(s, raw, true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if p != nil {
+			pending = append(pending, p)
+		}
+	}
+	if len(pending) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	responses := make([]json.RawMessage, 0, len(pending))
+	for _, p := range pending {
+		select {
+		case b := <-p.reply:
+			responses = append(responses, b)
+		case <-r.Context().Done():
+			for _, p := range pending {
+				t.forget(p)
+			}
+			return
+		case <-s.done:
+			http.Error(w, "Session closed", http.StatusNotFound)
+			return
+		}
+	}
+
+	var body []byte
+	if batch {
+		body, err = json.Marshal(responses)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		body = responses[0]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// handleSSE opens a session of the HTTP+SSE transport.
+func (t *Transport) handleSSE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s, err := t.newSession()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer t.closeSession(s)
+	t.stream(w, r, s, "/message?sessionId="+s.id)
+}
+
+// handleMessage receives messages of the HTTP+SSE transport. Responses are sent on the event stream.
+func (t *Transport) handleMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s, ok := t.session(r.URL.Query().Get("sessionId"))
+	if !ok {
+		http.Error(w, "Unknown session", http.StatusNotFound)
+		return
+	}
+	s.touch()
+	messages, _, err := readMessages(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, raw := range messages {
+		if _, err := t.dispatch(s, raw, false); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// stream writes the messages of the session as server-sent events until the client disconnects.
+// endpoint is sent first for the HTTP+SSE transport.
+func (t *Transport) stream(w http.ResponseWriter, r *http.Request, s *session, endpoint string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	if !s.streaming.CompareAndSwap(false, true) {
+		http.Error(w, "The session already has an event stream", http.StatusConflict)
+		return
+	}
+	defer func() {
+		s.touch()
+		s.streaming.Store(false)
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set(SessionHeader, s.id)
+	w.WriteHeader(http.StatusOK)
+	if endpoint != "" {
+		fmt.Fprintf(w, "event: endpoint\ndata: %s\n\n", endpoint)
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case b := <-s.out:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", b)
+			flusher.Flush()
+		}
+	}
+}
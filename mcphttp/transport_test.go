@@ -0,0 +1,240 @@
+package mcphttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// newTestServer serves t with a message handler which answers every request with its method.
+func newTestServer(t *testing.T, opts Options) (*Transport, *httptest.Server) {
+	t.Helper()
+	tr := New(opts)
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+			return
+		}
+		result, _ := json.Marshal(map[string]string{"method": message.JsonRpcRequest.Method})
+		go tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Jsonrpc: "2.0",
+			Id:      message.JsonRpcRequest.Id,
+			Result:  result,
+		}))
+	})
+	srv := httptest.NewServer(tr.handler())
+	go tr.expireSessions()
+	t.Cleanup(func() {
+		srv.Close()
+		tr.Close()
+	})
+	return tr, srv
+}
+
+func post(t *testing.T, url, session, token, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if session != "" {
+		req.Header.Set(SessionHeader, session)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+const initialize = `{"jsonrpc":"2.0","id":"init-1","method":"initialize","params":{}}`
+
+func TestBearerToken(t *testing.T) {
+	_, srv := newTestServer(t, Options{Token: "secret"})
+	for _, token := range []string{"", "wrong"} {
+		resp := post(t, srv.URL+"/mcp", "", token, initialize)
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: status = %d, want 401", token, resp.StatusCode)
+		}
+		if resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("token %q: no WWW-Authenticate header", token)
+		}
+	}
+	if resp := post(t, srv.URL+"/mcp", "", "secret", initialize); resp.StatusCode != http.StatusOK {
+		t.Errorf("valid token: status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestStreamableRoundTrip(t *testing.T) {
+	tr, srv := newTestServer(t, Options{})
+	var closed []string
+	var mu sync.Mutex
+	tr.OnSessionClosed(func(id string) {
+		mu.Lock()
+		defer mu.Unlock()
+		closed = append(closed, id)
+	})
+
+	resp := post(t, srv.URL+"/mcp", "", "", initialize)
+	sid := resp.Header.Get(SessionHeader)
+	if resp.StatusCode != http.StatusOK || sid == "" {
+		t.Fatalf("initialize: status = %d, session = %q", resp.StatusCode, sid)
+	}
+	var got struct {
+		ID     string            `json:"id"`
+		Result map[string]string `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode the initialize response: %v", err)
+	}
+	if got.ID != "init-1" || got.Result["method"] != "initialize" {
+		t.Errorf("initialize response = %+v, want the client id and the initialize result", got)
+	}
+
+	resp = post(t, srv.URL+"/mcp", sid, "", `{"jsonrpc":"2.0","id":7,"method":"tools/list"}`)
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"id":7`) || !strings.Contains(string(body), "tools/list") {
+		t.Errorf("tools/list: status = %d, body = %s", resp.StatusCode, body)
+	}
+
+	if resp := post(t, srv.URL+"/mcp", "", "", `{"jsonrpc":"2.0","id":8,"method":"tools/list"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("request without session: status = %d, want 400", resp.StatusCode)
+	}
+	if resp := post(t, srv.URL+"/mcp", "unknown", "", `{"jsonrpc":"2.0","id":9,"method":"tools/list"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("request of an unknown session: status = %d, want 404", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/mcp", nil)
+	req.Header.Set(SessionHeader, sid)
+	del, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	del.Body.Close()
+	if del.StatusCode != http.StatusOK {
+		t.Errorf("DELETE: status = %d, want 200", del.StatusCode)
+	}
+	if resp := post(t, srv.URL+"/mcp", sid, "", `{"jsonrpc":"2.0","id":10,"method":"tools/list"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("request after DELETE: status = %d, want 404", resp.StatusCode)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(closed) != 1 || closed[0] != sid {
+		t.Errorf("closed sessions = %v, want [%s]", closed, sid)
+	}
+}
+
+// readEvent reads the next server-sent event other than a ping.
+func readEvent(t *testing.T, r *bufio.Reader) (event, data string) {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read the event stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && event != "":
+			return event, data
+		}
+	}
+}
+
+func TestSSEMessage(t *testing.T) {
+	tr, srv := newTestServer(t, Options{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/sse", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+
+	event, endpoint := readEvent(t, events)
+	if event != "endpoint" || !strings.HasPrefix(endpoint, "/message?sessionId=") {
+		t.Fatalf("first event = %s %s, want the endpoint", event, endpoint)
+	}
+	sid := strings.TrimPrefix(endpoint, "/message?sessionId=")
+
+	if resp := post(t, srv.URL+endpoint, "", "", `{"jsonrpc":"2.0","id":"a","method":"tools/list"}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST /message: status = %d, want 202", resp.StatusCode)
+	}
+	event, data := readEvent(t, events)
+	if event != "message" || !strings.Contains(data, `"id":"a"`) || !strings.Contains(data, "tools/list") {
+		t.Errorf("response event = %s %s", event, data)
+	}
+
+	// Notifications with a session go only to that session.
+	err = tr.Send(WithSession(context.Background(), sid), transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+		Jsonrpc: "2.0",
+		Method:  "notifications/message",
+		Params:  json.RawMessage(`{"level":"info"}`),
+	}))
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	event, data = readEvent(t, events)
+	if event != "message" || !strings.Contains(data, "notifications/message") {
+		t.Errorf("notification event = %s %s", event, data)
+	}
+
+	if resp := post(t, srv.URL+"/message?sessionId=unknown", "", "", `{"jsonrpc":"2.0","id":"b","method":"tools/list"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("POST /message of an unknown session: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	tr, srv := newTestServer(t, Options{SessionTimeout: 50 * time.Millisecond})
+	closed := make(chan string, 2)
+	tr.OnSessionClosed(func(id string) { closed <- id })
+
+	idle := post(t, srv.URL+"/mcp", "", "", initialize).Header.Get(SessionHeader)
+	streaming := post(t, srv.URL+"/mcp", "", "", initialize).Header.Get(SessionHeader)
+
+	// A session with an open event stream does not expire.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/mcp", nil)
+	req.Header.Set(SessionHeader, streaming)
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+
+	select {
+	case id := <-closed:
+		if id != idle {
+			t.Fatalf("expired session = %s, want the idle one %s", id, idle)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the idle session did not expire")
+	}
+	if resp := post(t, srv.URL+"/mcp", idle, "", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("request of an expired session: status = %d, want 404", resp.StatusCode)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if _, ok := tr.session(streaming); !ok {
+		t.Error("the session with an open event stream expired")
+	}
+}
@@ -0,0 +1,257 @@
+// Package mcplog forwards log records to the MCP client as notifications/message,
+// so failures show up in the chat client instead of only in the log file.
+// Every client chooses its own minimum level with logging/setLevel.
+package mcplog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FlowingSPDG/mcp-vmix/mcpext"
+)
+
+// queueSize is how many records wait to be sent. Records are dropped when the client does not keep up.
+const queueSize = 256
+
+// levels maps the syslog levels of MCP to slog levels.
+var levels = []struct {
+	name  string
+	level slog.Level
+}{
+	{"debug", slog.LevelDebug},
+	{"info", slog.LevelInfo},
+	{"notice", slog.LevelInfo + 2},
+	{"warning", slog.LevelWarn},
+	{"error", slog.LevelError},
+	{"critical", slog.LevelError + 4},
+	{"alert", slog.LevelError + 8},
+	{"emergency", slog.LevelError + 12},
+}
+
+// levelOff is above every level, so nothing is sent.
+const levelOff = slog.Level(1 << 10)
+
+// ParseLevel parses an MCP log level. "warn" and "off" are accepted too.
+func ParseLevel(s string) (slog.Level, error) {
+	switch s {
+	case "warn":
+		return slog.LevelWarn, nil
+	case "off":
+		return levelOff, nil
+	}
+	for _, l := range levels {
+		if l.name == s {
+			return l.level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level: %s", s)
+}
+
+// levelName returns the highest MCP level which is not above level.
+func levelName(level slog.Level) string {
+	name := levels[0].name
+	for _, l := range levels {
+		if level >= l.level {
+			name = l.name
+		}
+	}
+	return name
+}
+
+// Notifier sends notifications to one client session.
+type Notifier interface {
+	NotifySession(ctx context.Context, id, method string, params any) error
+}
+
+// Forwarder is a slog.Handler which sends records to the clients.
+// Nothing is sent to a client until it has finished initialization.
+type Forwarder struct {
+	*handler
+}
+
+type forwarder struct {
+	level  slog.Level // until a client selects another one
+	queue  chan message
+	logger string
+
+	mu       sync.RWMutex
+	sessions map[string]*session // by client session ID
+}
+
+// session is the state of one client.
+type session struct {
+	level slog.Level
+	ready bool // the client finished initialization
+}
+
+type message struct {
+	Level  string         `json:"level"`
+	Logger string         `json:"logger,omitempty"`
+	Data   map[string]any `json:"data"`
+
+	level slog.Level
+}
+
+// New creates a forwarder which sends records at level or above until a client selects another level.
+func New(name string, level slog.Level) *Forwarder {
+	f := &forwarder{level: level, queue: make(chan message, queueSize), logger: name, sessions: map[string]*session{}}
+	return &Forwarder{handler: &handler{f: f}}
+}
+
+// Register answers logging/setLevel on t, advertises the logging capability and starts sending to a client after its notifications/initialized.
+func (fw *Forwarder) Register(t *mcpext.Transport) {
+	f := fw.f
+	t.AddCapability("logging", "", nil)
+	t.HandleRequest("logging/setLevel", func(ctx context.Context, params json.RawMessage) (any, error) {
+		return f.handleSetLevel(t.SessionID(ctx), params)
+	})
+	t.OnNotification("notifications/initialized", func(ctx context.Context, _ json.RawMessage) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.session(t.SessionID(ctx)).ready = true
+	})
+	t.OnSessionClosed(func(id string) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.sessions, id)
+	})
+	go f.send(t)
+}
+
+// session returns the state of the client id, creating it with the default level. f.mu must be locked.
+func (f *forwarder) session(id string) *session {
+	s, ok := f.sessions[id]
+	if !ok {
+		s = &session{level: f.level}
+		f.sessions[id] = s
+	}
+	return s
+}
+
+// receivers returns the IDs of the clients which want records at level.
+func (f *forwarder) receivers(level slog.Level) []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var ids []string
+	for id, s := range f.sessions {
+		if s.ready && level >= s.level {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// send writes queued records to the clients. Errors are not logged, since that would queue another record.
+func (f *forwarder) send(n Notifier) {
+	for m := range f.queue {
+		for _, id := range f.receivers(m.level) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			_ = n.NotifySession(ctx, id, "notifications/message", m)
+			cancel()
+		}
+	}
+}
+
+type setLevelParams struct {
+	Level string `json:"level"`
+}
+
+func (f *forwarder) handleSetLevel(id string, params json.RawMessage) (any, error) {
+	var p setLevelParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	level, err := ParseLevel(p.Level)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.session(id).level = level
+	return map[string]any{}, nil
+}
+
+// handler implements slog.Handler. attrs are added by WithAttrs and prefix by WithGroup.
+type handler struct {
+	f      *forwarder
+	attrs  []slog.Attr
+	prefix string
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	h.f.mu.RLock()
+	defer h.f.mu.RUnlock()
+	for _, s := range h.f.sessions {
+		if s.ready && level >= s.level {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *handler) Handle(_ context.Context, r slog.Record) error {
+	data := map[string]any{"message": r.Message}
+	for _, a := range h.attrs {
+		addAttr(data, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(data, h.prefix, a)
+		return true
+	})
+
+	select {
+	case h.f.queue <- message{Level: levelName(r.Level), Logger: h.f.logger, Data: data, level: r.Level}:
+	default:
+	}
+	return nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefixed := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	prefixed = append(prefixed, h.attrs...)
+	for _, a := range attrs {
+		prefixed = append(prefixed, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
+	}
+	return &handler{f: h.f, attrs: prefixed, prefix: h.prefix}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &handler{f: h.f, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+// addAttr adds a to data as JSON friendly values. Groups are flattened as group.key.
+func addAttr(data map[string]any, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		for _, ga := range v.Group() {
+			addAttr(data, strings.TrimPrefix(prefix+a.Key+".", "."), ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	switch v.Kind() {
+	case slog.KindDuration:
+		data[prefix+a.Key] = v.Duration().String()
+	case slog.KindTime:
+		data[prefix+a.Key] = v.Time().Format(time.RFC3339Nano)
+	default:
+		switch x := v.Any().(type) {
+		case error:
+			data[prefix+a.Key] = x.Error()
+		case fmt.Stringer:
+			data[prefix+a.Key] = x.String()
+		default:
+			data[prefix+a.Key] = x
+		}
+	}
+}
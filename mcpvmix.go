@@ -2,19 +2,21 @@ package mcpvmix
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	"image/jpeg"
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
-	models "github.com/FlowingSPDG/vmix-go"
-	vmixhttp "github.com/FlowingSPDG/vmix-go/http"
+	vmixtcp "github.com/FlowingSPDG/vmix-go/tcp"
 	mcp_golang "github.com/metoro-io/mcp-golang"
 	"github.com/metoro-io/mcp-golang/transport/stdio"
 	"github.com/samber/lo"
@@ -22,524 +24,809 @@ import (
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/xerrors"
 
+	"github.com/FlowingSPDG/mcp-vmix/audit"
+	"github.com/FlowingSPDG/mcp-vmix/config"
+	"github.com/FlowingSPDG/mcp-vmix/functions"
 	"github.com/FlowingSPDG/mcp-vmix/logger"
+	"github.com/FlowingSPDG/mcp-vmix/tcpapi"
 )
 
 type MCPvMix interface {
 	// general
-	FetchVMix(arguments ConnectVmixArguments) (*mcp_golang.ToolResponse, error)
+	FetchVMix(ctx context.Context, arguments ConnectVmixArguments) (*mcp_golang.ToolResponse, error)
+
+	// resources
+	RegisterResources(ctx context.Context, server *mcp_golang.Server, notifier ResourceNotifier) error
+
+	// instance functions
+	// ForgetSession drops the instance selected by a client session which ended.
+	ForgetSession(id string)
+	ListInstances(ctx context.Context, arguments ListInstancesArguments) (*mcp_golang.ToolResponse, error)
+	SelectInstance(ctx context.Context, arguments SelectInstanceArguments) (*mcp_golang.ToolResponse, error)
+
+	// event functions
+	EventsVMix(ctx context.Context, arguments VmixEventsArguments) (*mcp_golang.ToolResponse, error)
 
 	// shortcut functions
-	CutVMix(arguments VmixCutArguments) (*mcp_golang.ToolResponse, error)
-	FadeVMix(arguments VmixFadeArguments) (*mcp_golang.ToolResponse, error)
-	FadeToBlackVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
+	CutVMix(ctx context.Context, arguments VmixCutArguments) (*mcp_golang.ToolResponse, error)
+	FadeVMix(ctx context.Context, arguments VmixFadeArguments) (*mcp_golang.ToolResponse, error)
+	FadeToBlackVMix(ctx context.Context, arguments VmixFadeToBlackArguments) (*mcp_golang.ToolResponse, error)
+
+	// preview/program functions
+	PreviewInput(ctx context.Context, arguments PreviewInputArguments) (*mcp_golang.ToolResponse, error)
+	PreviewNext(ctx context.Context, arguments PreviewStepArguments) (*mcp_golang.ToolResponse, error)
+	PreviewPrevious(ctx context.Context, arguments PreviewStepArguments) (*mcp_golang.ToolResponse, error)
+	TransitionVMix(ctx context.Context, arguments TransitionArguments) (*mcp_golang.ToolResponse, error)
+	StingerVMix(ctx context.Context, arguments StingerArguments) (*mcp_golang.ToolResponse, error)
+	TransitionEffectVMix(ctx context.Context, arguments TransitionEffectArguments) (*mcp_golang.ToolResponse, error)
+	MixStatus(ctx context.Context, arguments MixStatusArguments) (*mcp_golang.ToolResponse, error)
+
+	// overlay functions
+	OverlayVMix(ctx context.Context, arguments OverlayArguments) (*mcp_golang.ToolResponse, error)
+	PreviewOverlayVMix(ctx context.Context, arguments PreviewOverlayArguments) (*mcp_golang.ToolResponse, error)
+	OverlayAllOffVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
+	OverlayStatus(ctx context.Context, arguments OverlayStatusArguments) (*mcp_golang.ToolResponse, error)
+
+	// title functions
+	TitleFields(ctx context.Context, arguments TitleFieldsArguments) (*mcp_golang.ToolResponse, error)
+	SetTitleFields(ctx context.Context, arguments SetTitleFieldsArguments) (*mcp_golang.ToolResponse, error)
+
+	// audio functions
+	AudioInputVMix(ctx context.Context, arguments AudioInputArguments) (*mcp_golang.ToolResponse, error)
+	AudioBusVMix(ctx context.Context, arguments AudioBusArguments) (*mcp_golang.ToolResponse, error)
+	AudioStatus(ctx context.Context, arguments AudioStatusArguments) (*mcp_golang.ToolResponse, error)
+	AudioAlerts(ctx context.Context, arguments AudioAlertsArguments) (*mcp_golang.ToolResponse, error)
+
+	// replay functions
+	ReplayMark(ctx context.Context, arguments ReplayMarkArguments) (*mcp_golang.ToolResponse, error)
+	ReplayPlay(ctx context.Context, arguments ReplayPlayArguments) (*mcp_golang.ToolResponse, error)
+	ReplaySpeed(ctx context.Context, arguments ReplaySpeedArguments) (*mcp_golang.ToolResponse, error)
+	ReplaySelectEvents(ctx context.Context, arguments ReplaySelectEventsArguments) (*mcp_golang.ToolResponse, error)
+	ReplayCamera(ctx context.Context, arguments ReplayCameraArguments) (*mcp_golang.ToolResponse, error)
+	ReplayStartRecording(ctx context.Context, arguments ReplayRecordingArguments) (*mcp_golang.ToolResponse, error)
+	ReplayStopRecording(ctx context.Context, arguments ReplayRecordingArguments) (*mcp_golang.ToolResponse, error)
+	ReplayStatus(ctx context.Context, arguments ReplayStatusArguments) (*mcp_golang.ToolResponse, error)
+	ReplayEvents(ctx context.Context, arguments ReplayEventsArguments) (*mcp_golang.ToolResponse, error)
+
+	// PTZ functions
+	PTZMove(ctx context.Context, arguments PTZMoveArguments) (*mcp_golang.ToolResponse, error)
+	PTZZoom(ctx context.Context, arguments PTZZoomArguments) (*mcp_golang.ToolResponse, error)
+	PTZFocus(ctx context.Context, arguments PTZFocusArguments) (*mcp_golang.ToolResponse, error)
+	PTZCreateVirtualInput(ctx context.Context, arguments PTZCreateVirtualInputArguments) (*mcp_golang.ToolResponse, error)
+	PTZMoveToVirtualInput(ctx context.Context, arguments PTZMoveToVirtualInputArguments) (*mcp_golang.ToolResponse, error)
+	PTZInputs(ctx context.Context, arguments PTZInputsArguments) (*mcp_golang.ToolResponse, error)
+
+	// input management functions
+	AddInput(ctx context.Context, arguments AddInputArguments) (*mcp_golang.ToolResponse, error)
+	DuplicateInput(ctx context.Context, arguments DuplicateInputArguments) (*mcp_golang.ToolResponse, error)
+	RemoveInput(ctx context.Context, arguments RemoveInputArguments) (*mcp_golang.ToolResponse, error)
+	RenameInput(ctx context.Context, arguments RenameInputArguments) (*mcp_golang.ToolResponse, error)
+	MoveInput(ctx context.Context, arguments MoveInputArguments) (*mcp_golang.ToolResponse, error)
+	ResetInput(ctx context.Context, arguments InputArguments) (*mcp_golang.ToolResponse, error)
+
+	// playback functions
+	PlaybackVMix(ctx context.Context, arguments PlaybackArguments) (*mcp_golang.ToolResponse, error)
+	SeekVMix(ctx context.Context, arguments SeekArguments) (*mcp_golang.ToolResponse, error)
+	PlaybackRateVMix(ctx context.Context, arguments PlaybackRateArguments) (*mcp_golang.ToolResponse, error)
+	LoopVMix(ctx context.Context, arguments LoopArguments) (*mcp_golang.ToolResponse, error)
+	MarkVMix(ctx context.Context, arguments MarkArguments) (*mcp_golang.ToolResponse, error)
+	TimeRemaining(ctx context.Context, arguments TimeRemainingArguments) (*mcp_golang.ToolResponse, error)
 
 	// recording functions
-	StartRecordingVMix(arguments VmixRecordingArguments) (*mcp_golang.ToolResponse, error)
-	StopRecordingVMix(arguments VmixRecordingArguments) (*mcp_golang.ToolResponse, error)
+	StartRecordingVMix(ctx context.Context, arguments VmixRecordingArguments) (*mcp_golang.ToolResponse, error)
+	StopRecordingVMix(ctx context.Context, arguments VmixStopRecordingArguments) (*mcp_golang.ToolResponse, error)
 
 	// streaming functions
-	StartStreamingVMix(arguments VmixStreamingArguments) (*mcp_golang.ToolResponse, error)
-	StopStreamingVMix(arguments VmixStreamingArguments) (*mcp_golang.ToolResponse, error)
+	StartStreamingVMix(ctx context.Context, arguments VmixStreamingArguments) (*mcp_golang.ToolResponse, error)
+	StopStreamingVMix(ctx context.Context, arguments VmixStopStreamingArguments) (*mcp_golang.ToolResponse, error)
 
 	// external output functions
-	StartExternalVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
-	StopExternalVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
+	StartExternalVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
+	StopExternalVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
 
 	// multicorder functions
-	StartMulticorderVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
-	StopMulticorderVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
+	StartMulticorderVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
+	StopMulticorderVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
 
 	// playlist functions
-	StartPlaylistVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
-	StopPlaylistVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
+	StartPlaylistVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
+	StopPlaylistVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
 
 	// fullscreen function
-	FullscreenVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
+	FullscreenVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error)
 
 	// Snapshot functions
-	SnapShotVMix(arguments GetCurrentScreenshotArguments) (*mcp_golang.ToolResponse, error)
-	SnapShotInputVMix(arguments GetCurrentScreenshotInputArguments) (*mcp_golang.ToolResponse, error)
+	SnapShotVMix(ctx context.Context, arguments GetCurrentScreenshotArguments) (*mcp_golang.ToolResponse, error)
+	SnapShotInputVMix(ctx context.Context, arguments GetCurrentScreenshotInputArguments) (*mcp_golang.ToolResponse, error)
 
 	// support functions
-	GetShortcutURL(arguments GetShortcutURLArguments) (*mcp_golang.ToolResponse, error)
-	AddBlank(arguments AddBlankArguments) (*mcp_golang.ToolResponse, error)
-	CheckScreenshot(arguments CheckScreenshotArguments) (*mcp_golang.ToolResponse, error)
-	CheckScreenshotInput(arguments CheckScreenshotInputArguments) (*mcp_golang.ToolResponse, error)
-	MakeScene(arguments MakeSceneArguments) (*mcp_golang.ToolResponse, error)
-	AdjustLayers(arguments AdjustLayersArguments) (*mcp_golang.ToolResponse, error)
+	GetShortcutURL(ctx context.Context, arguments GetShortcutURLArguments) (*mcp_golang.ToolResponse, error)
+	AddBlank(ctx context.Context, arguments AddBlankArguments) (*mcp_golang.ToolResponse, error)
+	CheckScreenshot(ctx context.Context, arguments CheckScreenshotArguments) (*mcp_golang.ToolResponse, error)
+	CheckScreenshotInput(ctx context.Context, arguments CheckScreenshotInputArguments) (*mcp_golang.ToolResponse, error)
+	MakeScene(ctx context.Context, arguments MakeSceneArguments) (*mcp_golang.ToolResponse, error)
+	AdjustLayers(ctx context.Context, arguments AdjustLayersArguments) (*mcp_golang.ToolResponse, error)
+	VmixFunction(ctx context.Context, arguments VmixFunctionArguments) (*mcp_golang.ToolResponse, error)
+	SearchFunctions(ctx context.Context, arguments SearchFunctionsArguments) (*mcp_golang.ToolResponse, error)
 }
 
 type mcpVmix struct {
-	logger logger.Logger
-	srv    *mcp_golang.Server
+	logger    logger.Logger
+	srv       *mcp_golang.Server
+	pool      *clientPool
+	instances *instanceRegistry
+	events    *eventHub
+
+	screenshotDir string
+
+	// confirmDestructive makes destructive tools return a confirmation token first.
+	confirmDestructive bool
+	confirmations      *confirmationStore
+
+	// audioMonitors watch the audio meters of each instance by name. nil unless the monitor is enabled.
+	audioMonitors map[string]*audioMonitor
+}
+
+// log returns the logger of the tool call running in ctx, which adds the tool name to every message.
+func (m *mcpVmix) log(ctx context.Context) logger.Logger {
+	return logger.FromContext(ctx, m.logger)
+}
+
+// resolveTarget resolves the vMix instance for the tool arguments of the client calling in ctx.
+func (m *mcpVmix) resolveTarget(ctx context.Context, arguments BaseVMixArguments) (vmixTarget, error) {
+	target, err := m.instances.resolve(clientSession(ctx), arguments)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to resolve vMix instance: %v", err)
+		// 解決できなかったので要求されたインスタンスを記録する
+		m.log(ctx).Error(errMsg, "instance", arguments.Instance, "ip", arguments.IP, "port", arguments.Port, "transport", arguments.Transport)
+		return vmixTarget{}, fmt.Errorf(errMsg)
+	}
+	return target, nil
+}
+
+// sendFunction sends a shortcut function to the target.
+// The TCP API is used when it is enabled for the instance, otherwise the pooled HTTP client is used.
+// The function is added to the audit record of the tool call running in ctx.
+func (m *mcpVmix) sendFunction(ctx context.Context, target vmixTarget, function string, params map[string]string) error {
+	fc := audit.FunctionCall{
+		Time:     time.Now(),
+		Function: function,
+		Query:    params,
+		Instance: target.Name,
+		Host:     target.Host,
+		Port:     target.Port,
+	}
+	err := m.doSendFunction(ctx, target, function, params, &fc.Transport)
+	duration := time.Since(fc.Time)
+	fc.LatencyMs = float64(duration.Microseconds()) / 1000
+	log := m.log(ctx).With("instance", target, "function", function, "input", params["Input"], "transport", fc.Transport, "duration", duration)
+	if err != nil {
+		fc.Error = err.Error()
+		log.Debug("Failed to send vMix function", "error", err)
+	} else {
+		log.Debug("Sent vMix function")
+	}
+	audit.RecordFunction(ctx, fc)
+	return err
+}
+
+func (m *mcpVmix) doSendFunction(ctx context.Context, target vmixTarget, function string, params map[string]string, transport *string) error {
+	if target.TCPPort != 0 {
+		client, err := m.events.client(target)
+		if err == nil {
+			*transport = "tcp"
+			ctx, cancel := context.WithTimeout(ctx, tcpRequestTimeout)
+			defer cancel()
+			return client.Function(ctx, function, params)
+		}
+		if target.TCPOnly {
+			return err
+		}
+		m.log(ctx).Warn("Failed to use vMix TCP API, falling back to HTTP API", "instance", target, "error", err)
+	}
+
+	*transport = "http"
+	ctx, cancel := context.WithTimeout(ctx, stateFetchTimeout)
+	defer cancel()
+	err := m.pool.get(target).sendFunction(ctx, function, params)
+	// vMix rejecting the function does not mean the connection is broken
+	var statusErr *apiStatusError
+	if err != nil && !errors.As(err, &statusErr) {
+		m.pool.evict(target)
+	}
+	return err
 }
 
 // FetchVMix implements MCPvMix.
-func (m *mcpVmix) FetchVMix(arguments ConnectVmixArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to connect to vMix instance at %s:%d", arguments.IP, arguments.Port))
+func (m *mcpVmix) FetchVMix(ctx context.Context, arguments ConnectVmixArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	m.log(ctx).Info("Attempting to connect to vMix instance", "instance", target)
 
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+	vmix, err := m.pool.refresh(target)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	m.log(ctx).Info("Successfully connected to vMix instance", "instance", target)
+
+	filter := inputFilter{types: arguments.Types, name: arguments.Name, onAir: arguments.OnAir}
+	filtered, err := vmix.filterInputs(filter)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to filter inputs: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	switch arguments.Format {
+	case "", "text":
+	case "json":
+		return m.fetchJSON(ctx, target, vmix, filtered)
+	default:
+		errMsg := fmt.Sprintf("Unknown format %q. Use 'text' or 'json'", arguments.Format)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
-	m.logger.Info(fmt.Sprintf("Successfully connected to vMix instance at %s:%d", arguments.IP, arguments.Port))
 
-	inputs := lo.Map(vmix.Inputs.Input, func(input models.Input, _ int) *mcp_golang.Content {
-		overlays := lo.Map(input.Overlay, func(overlay models.InputOverlay, _ int) string {
-			return fmt.Sprintf("Overlay: %d: Text: %s Key: %s Positions:%v", overlay.Index, overlay.Text, overlay.Key, overlay.Position)
+	inputs := lo.Map(filtered, func(input stateInput, _ int) *mcp_golang.Content {
+		overlays := lo.Map(input.Overlays, func(overlay stateInputOverlay, _ int) string {
+			return fmt.Sprintf("Overlay: %d: Key: %s Positions:%v", overlay.Index, overlay.Key, lo.FromPtr(overlay.Position))
 		})
 		overlaysStr := strings.Join(overlays, "\n")
 		return mcp_golang.NewTextContent(
-			fmt.Sprintf("Input: %d: Key:%s, Name: %s. State: %s, Position: %d, Duration: %d, Loop: %t Overlays:%v", input.Number, input.Key, input.Name, input.State, input.Position, input.Duration, input.Loop, overlaysStr),
+			fmt.Sprintf("Input: %d: Key:%s, Name: %s. State: %s, Position: %d, Duration: %d, Loop: %t Overlays:%v", input.Number, input.Key, strings.TrimSpace(input.Name), input.State, input.Position, input.Duration, input.Loop, overlaysStr),
 		)
 	})
 
 	allContents := append([]*mcp_golang.Content{
-		mcp_golang.NewTextContent(fmt.Sprintf("Connected to vMix instance %s:%d", arguments.IP, arguments.Port)),
+		mcp_golang.NewTextContent(fmt.Sprintf("Connected to vMix instance %s", target)),
 		mcp_golang.NewTextContent(fmt.Sprintf("vMix version is %s, Edition is %s.", vmix.Version, vmix.Edition)),
 		mcp_golang.NewTextContent(fmt.Sprintf("vMix is running on %s.", vmix.Preset)),
+		mcp_golang.NewTextContent(strings.Join(lo.Map(vmix.mixStates(), func(mix stateMix, _ int) string { return vmix.describeMix(mix) }), "\n")),
 	}, inputs...)
+	if !filter.empty() {
+		allContents = append(allContents, mcp_golang.NewTextContent(fmt.Sprintf("%d of %d inputs matched the filter.", len(filtered), len(vmix.Inputs))))
+	}
 
-	m.logger.Info(fmt.Sprintf("Successfully fetched vMix information: Version=%s, Edition=%s, Preset=%s", vmix.Version, vmix.Edition, vmix.Preset))
+	m.log(ctx).Info("Successfully fetched vMix information", "instance", target, "version", vmix.Version, "edition", vmix.Edition, "preset", vmix.Preset)
 	return mcp_golang.NewToolResponse(allContents...), nil
 }
 
-// CutVMix implements MCPvMix.
-func (m *mcpVmix) CutVMix(arguments VmixCutArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to cut to input %s on vMix instance at %s:%d", arguments.Input, arguments.IP, arguments.Port))
+// fetchResult is the JSON output of vmix_fetch. It is the vMix state with the filtered inputs.
+type fetchResult struct {
+	Instance    string `json:"instance"`
+	TotalInputs int    `json:"totalInputs"`
+	vmixState
+}
 
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
-	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+func (m *mcpVmix) fetchJSON(ctx context.Context, target vmixTarget, vmix *vmixState, inputs []stateInput) (*mcp_golang.ToolResponse, error) {
+	result := fetchResult{
+		Instance:    target.String(),
+		TotalInputs: len(vmix.Inputs),
+		vmixState:   *vmix,
 	}
+	result.Inputs = inputs
 
-	if err := vmix.Cut(arguments.Input); err != nil {
-		errMsg := fmt.Sprintf("Failed to perform cut operation: %v", err)
-		m.logger.Error(errMsg)
+	b, err := json.Marshal(result)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to marshal vMix state: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info(fmt.Sprintf("Successfully cut to input %s", arguments.Input))
-	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Cut to input %s", arguments.Input))), nil
+	m.log(ctx).Info("Successfully fetched vMix information as JSON", "instance", target, "inputs", len(inputs), "total", len(vmix.Inputs))
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(b))), nil
 }
 
-// FadeVMix implements MCPvMix.
-func (m *mcpVmix) FadeVMix(arguments VmixFadeArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to fade to input %s with duration %d on vMix instance at %s:%d", arguments.Input, arguments.Duration, arguments.IP, arguments.Port))
+// ForgetSession implements MCPvMix.
+func (m *mcpVmix) ForgetSession(id string) {
+	m.instances.forgetSession(id)
+}
+
+// ListInstances implements MCPvMix.
+func (m *mcpVmix) ListInstances(ctx context.Context, arguments ListInstancesArguments) (*mcp_golang.ToolResponse, error) {
+	selected := m.instances.selectedName(clientSession(ctx))
+	contents := lo.Map(m.instances.list(), func(in config.Instance, _ int) *mcp_golang.Content {
+		return mcp_golang.NewTextContent(fmt.Sprintf("Instance: %s, Host: %s, Port: %d, Selected: %t", in.Name, in.Host, in.Port, in.Name == selected))
+	})
+	return mcp_golang.NewToolResponse(contents...), nil
+}
+
+// SelectInstance implements MCPvMix.
+func (m *mcpVmix) SelectInstance(ctx context.Context, arguments SelectInstanceArguments) (*mcp_golang.ToolResponse, error) {
+	m.log(ctx).Info("Attempting to select vMix instance", "instance", arguments.Name)
 
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+	in, err := m.instances.selectInstance(clientSession(ctx), arguments.Name)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
+		errMsg := fmt.Sprintf("Failed to select vMix instance: %v", err)
+		m.log(ctx).Error(errMsg, "instance", arguments.Name)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	if err := vmix.Fade(arguments.Input, uint(arguments.Duration)); err != nil {
-		errMsg := fmt.Sprintf("Failed to perform fade operation: %v", err)
-		m.logger.Error(errMsg)
+	m.log(ctx).Info("Successfully selected vMix instance", "instance", in.Name, "host", in.Host, "port", in.Port)
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Selected vMix instance %s at %s:%d", in.Name, in.Host, in.Port))), nil
+}
+
+// EventsVMix implements MCPvMix.
+func (m *mcpVmix) EventsVMix(ctx context.Context, arguments VmixEventsArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	m.log(ctx).Info("Attempting to read events of vMix instance", "instance", target)
+
+	if _, err := m.events.client(target); err != nil {
+		errMsg := fmt.Sprintf("Failed to connect to vMix TCP API: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info(fmt.Sprintf("Successfully faded to input %s with duration %d", arguments.Input, arguments.Duration))
-	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Fade to input %s for Duration %d", arguments.Input, arguments.Duration))), nil
+	events, tally := m.events.recent(target, arguments.Limit)
+	tallies := lo.Map(tally, func(t vmixtcp.TallyStatus, i int) string {
+		return fmt.Sprintf("%d:%s", i+1, t)
+	})
+	contents := []*mcp_golang.Content{
+		mcp_golang.NewTextContent(fmt.Sprintf("Tally: %s", strings.Join(tallies, ", "))),
+	}
+	for _, ev := range events {
+		switch ev.Type {
+		case tcpapi.EventTally:
+			continue
+		default:
+			contents = append(contents, mcp_golang.NewTextContent(fmt.Sprintf("%s Activator: %s, Input: %d, Value: %s", ev.Time.Format(time.RFC3339Nano), ev.Name, ev.Input, ev.Value)))
+		}
+	}
+
+	return mcp_golang.NewToolResponse(contents...), nil
 }
 
-// FadeToBlackVMix implements MCPvMix.
-func (m *mcpVmix) FadeToBlackVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to fade to black on vMix instance at %s:%d", arguments.IP, arguments.Port))
+// CutVMix implements MCPvMix.
+func (m *mcpVmix) CutVMix(ctx context.Context, arguments VmixCutArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	action := fmt.Sprintf("cut to input %s", arguments.Input)
+	if err := m.sendMixFunction(ctx, target, action, "Cut", map[string]string{"Input": arguments.Input}, arguments.mix()); err != nil {
+		return nil, err
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Cut to input %s on %s", arguments.Input, mixName(arguments.mix())))), nil
+}
 
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+// FadeVMix implements MCPvMix.
+func (m *mcpVmix) FadeVMix(ctx context.Context, arguments VmixFadeArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
-	if err := vmix.FadeToBlack(); err != nil {
+	action := fmt.Sprintf("fade to input %s with duration %d", arguments.Input, arguments.Duration)
+	params := map[string]string{"Input": arguments.Input, "Duration": strconv.Itoa(arguments.Duration)}
+	if err := m.sendMixFunction(ctx, target, action, "Fade", params, arguments.mix()); err != nil {
+		return nil, err
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Fade to input %s for Duration %d on %s", arguments.Input, arguments.Duration, mixName(arguments.mix())))), nil
+}
+
+// FadeToBlackVMix implements MCPvMix.
+func (m *mcpVmix) FadeToBlackVMix(ctx context.Context, arguments VmixFadeToBlackArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	if res, err := m.confirm(ctx, fadeToBlackConfirmation, arguments.ConfirmationToken, target); res != nil || err != nil {
+		return res, err
+	}
+
+	m.log(ctx).Info("Attempting to fade to black", "instance", target)
+
+	if err := m.sendFunction(ctx, target, "FadeToBlack", nil); err != nil {
 		errMsg := fmt.Sprintf("Failed to perform fade to black: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully performed fade to black")
+	m.log(ctx).Info("Successfully performed fade to black", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Performed Fade To Black")), nil
 }
 
 // StartRecordingVMix implements MCPvMix.
-func (m *mcpVmix) StartRecordingVMix(arguments VmixRecordingArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to start recording on vMix instance at %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) StartRecordingVMix(ctx context.Context, arguments VmixRecordingArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
-	if err := vmix.StartRecording(); err != nil {
+	m.log(ctx).Info("Attempting to start recording", "instance", target)
+
+	if err := m.sendFunction(ctx, target, "StartRecording", nil); err != nil {
 		errMsg := fmt.Sprintf("Failed to start recording: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully started recording")
+	m.log(ctx).Info("Successfully started recording", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Started recording")), nil
 }
 
 // StopRecordingVMix implements MCPvMix.
-func (m *mcpVmix) StopRecordingVMix(arguments VmixRecordingArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to stop recording on vMix instance at %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) StopRecordingVMix(ctx context.Context, arguments VmixStopRecordingArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
-	if err := vmix.StopRecording(); err != nil {
+	if res, err := m.confirm(ctx, stopRecordingConfirmation, arguments.ConfirmationToken, target); res != nil || err != nil {
+		return res, err
+	}
+
+	m.log(ctx).Info("Attempting to stop recording", "instance", target)
+
+	if err := m.sendFunction(ctx, target, "StopRecording", nil); err != nil {
 		errMsg := fmt.Sprintf("Failed to stop recording: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully stopped recording")
+	m.log(ctx).Info("Successfully stopped recording", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Stopped recording")), nil
 }
 
 // StartStreamingVMix implements MCPvMix.
-func (m *mcpVmix) StartStreamingVMix(arguments VmixStreamingArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to start streaming on vMix instance at %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) StartStreamingVMix(ctx context.Context, arguments VmixStreamingArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
-	if err := vmix.StartStreaming(uint(arguments.StreamNumber)); err != nil {
+	m.log(ctx).Info("Attempting to start streaming", "instance", target)
+
+	if err := m.sendFunction(ctx, target, "StartStreaming", map[string]string{"Value": strconv.Itoa(arguments.StreamNumber)}); err != nil {
 		errMsg := fmt.Sprintf("Failed to start streaming: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully started streaming")
+	m.log(ctx).Info("Successfully started streaming", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Started streaming")), nil
 }
 
 // StopStreamingVMix implements MCPvMix.
-func (m *mcpVmix) StopStreamingVMix(arguments VmixStreamingArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to stop streaming on vMix instance at %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) StopStreamingVMix(ctx context.Context, arguments VmixStopStreamingArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
+	}
+
+	liveFor := func(channel int) string { return m.pool.streams.liveFor(poolKey(target), channel) }
+	if res, err := m.confirm(ctx, stopStreamingConfirmation(arguments.StreamNumber, liveFor), arguments.ConfirmationToken, target); res != nil || err != nil {
+		return res, err
 	}
 
-	if err := vmix.StopStreaming(uint(arguments.StreamNumber)); err != nil {
+	m.log(ctx).Info("Attempting to stop streaming", "instance", target)
+
+	if err := m.sendFunction(ctx, target, "StopStreaming", map[string]string{"Value": strconv.Itoa(arguments.StreamNumber)}); err != nil {
 		errMsg := fmt.Sprintf("Failed to stop streaming: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully stopped streaming")
+	m.log(ctx).Info("Successfully stopped streaming", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Stopped streaming")), nil
 }
 
 // StartExternalVMix implements MCPvMix.
-func (m *mcpVmix) StartExternalVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to start external output on vMix instance at %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) StartExternalVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
-	if err := vmix.StartExternal(); err != nil {
+	m.log(ctx).Info("Attempting to start external output", "instance", target)
+
+	if err := m.sendFunction(ctx, target, "StartExternal", nil); err != nil {
 		errMsg := fmt.Sprintf("Failed to start external output: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully started external output")
+	m.log(ctx).Info("Successfully started external output", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Started external output")), nil
 }
 
 // StopExternalVMix implements MCPvMix.
-func (m *mcpVmix) StopExternalVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to stop external output on vMix instance at %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) StopExternalVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
-	if err := vmix.StopExternal(); err != nil {
+	m.log(ctx).Info("Attempting to stop external output", "instance", target)
+
+	if err := m.sendFunction(ctx, target, "StopExternal", nil); err != nil {
 		errMsg := fmt.Sprintf("Failed to stop external output: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully stopped external output")
+	m.log(ctx).Info("Successfully stopped external output", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Stopped external output")), nil
 }
 
 // StartMulticorderVMix implements MCPvMix.
-func (m *mcpVmix) StartMulticorderVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to start MultiCorder on vMix instance at %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) StartMulticorderVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
-	if err := vmix.StartMultiCorder(); err != nil {
+	m.log(ctx).Info("Attempting to start MultiCorder", "instance", target)
+
+	if err := m.sendFunction(ctx, target, "StartMultiCorder", nil); err != nil {
 		errMsg := fmt.Sprintf("Failed to start MultiCorder: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully started MultiCorder")
+	m.log(ctx).Info("Successfully started MultiCorder", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Started MultiCorder")), nil
 }
 
 // StopMulticorderVMix implements MCPvMix.
-func (m *mcpVmix) StopMulticorderVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to stop MultiCorder on vMix instance at %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) StopMulticorderVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
-	if err := vmix.StopMultiCorder(); err != nil {
+	m.log(ctx).Info("Attempting to stop MultiCorder", "instance", target)
+
+	if err := m.sendFunction(ctx, target, "StopMultiCorder", nil); err != nil {
 		errMsg := fmt.Sprintf("Failed to stop MultiCorder: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully stopped MultiCorder")
+	m.log(ctx).Info("Successfully stopped MultiCorder", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Stopped MultiCorder")), nil
 }
 
 // StartPlaylistVMix implements MCPvMix.
-func (m *mcpVmix) StartPlaylistVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to start playlist on vMix instance at %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) StartPlaylistVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
-	if err := vmix.SendFunction("StartPlayList", nil); err != nil {
+	m.log(ctx).Info("Attempting to start playlist", "instance", target)
+
+	if err := m.sendFunction(ctx, target, "StartPlayList", nil); err != nil {
 		errMsg := fmt.Sprintf("Failed to start playlist: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully started playlist")
+	m.log(ctx).Info("Successfully started playlist", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Started playlist")), nil
 }
 
 // StopPlaylistVMix implements MCPvMix.
-func (m *mcpVmix) StopPlaylistVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to stop playlist on vMix instance at %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) StopPlaylistVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
-	if err := vmix.SendFunction("StopPlayList", nil); err != nil {
+	m.log(ctx).Info("Attempting to stop playlist", "instance", target)
+
+	if err := m.sendFunction(ctx, target, "StopPlayList", nil); err != nil {
 		errMsg := fmt.Sprintf("Failed to stop playlist: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully stopped playlist")
+	m.log(ctx).Info("Successfully stopped playlist", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Stopped playlist")), nil
 }
 
 // FullscreenVMix implements MCPvMix.
-func (m *mcpVmix) FullscreenVMix(arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to toggle fullscreen on vMix instance at %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) FullscreenVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
-	if err := vmix.Fullscreen(); err != nil {
+	m.log(ctx).Info("Attempting to toggle fullscreen", "instance", target)
+
+	if err := m.sendFunction(ctx, target, "Fullscreen", nil); err != nil {
 		errMsg := fmt.Sprintf("Failed to toggle fullscreen: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully toggled fullscreen")
+	m.log(ctx).Info("Successfully toggled fullscreen", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Toggled fullscreen")), nil
 }
 
 // GetShortcutURL implements MCPvMix.
-func (m *mcpVmix) GetShortcutURL(arguments GetShortcutURLArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("vMixインスタンス %s:%d のショートカットURLを生成します。関数: %s", arguments.IP, arguments.Port, arguments.Function))
+func (m *mcpVmix) GetShortcutURL(ctx context.Context, arguments GetShortcutURLArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	m.log(ctx).Info("Attempting to build shortcut URL", "instance", target, "function", arguments.Function)
+
+	// 関数名とパラメータをカタログで検証する
+	catalogue, err := functions.Default()
+	if err != nil {
+		m.log(ctx).Error(err.Error(), "instance", target)
+		return nil, err
+	}
+	var function functions.Function
+	call, err := functions.ParseQuery(arguments.Function, arguments.Queries)
+	if err == nil {
+		function, err = catalogue.Validate(call)
+	}
+	// カタログにない関数は警告してURLを返す
+	var warning string
+	if errors.Is(err, functions.ErrUnknownFunction) {
+		warning = fmt.Sprintf("Warning: %v (it is not in the function catalogue, so only its parameters were checked)", err)
+		m.log(ctx).Warn("Building a shortcut URL of a vMix function which is not in the catalogue", "instance", target, "function", function.Name)
+		err = nil
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("Invalid vMix function call (look it up with vmix_search_functions): %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
 
 	// URLを構築
 	u := &url.URL{
 		Scheme: "http",
-		Host:   fmt.Sprintf("%s:%d", arguments.IP, arguments.Port),
+		Host:   fmt.Sprintf("%s:%d", target.Host, target.Port),
 		Path:   "/api",
 	}
 
 	// クエリパラメータを設定
 	q := u.Query()
-	q.Set("Function", arguments.Function)
-	for key, value := range arguments.Queries {
+	q.Set("Function", function.Name)
+	// Mix はAPIの0始まりに変換された値を使う
+	for key, value := range function.Query(call) {
 		q.Set(key, value)
 	}
 	u.RawQuery = q.Encode()
 
 	shortcutURL := u.String()
 
-	m.logger.Info(fmt.Sprintf("ショートカットURLを生成しました: %s", shortcutURL))
+	m.log(ctx).Info("Successfully built shortcut URL", "instance", target, "url", shortcutURL)
+	if warning != "" {
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(shortcutURL), mcp_golang.NewTextContent(warning)), nil
+	}
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(shortcutURL)), nil
 }
 
-func (m *mcpVmix) addBlank(client *vmixhttp.Client, isTransparent bool) error {
-	value := "Black"
-	if isTransparent {
-		value = "Transparent"
-	}
-
-	if err := client.AddInput("Colour", value); err != nil {
-		errMsg := fmt.Sprintf("Failed to add blank: %v", err)
-		m.logger.Error(errMsg)
-		return fmt.Errorf(errMsg)
-	}
-
-	return nil
-}
+// maxBlankInputs is the most blank inputs vmix_add_blank adds in one call.
+const maxBlankInputs = 50
 
 // AddBlank implements MCPvMix.
-func (m *mcpVmix) AddBlank(arguments AddBlankArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to add %d blank inputs on vMix instance at %s:%d", arguments.Numbers, arguments.IP, arguments.Port))
+// Inputs are added one by one, so each new input is found by comparing the inputs and they keep the order of names.
+func (m *mcpVmix) AddBlank(ctx context.Context, arguments AddBlankArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
 
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+	switch {
+	case arguments.Numbers < 1 || arguments.Numbers > maxBlankInputs:
+		err = fmt.Errorf("numbers must be 1~%d: %d", maxBlankInputs, arguments.Numbers)
+	case len(arguments.Names) != 0 && len(arguments.Names) != arguments.Numbers:
+		err = fmt.Errorf("%d names were given for %d inputs", len(arguments.Names), arguments.Numbers)
+	}
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
+		errMsg := fmt.Sprintf("Failed to add blank inputs, nothing was sent to vMix: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	var wg sync.WaitGroup
-	for range arguments.Numbers {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if err := m.addBlank(vmix, arguments.IsTransparent); err != nil {
-				errMsg := fmt.Sprintf("Failed to add blank input: %v", err)
-				m.logger.Error(errMsg)
+	m.log(ctx).Info("Attempting to add blank inputs", "instance", target, "count", arguments.Numbers)
+
+	value := "Colour|" + lo.Ternary(arguments.IsTransparent, "Transparent", "Black")
+	var added []string
+	for i := range arguments.Numbers {
+		name := ""
+		if len(arguments.Names) != 0 {
+			name = arguments.Names[i]
+		}
+		in, err := m.addInput(ctx, target, fmt.Sprintf("add blank input %d of %d", i+1, arguments.Numbers), functions.Call{Function: "AddInput", Value: value}, name)
+		if in.Key != "" {
+			added = append(added, describeInput(in))
+		}
+		if err != nil {
+			errMsg := fmt.Sprintf("Failed to add blank input %d of %d: %v. %d inputs were added", i+1, arguments.Numbers, err, len(added))
+			if len(added) > 0 {
+				errMsg += ":\n" + strings.Join(added, "\n")
 			}
-		}()
+			m.log(ctx).Error(errMsg, "instance", target)
+			return nil, fmt.Errorf(errMsg)
+		}
 	}
-	wg.Wait()
 
-	m.logger.Info("Successfully added blank inputs")
-	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Added blank inputs")), nil
+	m.log(ctx).Info("Successfully added blank inputs", "instance", target, "count", len(added))
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Added %d blank inputs:\n%s", len(added), strings.Join(added, "\n")))), nil
 }
 
 // SnapShotVMix implements MCPvMix.
-func (m *mcpVmix) SnapShotVMix(arguments GetCurrentScreenshotArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to get screenshot from vMix instance at %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) SnapShotVMix(ctx context.Context, arguments GetCurrentScreenshotArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
-	if err := vmix.Snapshot(arguments.SaveDir); err != nil {
+	m.log(ctx).Info("Attempting to get screenshot", "instance", target)
+
+	if err := m.sendFunction(ctx, target, "Snapshot", map[string]string{"Value": arguments.SaveDir}); err != nil {
 		errMsg := fmt.Sprintf("Failed to take screenshot: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully took screenshot")
+	m.log(ctx).Info("Successfully took screenshot", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Took screenshot")), nil
 }
 
 // SnapShotInputVMix implements MCPvMix.
-func (m *mcpVmix) SnapShotInputVMix(arguments GetCurrentScreenshotInputArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Attempting to get input screenshot from vMix instance at %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) SnapShotInputVMix(ctx context.Context, arguments GetCurrentScreenshotInputArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
-	if err := vmix.SnapshotInput(arguments.Input, arguments.SaveDir); err != nil {
+	m.log(ctx).Info("Attempting to get input screenshot", "instance", target, "input", arguments.Input)
+
+	if err := m.sendFunction(ctx, target, "SnapshotInput", map[string]string{"Input": arguments.Input, "Value": arguments.SaveDir}); err != nil {
 		errMsg := fmt.Sprintf("Failed to take input screenshot: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully took input screenshot")
+	m.log(ctx).Info("Successfully took input screenshot", "instance", target)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Took input screenshot")), nil
 }
 
 // CheckScreenshot implements MCPvMix.
-func (m *mcpVmix) CheckScreenshot(arguments CheckScreenshotArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("スクリーンショットを確認します。vMixインスタンス %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) CheckScreenshot(ctx context.Context, arguments CheckScreenshotArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
+	m.log(ctx).Info("Attempting to check screenshot", "instance", target)
+
 	// 一時ディレクトリにスクリーンショットを保存
 	now := time.Now().Format("20060102_150405.jpg")
-	tmpDir := os.TempDir()
-	filePath := path.Join(tmpDir, now)
-	if err := vmix.Snapshot(filePath); err != nil {
+	filePath := path.Join(m.screenshotDir, now)
+	if err := m.sendFunction(ctx, target, "Snapshot", map[string]string{"Value": filePath}); err != nil {
 		errMsg := fmt.Sprintf("Failed to take screenshot: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully checked screenshot")
+	m.log(ctx).Info("Successfully checked screenshot", "instance", target)
 	time.Sleep(5 * time.Second) // wait until screen shot is saved
 
 	// 保存したスクリーンショットを取得してBase64にエンコード
 	snapShotFileBase64, err := retryReadScreenshot(filePath, 30, 200*time.Millisecond)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to read screenshot: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
@@ -548,33 +835,30 @@ func (m *mcpVmix) CheckScreenshot(arguments CheckScreenshotArguments) (*mcp_gola
 }
 
 // CheckScreenshotInput implements MCPvMix.
-func (m *mcpVmix) CheckScreenshotInput(arguments CheckScreenshotInputArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("Checking screenshot for input %s on vMix instance at %s:%d", arguments.Input, arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) CheckScreenshotInput(ctx context.Context, arguments CheckScreenshotInputArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
+	m.log(ctx).Info("Attempting to check input screenshot", "instance", target, "input", arguments.Input)
+
 	// 一時ディレクトリにスクリーンショットを保存
 	now := time.Now().Format("20060102_150405.jpg")
-	tmpDir := os.TempDir()
-	filePath := path.Join(tmpDir, now)
-	if err := vmix.SnapshotInput(arguments.Input, filePath); err != nil {
+	filePath := path.Join(m.screenshotDir, now)
+	if err := m.sendFunction(ctx, target, "SnapshotInput", map[string]string{"Input": arguments.Input, "Value": filePath}); err != nil {
 		errMsg := fmt.Sprintf("Failed to take input screenshot: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info("Successfully checked input screenshot")
+	m.log(ctx).Info("Successfully checked input screenshot", "instance", target)
 	time.Sleep(5 * time.Second) // wait until screen shot is saved
 	// 保存したスクリーンショットを取得してBase64にエンコード
 	snapShotFileBase64, err := retryReadScreenshot(filePath, 30, 200*time.Millisecond)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to read screenshot: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
@@ -620,41 +904,39 @@ func retryReadScreenshot(filePath string, maxRetries int, retryInterval time.Dur
 }
 
 // MakeScene implements MCPvMix.
-func (m *mcpVmix) MakeScene(arguments MakeSceneArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("シーン %s を作成します。vMixインスタンス %s:%d", arguments.Input, arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) MakeScene(ctx context.Context, arguments MakeSceneArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("vMixインスタンスへの接続に失敗しました: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
+	m.log(ctx).Info("Attempting to create scene", "instance", target, "input", arguments.Input)
+
 	// 各レイヤーを設定
 	eg := errgroup.Group{}
 	for index, layer := range arguments.Layers {
 		eg.Go(func() error {
-			if err := vmix.SetLayer(arguments.Input, uint8(index+1), layer.Input); err != nil {
+			if err := m.setLayer(ctx, target, arguments.Input, index+1, layer.Input); err != nil {
 				errMsg := fmt.Sprintf("failed to set input layer for index:%d input: %s: error: %v", index+1, layer.Input, err)
-				m.logger.Error(errMsg)
+				m.log(ctx).Error(errMsg, "instance", target)
 				return xerrors.Errorf(errMsg)
 			}
 
-			if err := vmix.SetLayerPanX(arguments.Input, uint8(index+1), layer.PanX); err != nil {
+			if err := m.setLayerPanX(ctx, target, arguments.Input, index+1, layer.PanX); err != nil {
 				errMsg := fmt.Sprintf("failed to set input layer position for index:%d input: %s: error: %v", index+1, layer.Input, err)
-				m.logger.Error(errMsg)
+				m.log(ctx).Error(errMsg, "instance", target)
 				return xerrors.Errorf(errMsg)
 			}
 
-			if err := vmix.SetLayerPanY(arguments.Input, uint8(index+1), layer.PanY); err != nil {
+			if err := m.setLayerPanY(ctx, target, arguments.Input, index+1, layer.PanY); err != nil {
 				errMsg := fmt.Sprintf("failed to set input layer position for index:%d input: %s: error: %v", index+1, layer.Input, err)
-				m.logger.Error(errMsg)
+				m.log(ctx).Error(errMsg, "instance", target)
 				return xerrors.Errorf(errMsg)
 			}
 
-			if err := vmix.SetLayerZoom(arguments.Input, uint8(index+1), layer.Zoom); err != nil {
+			if err := m.setLayerZoom(ctx, target, arguments.Input, index+1, layer.Zoom); err != nil {
 				errMsg := fmt.Sprintf("failed to set input layer zoom for index:%d input: %s: error: %v", index+1, layer.Input, err)
-				m.logger.Error(errMsg)
+				m.log(ctx).Error(errMsg, "instance", target)
 				return xerrors.Errorf(errMsg)
 			}
 
@@ -664,70 +946,68 @@ func (m *mcpVmix) MakeScene(arguments MakeSceneArguments) (*mcp_golang.ToolRespo
 
 	if err := eg.Wait(); err != nil {
 		errMsg := fmt.Sprintf("failed to set input layer: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info(fmt.Sprintf("シーン %s の作成に成功しました", arguments.Input))
+	m.log(ctx).Info("Successfully created scene", "instance", target, "input", arguments.Input)
 
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("シーン %s を作成しました", arguments.Input))), nil
 }
 
 // AdjustLayers implements MCPvMix.
-func (m *mcpVmix) AdjustLayers(arguments AdjustLayersArguments) (*mcp_golang.ToolResponse, error) {
-	m.logger.Info(fmt.Sprintf("レイヤーを調整します。vMixインスタンス %s:%d", arguments.IP, arguments.Port))
-
-	vmix, err := vmixhttp.NewClient(arguments.IP, arguments.Port)
+func (m *mcpVmix) AdjustLayers(ctx context.Context, arguments AdjustLayersArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
 	if err != nil {
-		errMsg := fmt.Sprintf("vMixインスタンスへの接続に失敗しました: %v", err)
-		m.logger.Error(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, err
 	}
 
+	m.log(ctx).Info("Attempting to adjust layers", "instance", target, "input", arguments.Input)
+
 	// 各レイヤーを設定
 	eg := errgroup.Group{}
 	for index, layer := range arguments.Layers {
 		eg.Go(func() error {
 			eg.Go(func() error {
-				if err := vmix.SetLayer(arguments.Input, uint8(layer.Index), layer.Input); err != nil {
+				if err := m.setLayer(ctx, target, arguments.Input, layer.Index, layer.Input); err != nil {
 					errMsg := fmt.Sprintf("failed to set input layer for index:%d input: %s: error: %v", index+1, arguments.Input, err)
-					m.logger.Error(errMsg)
+					m.log(ctx).Error(errMsg, "instance", target)
 					return xerrors.Errorf(errMsg)
 				}
 				return nil
 			})
 
 			eg.Go(func() error {
-				if err := vmix.SetLayerPanX(arguments.Input, uint8(layer.Index), layer.PanX); err != nil {
+				if err := m.setLayerPanX(ctx, target, arguments.Input, layer.Index, layer.PanX); err != nil {
 					errMsg := fmt.Sprintf("failed to set input layer for index:%d input: %s: error: %v", index+1, arguments.Input, err)
-					m.logger.Error(errMsg)
+					m.log(ctx).Error(errMsg, "instance", target)
 					return xerrors.Errorf(errMsg)
 				}
 				return nil
 			})
 
 			eg.Go(func() error {
-				if err := vmix.SetLayerPanY(arguments.Input, uint8(layer.Index), layer.PanY); err != nil {
+				if err := m.setLayerPanY(ctx, target, arguments.Input, layer.Index, layer.PanY); err != nil {
 					errMsg := fmt.Sprintf("failed to set input layer position for index:%d input: %s: error: %v", index+1, arguments.Input, err)
-					m.logger.Error(errMsg)
+					m.log(ctx).Error(errMsg, "instance", target)
 					return xerrors.Errorf(errMsg)
 				}
 				return nil
 			})
 
 			eg.Go(func() error {
-				if err := vmix.SetLayerZoom(arguments.Input, uint8(layer.Index), layer.Zoom); err != nil {
+				if err := m.setLayerZoom(ctx, target, arguments.Input, layer.Index, layer.Zoom); err != nil {
 					errMsg := fmt.Sprintf("failed to set input layer zoom for index:%d input: %s: error: %v", index+1, arguments.Input, err)
-					m.logger.Error(errMsg)
+					m.log(ctx).Error(errMsg, "instance", target)
 					return xerrors.Errorf(errMsg)
 				}
 				return nil
 			})
 
 			eg.Go(func() error {
-				if err := vmix.SetLayerCrop(arguments.Input, uint8(layer.Index), layer.CropX1, layer.CropY1, layer.CropX2, layer.CropY2); err != nil {
+				if err := m.setLayerCrop(ctx, target, arguments.Input, layer.Index, layer.CropX1, layer.CropY1, layer.CropX2, layer.CropY2); err != nil {
 					errMsg := fmt.Sprintf("failed to set input layer crop for index:%d input: %s: error: %v", index+1, arguments.Input, err)
-					m.logger.Error(errMsg)
+					m.log(ctx).Error(errMsg, "instance", target)
 					return xerrors.Errorf(errMsg)
 				}
 				return nil
@@ -739,19 +1019,61 @@ func (m *mcpVmix) AdjustLayers(arguments AdjustLayersArguments) (*mcp_golang.Too
 
 	if err := eg.Wait(); err != nil {
 		errMsg := fmt.Sprintf("failed to set input layer: %v", err)
-		m.logger.Error(errMsg)
+		m.log(ctx).Error(errMsg, "instance", target)
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	m.logger.Info(fmt.Sprintf("レイヤーを調整しました"))
+	m.log(ctx).Info("Successfully adjusted layers", "instance", target, "input", arguments.Input)
 	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("レイヤーを調整しました")), nil
 }
 
-func NewMCPvMix(logger logger.Logger) MCPvMix {
+// NewMCPvMix creates the vMix tools. Background connections and monitors stop when ctx is done.
+func NewMCPvMix(ctx context.Context, logger logger.Logger, cfg *config.Config) MCPvMix {
 	srv := mcp_golang.NewServer(stdio.NewStdioServerTransport())
 
-	return &mcpVmix{
-		logger: logger,
-		srv:    srv,
+	instances := newInstanceRegistry(cfg)
+
+	screenshotDir := cfg.ScreenshotDir
+	if screenshotDir == "" {
+		screenshotDir = os.TempDir()
 	}
+
+	m := &mcpVmix{
+		logger:        logger,
+		srv:           srv,
+		pool:          newClientPool(defaultStateMaxAge, defaultIdleTTL),
+		instances:     instances,
+		events:        newEventHub(logger),
+		screenshotDir: screenshotDir,
+
+		confirmDestructive: cfg.Confirm,
+		confirmations:      newConfirmationStore(),
+	}
+
+	// TCP APIを使うインスタンスの状態はTCPのXMLで読む
+	m.pool.tcpClient = m.events.client
+
+	// 状態が変わったらキャッシュを破棄する
+	m.events.listen(func(ev vmixEvent) {
+		m.pool.markStale(ev.Target)
+	})
+
+	tcpTargets := lo.FilterMap(cfg.Instances, func(in config.Instance, _ int) (vmixTarget, bool) {
+		return instanceTarget(in), in.TCP
+	})
+	if len(tcpTargets) > 0 {
+		go m.events.watch(ctx, tcpTargets, 5*time.Second)
+	}
+
+	// 音声メーターの監視を開始する
+	if cfg.AudioMonitor.Enabled {
+		m.audioMonitors = map[string]*audioMonitor{}
+		for _, in := range cfg.Instances {
+			monitor := newAudioMonitor(m, instanceTarget(in), cfg.AudioMonitor)
+			m.audioMonitors[in.Name] = monitor
+			go monitor.run(ctx)
+		}
+	}
+
+	return m
 }
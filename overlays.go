@@ -0,0 +1,162 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// overlayChannels is how many overlay channels vMix has.
+const overlayChannels = 4
+
+// overlayFunctions maps the actions of vmix_overlay to the function suffix of OverlayInput{N}.
+var overlayFunctions = map[string]string{
+	"in":     "In",
+	"out":    "Out",
+	"off":    "Off",
+	"toggle": "",
+}
+
+func checkOverlayChannel(channel int) error {
+	if channel < 1 || channel > overlayChannels {
+		return fmt.Errorf("invalid overlay channel: %d. Use 1~%d", channel, overlayChannels)
+	}
+	return nil
+}
+
+// OverlayVMix implements MCPvMix.
+func (m *mcpVmix) OverlayVMix(ctx context.Context, arguments OverlayArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix, ok := overlayFunctions[arguments.Action]
+	if !ok {
+		err = fmt.Errorf("unknown overlay action: %s. Use in, out, off or toggle", arguments.Action)
+	} else {
+		err = checkOverlayChannel(arguments.Channel)
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to control overlay: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	params := map[string]string{}
+	// Out と Off はチャンネル全体に作用するため Input を送らない
+	if arguments.Input != "" && (arguments.Action == "in" || arguments.Action == "toggle") {
+		params["Input"] = arguments.Input
+	}
+	function := fmt.Sprintf("OverlayInput%d%s", arguments.Channel, suffix)
+
+	m.log(ctx).Info("Attempting to control overlay", "instance", target, "action", arguments.Action, "overlay", arguments.Channel)
+	if err := m.sendFunction(ctx, target, function, params); err != nil {
+		errMsg := fmt.Sprintf("Failed to %s overlay %d: %v", arguments.Action, arguments.Channel, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	m.log(ctx).Info("Successfully sent vMix function", "instance", target, "function", function)
+	return m.overlayStatusResponse(ctx, target, fmt.Sprintf("Sent %s", function))
+}
+
+// PreviewOverlayVMix implements MCPvMix.
+func (m *mcpVmix) PreviewOverlayVMix(ctx context.Context, arguments PreviewOverlayArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOverlayChannel(arguments.Channel); err != nil {
+		errMsg := fmt.Sprintf("Failed to toggle preview overlay: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	params := map[string]string{}
+	if arguments.Input != "" {
+		params["Input"] = arguments.Input
+	}
+	function := fmt.Sprintf("PreviewOverlayInput%d", arguments.Channel)
+
+	m.log(ctx).Info("Attempting to toggle preview overlay", "instance", target, "overlay", arguments.Channel)
+	if err := m.sendFunction(ctx, target, function, params); err != nil {
+		errMsg := fmt.Sprintf("Failed to toggle preview overlay %d: %v", arguments.Channel, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	m.log(ctx).Info("Successfully sent vMix function", "instance", target, "function", function)
+	return m.overlayStatusResponse(ctx, target, fmt.Sprintf("Sent %s", function))
+}
+
+// OverlayAllOffVMix implements MCPvMix.
+func (m *mcpVmix) OverlayAllOffVMix(ctx context.Context, arguments VmixBasicArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	m.log(ctx).Info("Attempting to turn all overlays off", "instance", target)
+	if err := m.sendFunction(ctx, target, "OverlayInputAllOff", nil); err != nil {
+		errMsg := fmt.Sprintf("Failed to turn all overlays off: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	m.log(ctx).Info("Successfully turned all overlays off", "instance", target)
+	return m.overlayStatusResponse(ctx, target, "Turned all overlays off")
+}
+
+// OverlayStatus implements MCPvMix.
+func (m *mcpVmix) OverlayStatus(ctx context.Context, arguments OverlayStatusArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+	return m.overlayStatusResponse(ctx, target, "")
+}
+
+// overlayStatusResponse reads the latest state and returns which input occupies each overlay channel.
+func (m *mcpVmix) overlayStatusResponse(ctx context.Context, target vmixTarget, header string) (*mcp_golang.ToolResponse, error) {
+	state, err := m.pool.refresh(target)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	var lines []string
+	if header != "" {
+		lines = append(lines, header)
+	}
+	lines = append(lines, state.describeOverlays()...)
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(strings.Join(lines, "\n"))), nil
+}
+
+// describeOverlays returns one line per overlay channel from the overlays element of the state.
+func (s *vmixState) describeOverlays() []string {
+	lines := make([]string, 0, overlayChannels)
+	for channel := 1; channel <= overlayChannels; channel++ {
+		line := fmt.Sprintf("Overlay %d: off", channel)
+		for _, overlay := range s.Overlays {
+			if overlay.Number != channel || strings.TrimSpace(overlay.Input) == "" {
+				continue
+			}
+			number, err := strconv.Atoi(strings.TrimSpace(overlay.Input))
+			if err != nil {
+				continue
+			}
+			where := "program"
+			if overlay.Preview {
+				where = "preview only"
+			}
+			line = fmt.Sprintf("Overlay %d: %s (%s)", channel, s.describeInputNumber(number), where)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
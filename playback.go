@@ -0,0 +1,208 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/samber/lo"
+
+	"github.com/FlowingSPDG/mcp-vmix/functions"
+)
+
+// defaultWarnSeconds is how close to the end an input on air is flagged by vmix_time_remaining by default.
+const defaultWarnSeconds = 10
+
+var playbackFunctions = map[string]string{
+	"play":      "Play",
+	"pause":     "Pause",
+	"playPause": "PlayPause",
+	"restart":   "Restart",
+}
+
+var markFunctions = map[string]string{
+	"in":       "MarkIn",
+	"out":      "MarkOut",
+	"reset":    "MarkReset",
+	"resetIn":  "MarkResetIn",
+	"resetOut": "MarkResetOut",
+}
+
+// end returns where playback of the input ends in milliseconds: the mark out point, or the duration.
+func (in stateInput) end() int {
+	if in.MarkOut != nil && *in.MarkOut > 0 && *in.MarkOut < in.Duration {
+		return *in.MarkOut
+	}
+	return in.Duration
+}
+
+// remaining returns how long the input plays until its end. Looping inputs do not end, but it is still the time until they loop.
+func (in stateInput) remaining() time.Duration {
+	return time.Duration(max(in.end()-in.Position, 0)) * time.Millisecond
+}
+
+// formatClock formats d as mm:ss or h:mm:ss.
+func formatClock(d time.Duration) string {
+	d = d.Round(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// describePlayback describes the playback state of an input, e.g. "Input 5 Intro (key ...): running 00:12 / 01:30, 01:18 remaining".
+func describePlayback(in stateInput) string {
+	line := fmt.Sprintf("Input %d %s (key %s): %s %s / %s, %s remaining", in.Number, strings.TrimSpace(in.Title), in.Key, strings.ToLower(in.State),
+		formatClock(time.Duration(in.Position)*time.Millisecond), formatClock(time.Duration(in.Duration)*time.Millisecond), formatClock(in.remaining()))
+	var marks []string
+	if in.MarkIn != nil && *in.MarkIn > 0 {
+		marks = append(marks, "in "+formatClock(time.Duration(*in.MarkIn)*time.Millisecond))
+	}
+	if in.MarkOut != nil && *in.MarkOut > 0 {
+		marks = append(marks, "out "+formatClock(time.Duration(*in.MarkOut)*time.Millisecond))
+	}
+	if len(marks) > 0 {
+		line += ", marks " + strings.Join(marks, " ")
+	}
+	if in.Loop {
+		line += ", loop"
+	}
+	return line
+}
+
+// sendPlaybackFunction sends a function to an existing input by its key and reports its playback state afterwards.
+// value returns the Value of the function for the input, or an error which rejects the call before anything is sent.
+func (m *mcpVmix) sendPlaybackFunction(ctx context.Context, base BaseVMixArguments, input, action, function string, value func(in stateInput) (string, error)) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+
+	_, in, err := m.existingInput(target, input)
+	call := functions.Call{Function: function}
+	if err == nil {
+		call.Input = in.Key
+		if value != nil {
+			call.Value, err = value(*in)
+		}
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to %s, nothing was sent to vMix: %v", action, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	if err := m.sendCatalogueFunction(ctx, target, action, call); err != nil {
+		return nil, err
+	}
+
+	after, err := m.waitInput(ctx, target, in.Key, nil)
+	if err != nil {
+		errMsg := fmt.Sprintf("Sent %s but failed to read the input afterwards: %v", function, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Sent %s\n%s", strings.TrimSpace(function+" "+call.Value), describePlayback(after)))), nil
+}
+
+// PlaybackVMix implements MCPvMix.
+func (m *mcpVmix) PlaybackVMix(ctx context.Context, arguments PlaybackArguments) (*mcp_golang.ToolResponse, error) {
+	function, ok := playbackFunctions[arguments.Action]
+	if !ok {
+		errMsg := fmt.Sprintf("Unknown playback action: %s. Use play, pause, playPause or restart", arguments.Action)
+		m.log(ctx).Error(errMsg)
+		return nil, fmt.Errorf(errMsg)
+	}
+	return m.sendPlaybackFunction(ctx, arguments.BaseVMixArguments, arguments.Input, fmt.Sprintf("%s input %s", arguments.Action, arguments.Input), function, nil)
+}
+
+// SeekVMix implements MCPvMix.
+func (m *mcpVmix) SeekVMix(ctx context.Context, arguments SeekArguments) (*mcp_golang.ToolResponse, error) {
+	if (arguments.PositionMs == nil) == (arguments.FromEndMs == nil) {
+		errMsg := "Give either positionMs or fromEndMs"
+		m.log(ctx).Error(errMsg)
+		return nil, fmt.Errorf(errMsg)
+	}
+	position := func(in stateInput) (string, error) {
+		if in.Duration <= 0 {
+			return "", fmt.Errorf("input %d %s has no duration to seek in", in.Number, strings.TrimSpace(in.Title))
+		}
+		ms := lo.FromPtr(arguments.PositionMs)
+		if arguments.FromEndMs != nil {
+			ms = in.end() - *arguments.FromEndMs
+		}
+		if ms < 0 || ms > in.Duration {
+			return "", fmt.Errorf("position %s is outside the input (%s long)", formatClock(time.Duration(ms)*time.Millisecond), formatClock(time.Duration(in.Duration)*time.Millisecond))
+		}
+		return strconv.Itoa(ms), nil
+	}
+	return m.sendPlaybackFunction(ctx, arguments.BaseVMixArguments, arguments.Input, fmt.Sprintf("seek input %s", arguments.Input), "SetPosition", position)
+}
+
+// PlaybackRateVMix implements MCPvMix.
+func (m *mcpVmix) PlaybackRateVMix(ctx context.Context, arguments PlaybackRateArguments) (*mcp_golang.ToolResponse, error) {
+	rate := func(stateInput) (string, error) { return strconv.FormatFloat(arguments.Rate, 'g', -1, 64), nil }
+	return m.sendPlaybackFunction(ctx, arguments.BaseVMixArguments, arguments.Input, fmt.Sprintf("set playback rate of input %s to %g", arguments.Input, arguments.Rate), "SetRate", rate)
+}
+
+// LoopVMix implements MCPvMix.
+func (m *mcpVmix) LoopVMix(ctx context.Context, arguments LoopArguments) (*mcp_golang.ToolResponse, error) {
+	return m.sendPlaybackFunction(ctx, arguments.BaseVMixArguments, arguments.Input, fmt.Sprintf("turn loop of input %s %s", arguments.Input, lo.Ternary(arguments.Loop, "on", "off")), onOff("Loop", arguments.Loop), nil)
+}
+
+// MarkVMix implements MCPvMix.
+func (m *mcpVmix) MarkVMix(ctx context.Context, arguments MarkArguments) (*mcp_golang.ToolResponse, error) {
+	function, ok := markFunctions[arguments.Mark]
+	if !ok {
+		errMsg := fmt.Sprintf("Unknown mark: %s. Use in, out, reset, resetIn or resetOut", arguments.Mark)
+		m.log(ctx).Error(errMsg)
+		return nil, fmt.Errorf(errMsg)
+	}
+	return m.sendPlaybackFunction(ctx, arguments.BaseVMixArguments, arguments.Input, fmt.Sprintf("mark %s of input %s", arguments.Mark, arguments.Input), function, nil)
+}
+
+// TimeRemaining implements MCPvMix.
+func (m *mcpVmix) TimeRemaining(ctx context.Context, arguments TimeRemainingArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+	state, err := m.pool.refresh(target)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	inputs := lo.Filter(state.Inputs, func(in stateInput, _ int) bool { return in.Duration > 0 })
+	if arguments.Input != "" {
+		in, ok := state.input(arguments.Input)
+		if !ok {
+			errMsg := fmt.Sprintf("Input %s not found", arguments.Input)
+			m.log(ctx).Error(errMsg, "instance", target)
+			return nil, fmt.Errorf(errMsg)
+		}
+		inputs = []stateInput{*in}
+	}
+	if len(inputs) == 0 {
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("There are no inputs with a duration.")), nil
+	}
+
+	warn := time.Duration(lo.Ternary(arguments.WarnSeconds > 0, arguments.WarnSeconds, defaultWarnSeconds)) * time.Second
+	onAir := state.onAir()
+	lines := make([]string, 0, len(inputs))
+	for _, in := range inputs {
+		line := describePlayback(in)
+		if onAir[in.Key] {
+			line += ", on air"
+			if strings.EqualFold(in.State, "Running") && !in.Loop && in.remaining() <= warn {
+				line += fmt.Sprintf(". ENDING in %s", formatClock(in.remaining()))
+			}
+		}
+		lines = append(lines, line)
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(strings.Join(lines, "\n"))), nil
+}
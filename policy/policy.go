@@ -0,0 +1,141 @@
+// Package policy decides which tools may be called, so a model connected during a live show cannot touch the program output.
+// Every tool is classified when it is registered, and calls the mode does not allow are rejected before vMix is touched.
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Class is how much a tool can affect vMix.
+type Class int
+
+const (
+	// Read does not change vMix.
+	Read Class = iota
+	// Preview changes only what viewers do not see: preview, inputs not on air, snapshots.
+	Preview
+	// Program changes the program output or starts outputs.
+	Program
+	// Destructive ends outputs viewers see or loses recordings: stop streaming/recording, fade to black.
+	Destructive
+)
+
+func (c Class) String() string {
+	switch c {
+	case Read:
+		return "read"
+	case Preview:
+		return "preview-only"
+	case Program:
+		return "program-affecting"
+	case Destructive:
+		return "destructive"
+	}
+	return fmt.Sprintf("Class(%d)", int(c))
+}
+
+// Classes lists every class.
+var Classes = []Class{Read, Preview, Program, Destructive}
+
+// ParseClass parses the name of a class as returned by String.
+func ParseClass(s string) (Class, error) {
+	for _, c := range Classes {
+		if s == c.String() {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown class: %s", s)
+}
+
+// Mode is the safety mode the server runs in.
+type Mode string
+
+const (
+	ModeFull        Mode = "full"
+	ModePreviewOnly Mode = "preview-only"
+	ModeReadOnly    Mode = "read-only"
+)
+
+// Modes lists every mode.
+var Modes = []Mode{ModeFull, ModePreviewOnly, ModeReadOnly}
+
+// ParseMode parses full, preview-only or read-only.
+func ParseMode(s string) (Mode, error) {
+	for _, m := range Modes {
+		if strings.EqualFold(s, string(m)) {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown mode: %s", s)
+}
+
+// Allows reports whether tools of class may be called in the mode.
+func (m Mode) Allows(class Class) bool {
+	switch m {
+	case ModeReadOnly:
+		return class == Read
+	case ModePreviewOnly:
+		return class <= Preview
+	default:
+		return true
+	}
+}
+
+// Policy checks tool calls against the mode.
+type Policy struct {
+	mode Mode
+	// OnReject is called when a call is rejected, e.g. to log it.
+	OnReject func(tool string, class Class, err error)
+}
+
+// New creates a policy for mode.
+func New(mode Mode) *Policy {
+	return &Policy{mode: mode}
+}
+
+// Mode returns the mode of the policy.
+func (p *Policy) Mode() Mode {
+	return p.mode
+}
+
+// Check returns an error if the tool may not be called.
+func (p *Policy) Check(tool string, class Class) error {
+	if p.mode.Allows(class) {
+		return nil
+	}
+	return fmt.Errorf("%s is disabled: the server runs in %s mode and this tool is %s. Nothing was sent to vMix", tool, p.mode, class)
+}
+
+// Describe adds a note to the description of tools the mode does not allow, so the model does not try them.
+func (p *Policy) Describe(class Class, description string) string {
+	if p.mode.Allows(class) {
+		return description
+	}
+	return fmt.Sprintf("%s (Disabled: the server runs in %s mode.)", description, p.mode)
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Guard wraps an mcp-golang tool handler so calls are checked before the handler runs.
+// The returned handler has the same type as handler, so the input schema of the tool does not change.
+func (p *Policy) Guard(tool string, class Class, handler any) (any, error) {
+	v := reflect.ValueOf(handler)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumOut() != 2 || t.Out(1) != errorType {
+		return nil, fmt.Errorf("handler of %s must be a function returning (response, error)", tool)
+	}
+
+	return reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		if err := p.Check(tool, class); err != nil {
+			if p.OnReject != nil {
+				p.OnReject(tool, class, err)
+			}
+			errValue := reflect.New(errorType).Elem()
+			errValue.Set(reflect.ValueOf(err))
+			return []reflect.Value{reflect.Zero(t.Out(0)), errValue}
+		}
+		return v.Call(args)
+	}).Interface(), nil
+}
@@ -0,0 +1,128 @@
+package policy_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/FlowingSPDG/mcp-vmix/policy"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    policy.Mode
+		wantErr bool
+	}{
+		{in: "full", want: policy.ModeFull},
+		{in: "preview-only", want: policy.ModePreviewOnly},
+		{in: "read-only", want: policy.ModeReadOnly},
+		{in: "Read-Only", want: policy.ModeReadOnly},
+		{in: "", wantErr: true},
+		{in: "readonly", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := policy.ParseMode(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMode(%q) error = %v, wantErr %t", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAllows(t *testing.T) {
+	classes := policy.Classes
+	// allowed lists the result of Allows for every class in the order of policy.Classes.
+	allowed := map[policy.Mode][]bool{
+		policy.ModeFull:        {true, true, true, true},
+		policy.ModePreviewOnly: {true, true, false, false},
+		policy.ModeReadOnly:    {true, false, false, false},
+	}
+	if len(allowed) != len(policy.Modes) {
+		t.Fatalf("the table covers %d modes, want all %d", len(allowed), len(policy.Modes))
+	}
+	for _, mode := range policy.Modes {
+		for i, class := range classes {
+			if got := mode.Allows(class); got != allowed[mode][i] {
+				t.Errorf("%s.Allows(%s) = %t, want %t", mode, class, got, allowed[mode][i])
+			}
+		}
+	}
+}
+
+func TestParseClass(t *testing.T) {
+	for _, class := range policy.Classes {
+		got, err := policy.ParseClass(class.String())
+		if err != nil || got != class {
+			t.Errorf("ParseClass(%q) = %v, %v, want %v", class.String(), got, err, class)
+		}
+	}
+	if _, err := policy.ParseClass("program"); err == nil {
+		t.Error(`ParseClass("program") succeeded`)
+	}
+}
+
+type args struct {
+	Input string
+}
+
+func TestGuardRejects(t *testing.T) {
+	p := policy.New(policy.ModePreviewOnly)
+	var rejected string
+	p.OnReject = func(tool string, class policy.Class, err error) {
+		rejected = tool
+	}
+
+	called := false
+	handler := func(ctx context.Context, a args) (*string, error) {
+		called = true
+		return &a.Input, nil
+	}
+	guarded, err := p.Guard("vmix_cut", policy.Program, handler)
+	if err != nil {
+		t.Fatalf("Guard() error = %v", err)
+	}
+
+	resp, err := guarded.(func(context.Context, args) (*string, error))(context.Background(), args{Input: "1"})
+	if err == nil || !strings.Contains(err.Error(), "Nothing was sent to vMix") {
+		t.Errorf("guarded handler error = %v, want the rejection", err)
+	}
+	if resp != nil {
+		t.Errorf("guarded handler response = %v, want nil", resp)
+	}
+	if called {
+		t.Error("the handler was called although the mode rejects the tool")
+	}
+	if rejected != "vmix_cut" {
+		t.Errorf("OnReject got %q, want vmix_cut", rejected)
+	}
+}
+
+func TestGuardAllows(t *testing.T) {
+	p := policy.New(policy.ModePreviewOnly)
+	handler := func(ctx context.Context, a args) (*string, error) {
+		return &a.Input, nil
+	}
+	guarded, err := p.Guard("vmix_preview_input", policy.Preview, handler)
+	if err != nil {
+		t.Fatalf("Guard() error = %v", err)
+	}
+
+	resp, err := guarded.(func(context.Context, args) (*string, error))(context.Background(), args{Input: "1"})
+	if err != nil {
+		t.Fatalf("guarded handler error = %v", err)
+	}
+	if resp == nil || *resp != "1" {
+		t.Errorf("guarded handler response = %v, want the response of the handler", resp)
+	}
+}
+
+func TestGuardInvalidHandler(t *testing.T) {
+	p := policy.New(policy.ModeFull)
+	if _, err := p.Guard("vmix_cut", policy.Program, func() {}); err == nil {
+		t.Error("Guard() of a handler without an error result succeeded")
+	}
+}
@@ -0,0 +1,233 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/FlowingSPDG/mcp-vmix/tcpapi"
+)
+
+const (
+	// defaultStateMaxAge is how long a cached XML state is considered fresh.
+	defaultStateMaxAge = 2 * time.Second
+	// defaultIdleTTL is how long an unused client stays in the pool.
+	defaultIdleTTL = 10 * time.Minute
+)
+
+// clientPool caches one vMix HTTP API client and the latest XML state per host:port.
+// Reusing the cached state keeps shortcut functions from downloading the whole XML state on every call.
+type clientPool struct {
+	mu      sync.Mutex
+	clients map[string]*pooledClient
+
+	maxAge  time.Duration
+	idleTTL time.Duration
+
+	// tcpClient returns the TCP API connection of a target. The state of targets with the TCP API is read with XML over it.
+	tcpClient func(target vmixTarget) (*tcpapi.Client, error)
+
+	// streams remembers when streams went live in the downloaded states. It outlives evicted clients.
+	streams *streamClock
+}
+
+type pooledClient struct {
+	client    *httpAPIClient
+	state     *vmixState
+	fetchedAt time.Time // when state was downloaded
+	lastUsed  time.Time
+}
+
+func newClientPool(maxAge, idleTTL time.Duration) *clientPool {
+	return &clientPool{
+		clients: map[string]*pooledClient{},
+		maxAge:  maxAge,
+		idleTTL: idleTTL,
+		streams: newStreamClock(),
+	}
+}
+
+// poolKey identifies the client of the target. Targets with other credentials do not share a client.
+func poolKey(target vmixTarget) string {
+	if target.Username == "" {
+		return fmt.Sprintf("%s:%d", target.Host, target.Port)
+	}
+	return fmt.Sprintf("%s@%s:%d", target.Username, target.Host, target.Port)
+}
+
+// entryLocked returns the entry for target, creating one. p.mu must be held.
+// use is false for background polls, which must not keep an idle client from being swept.
+func (p *clientPool) entryLocked(target vmixTarget, use bool) *pooledClient {
+	now := time.Now()
+	p.sweepLocked(now)
+	key := poolKey(target)
+	pc, ok := p.clients[key]
+	if !ok {
+		pc = &pooledClient{client: newHTTPAPIClient(target), lastUsed: now}
+		p.clients[key] = pc
+	}
+	if use {
+		pc.lastUsed = now
+	}
+	return pc
+}
+
+// get returns a cached client for the target, creating one if there is none.
+// Use state() when the state matters.
+func (p *clientPool) get(target vmixTarget) *httpAPIClient {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.entryLocked(target, true).client
+}
+
+// state returns the XML state which is not older than maxAge.
+func (p *clientPool) state(target vmixTarget) (*vmixState, error) {
+	p.mu.Lock()
+	pc := p.entryLocked(target, true)
+	if pc.state != nil && time.Since(pc.fetchedAt) < p.maxAge {
+		state := pc.state
+		p.mu.Unlock()
+		return state, nil
+	}
+	p.mu.Unlock()
+
+	return p.refresh(target)
+}
+
+// refresh downloads the XML state again.
+func (p *clientPool) refresh(target vmixTarget) (*vmixState, error) {
+	return p.download(target, true)
+}
+
+// poll downloads the XML state for a background monitor.
+// Unlike refresh it does not evict the client when vMix does not respond and does not count as a use of the client.
+func (p *clientPool) poll(target vmixTarget) (*vmixState, error) {
+	return p.download(target, false)
+}
+
+func (p *clientPool) download(target vmixTarget, use bool) (*vmixState, error) {
+	state, err := p.fetch(target, use)
+	if err != nil {
+		if use {
+			p.evict(target)
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	pc := p.entryLocked(target, use)
+	pc.state = state
+	pc.fetchedAt = now
+	p.mu.Unlock()
+	p.streams.update(poolKey(target), state, now)
+	return state, nil
+}
+
+// fetch reads the XML state with the TCP API when the target uses it, otherwise with the HTTP API.
+func (p *clientPool) fetch(target vmixTarget, use bool) (*vmixState, error) {
+	if target.TCPPort != 0 && p.tcpClient != nil {
+		state, err := p.fetchTCP(target)
+		if err == nil || target.TCPOnly {
+			return state, err
+		}
+	}
+	p.mu.Lock()
+	client := p.entryLocked(target, use).client
+	p.mu.Unlock()
+	return client.fetchState()
+}
+
+func (p *clientPool) fetchTCP(target vmixTarget) (*vmixState, error) {
+	client, err := p.tcpClient(target)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), stateFetchTimeout)
+	defer cancel()
+	body, err := client.XML(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read XML over TCP API: %w", err)
+	}
+	return parseState(body)
+}
+
+// markStale makes the next state() call download the XML state again.
+func (p *clientPool) markStale(target vmixTarget) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pc, ok := p.clients[poolKey(target)]; ok {
+		pc.fetchedAt = time.Time{}
+	}
+}
+
+// evict removes the client for the target. Call this when vMix did not respond so the next call reconnects.
+func (p *clientPool) evict(target vmixTarget) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.clients, poolKey(target))
+}
+
+// sweepLocked drops clients which have not been used for idleTTL. p.mu must be held.
+func (p *clientPool) sweepLocked(now time.Time) {
+	for key, pc := range p.clients {
+		if now.Sub(pc.lastUsed) > p.idleTTL {
+			delete(p.clients, key)
+		}
+	}
+}
+
+// streamClock remembers since when the streams of each instance are live, since the XML state has no stream duration.
+// Streams which were already live when an instance was first read are timed from then.
+type streamClock struct {
+	mu    sync.Mutex
+	since map[string]map[int]liveSince // by pool key and stream channel. Channel 0 is any stream for vMix older than 26.
+}
+
+type liveSince struct {
+	time time.Time
+	// seen is set when the stream went live between two reads. Otherwise it was live on the first read.
+	seen bool
+}
+
+func newStreamClock() *streamClock {
+	return &streamClock{since: map[string]map[int]liveSince{}}
+}
+
+// update records the streams which are live in the state read at now.
+func (c *streamClock) update(key string, s *vmixState, now time.Time) {
+	live, known := s.liveStreams()
+	if !known && s.Streaming.On {
+		live = []int{0}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prev, watched := c.since[key]
+	next := make(map[int]liveSince, len(live))
+	for _, channel := range live {
+		if l, ok := prev[channel]; ok {
+			next[channel] = l
+			continue
+		}
+		next[channel] = liveSince{time: now, seen: watched}
+	}
+	c.since[key] = next
+}
+
+// liveFor returns " for 01:23:45" for a stream channel, " for at least 01:23:45" when it was live on the first read,
+// or "" when it is not known to be live.
+func (c *streamClock) liveFor(key string, channel int) string {
+	c.mu.Lock()
+	l, ok := c.since[key][channel]
+	c.mu.Unlock()
+	if !ok {
+		return ""
+	}
+	d := formatSeconds(int(time.Since(l.time).Seconds()))
+	if !l.seen {
+		return " for at least " + d + " (since the server first read it)"
+	}
+	return " for " + d
+}
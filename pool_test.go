@@ -0,0 +1,33 @@
+package mcpvmix
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPoolPoll(t *testing.T) {
+	// Nothing listens on port 1, so every download fails.
+	target := vmixTarget{Name: "main", Host: "127.0.0.1", Port: 1}
+	p := newClientPool(time.Second, time.Minute)
+	p.get(target)
+	lastUsed := time.Now().Add(-30 * time.Second)
+	p.clients[poolKey(target)].lastUsed = lastUsed
+
+	if _, err := p.poll(target); err == nil {
+		t.Fatal("poll() of an unreachable instance succeeded")
+	}
+	pc, ok := p.clients[poolKey(target)]
+	if !ok {
+		t.Fatal("poll() evicted the client")
+	}
+	if !pc.lastUsed.Equal(lastUsed) {
+		t.Errorf("poll() changed lastUsed to %v", pc.lastUsed)
+	}
+
+	if _, err := p.refresh(target); err == nil {
+		t.Fatal("refresh() of an unreachable instance succeeded")
+	}
+	if _, ok := p.clients[poolKey(target)]; ok {
+		t.Error("refresh() kept the client of an instance which did not respond")
+	}
+}
@@ -0,0 +1,272 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/samber/lo"
+
+	"github.com/FlowingSPDG/mcp-vmix/functions"
+)
+
+// maxPTZMotion is the longest motion stopped by the tool itself.
+const maxPTZMotion = 10 * time.Second
+
+var ptzMoveFunctions = map[string]string{
+	"up":        "PTZMoveUp",
+	"down":      "PTZMoveDown",
+	"left":      "PTZMoveLeft",
+	"right":     "PTZMoveRight",
+	"upLeft":    "PTZMoveUpLeft",
+	"upRight":   "PTZMoveUpRight",
+	"downLeft":  "PTZMoveDownLeft",
+	"downRight": "PTZMoveDownRight",
+}
+
+// ptzMotion sends a continuous PTZ function. With durationMs it waits and sends stop, even when ctx is cancelled meanwhile.
+func (m *mcpVmix) ptzMotion(ctx context.Context, target vmixTarget, action, input, function, stop string, motion PTZMotion) (*mcp_golang.ToolResponse, error) {
+	duration := time.Duration(motion.DurationMs) * time.Millisecond
+	if duration < 0 || duration > maxPTZMotion {
+		errMsg := fmt.Sprintf("durationMs must be 0~%d: %d", maxPTZMotion.Milliseconds(), motion.DurationMs)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	if err := m.checkInputExists(target, input); err != nil {
+		errMsg := fmt.Sprintf("Failed to %s, nothing was sent to vMix: %v", action, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	call := functions.Call{Function: function, Input: input}
+	if motion.Speed != nil {
+		call.Value = strconv.FormatFloat(*motion.Speed, 'g', -1, 64)
+	}
+	if err := m.sendCatalogueFunction(ctx, target, action, call); err != nil {
+		return nil, err
+	}
+	if duration == 0 {
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Sent %s to input %s. It keeps going until stop is sent.", function, input))), nil
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(duration):
+	}
+	// 呼び出しがキャンセルされてもカメラは止める
+	if err := m.sendCatalogueFunction(context.WithoutCancel(ctx), target, fmt.Sprintf("stop camera %s", input), functions.Call{Function: stop, Input: input}); err != nil {
+		return nil, err
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Sent %s to input %s and %s after %s", function, input, stop, duration))), nil
+}
+
+// checkInputExists rejects inputs which are not in the cached state. Unknown state is left to vMix.
+func (m *mcpVmix) checkInputExists(target vmixTarget, input string) error {
+	state, err := m.pool.state(target)
+	if err != nil {
+		return nil
+	}
+	if _, ok := state.input(input); !ok {
+		return fmt.Errorf("input %s not found", input)
+	}
+	return nil
+}
+
+// PTZMove implements MCPvMix.
+func (m *mcpVmix) PTZMove(ctx context.Context, arguments PTZMoveArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	switch arguments.Direction {
+	case "home", "stop":
+		if arguments.Speed != nil || arguments.DurationMs != 0 {
+			errMsg := fmt.Sprintf("speed and durationMs are not used with direction %s", arguments.Direction)
+			m.log(ctx).Error(errMsg, "instance", target)
+			return nil, fmt.Errorf(errMsg)
+		}
+		function := lo.Ternary(arguments.Direction == "home", "PTZHome", "PTZMoveStop")
+		return m.ptzSingle(ctx, target, fmt.Sprintf("move camera %s %s", arguments.Input, arguments.Direction), arguments.Input, function)
+	}
+	function, ok := ptzMoveFunctions[arguments.Direction]
+	if !ok {
+		errMsg := fmt.Sprintf("Unknown PTZ direction: %s", arguments.Direction)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	return m.ptzMotion(ctx, target, fmt.Sprintf("move camera %s %s", arguments.Input, arguments.Direction), arguments.Input, function, "PTZMoveStop", arguments.PTZMotion)
+}
+
+// PTZZoom implements MCPvMix.
+func (m *mcpVmix) PTZZoom(ctx context.Context, arguments PTZZoomArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	action := fmt.Sprintf("zoom camera %s %s", arguments.Input, arguments.Zoom)
+	switch arguments.Zoom {
+	case "in":
+		return m.ptzMotion(ctx, target, action, arguments.Input, "PTZZoomIn", "PTZZoomStop", arguments.PTZMotion)
+	case "out":
+		return m.ptzMotion(ctx, target, action, arguments.Input, "PTZZoomOut", "PTZZoomStop", arguments.PTZMotion)
+	case "stop":
+		return m.ptzSingle(ctx, target, action, arguments.Input, "PTZZoomStop")
+	}
+	errMsg := fmt.Sprintf("Unknown PTZ zoom: %s. Use in, out or stop", arguments.Zoom)
+	m.log(ctx).Error(errMsg, "instance", target)
+	return nil, fmt.Errorf(errMsg)
+}
+
+// PTZFocus implements MCPvMix.
+func (m *mcpVmix) PTZFocus(ctx context.Context, arguments PTZFocusArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	action := fmt.Sprintf("focus camera %s %s", arguments.Input, arguments.Focus)
+	switch arguments.Focus {
+	case "near":
+		return m.ptzMotion(ctx, target, action, arguments.Input, "PTZFocusNear", "PTZFocusStop", arguments.PTZMotion)
+	case "far":
+		return m.ptzMotion(ctx, target, action, arguments.Input, "PTZFocusFar", "PTZFocusStop", arguments.PTZMotion)
+	case "auto", "manual", "stop":
+		function := map[string]string{"auto": "PTZFocusAuto", "manual": "PTZFocusManual", "stop": "PTZFocusStop"}[arguments.Focus]
+		return m.ptzSingle(ctx, target, action, arguments.Input, function)
+	}
+	errMsg := fmt.Sprintf("Unknown PTZ focus: %s. Use auto, manual, near, far or stop", arguments.Focus)
+	m.log(ctx).Error(errMsg, "instance", target)
+	return nil, fmt.Errorf(errMsg)
+}
+
+// ptzSingle sends a PTZ function which takes no speed.
+func (m *mcpVmix) ptzSingle(ctx context.Context, target vmixTarget, action, input, function string) (*mcp_golang.ToolResponse, error) {
+	if err := m.checkInputExists(target, input); err != nil {
+		errMsg := fmt.Sprintf("Failed to %s, nothing was sent to vMix: %v", action, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	if err := m.sendCatalogueFunction(ctx, target, action, functions.Call{Function: function, Input: input}); err != nil {
+		return nil, err
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Sent %s to input %s", function, input))), nil
+}
+
+// PTZCreateVirtualInput implements MCPvMix.
+func (m *mcpVmix) PTZCreateVirtualInput(ctx context.Context, arguments PTZCreateVirtualInputArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	action := fmt.Sprintf("create a PTZ virtual input of camera %s", arguments.Input)
+	state, err := m.pool.refresh(target)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	if _, ok := state.input(arguments.Input); !ok {
+		errMsg := fmt.Sprintf("Failed to %s, nothing was sent to vMix: input %s not found", action, arguments.Input)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	before := state.inputKeys()
+
+	if err := m.sendCatalogueFunction(ctx, target, action, functions.Call{Function: "PTZCreateVirtualInput", Input: arguments.Input}); err != nil {
+		return nil, err
+	}
+	added, err := m.waitAddedInputs(ctx, target, before, 1)
+	if err != nil {
+		errMsg := fmt.Sprintf("Created a PTZ virtual input but failed to find it: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	if len(added) == 0 {
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Sent PTZCreateVirtualInput to input %s, but no new input appeared within %s. Is it a PTZ camera?", arguments.Input, inputWaitTimeout))), nil
+	}
+	lines := lo.Map(added, func(in stateInput, _ int) string { return "Created " + describeInput(in) })
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(strings.Join(lines, "\n"))), nil
+}
+
+// PTZMoveToVirtualInput implements MCPvMix.
+func (m *mcpVmix) PTZMoveToVirtualInput(ctx context.Context, arguments PTZMoveToVirtualInputArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	if arguments.Index != nil {
+		action := fmt.Sprintf("move camera %s to virtual input position %d", arguments.Input, *arguments.Index)
+		if err := m.checkInputExists(target, arguments.Input); err != nil {
+			errMsg := fmt.Sprintf("Failed to %s, nothing was sent to vMix: %v", action, err)
+			m.log(ctx).Error(errMsg, "instance", target)
+			return nil, fmt.Errorf(errMsg)
+		}
+		call := functions.Call{Function: "PTZMoveToVirtualInputPositionByIndex", Input: arguments.Input, Value: strconv.Itoa(*arguments.Index)}
+		if err := m.sendCatalogueFunction(ctx, target, action, call); err != nil {
+			return nil, err
+		}
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Moving camera %s to the position of its virtual input %d", arguments.Input, *arguments.Index))), nil
+	}
+
+	action := fmt.Sprintf("move the camera of virtual input %s to its position", arguments.Input)
+	if state, err := m.pool.state(target); err == nil {
+		in, ok := state.input(arguments.Input)
+		switch {
+		case !ok:
+			err = fmt.Errorf("input %s not found", arguments.Input)
+		case !isPTZVirtualInput(*in):
+			err = fmt.Errorf("input %s is a %s input, not a PTZ virtual input. Use vmix_ptz_inputs to find them", arguments.Input, in.Type)
+		}
+		if err != nil {
+			errMsg := fmt.Sprintf("Failed to %s, nothing was sent to vMix: %v", action, err)
+			m.log(ctx).Error(errMsg, "instance", target)
+			return nil, fmt.Errorf(errMsg)
+		}
+	}
+	if err := m.sendCatalogueFunction(ctx, target, action, functions.Call{Function: "PTZMoveToVirtualInputPosition", Input: arguments.Input}); err != nil {
+		return nil, err
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Moving the camera to the position of virtual input %s", arguments.Input))), nil
+}
+
+// PTZInputs implements MCPvMix.
+func (m *mcpVmix) PTZInputs(ctx context.Context, arguments PTZInputsArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+	state, err := m.pool.refresh(target)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	virtuals := lo.Filter(state.Inputs, func(in stateInput, _ int) bool { return isPTZVirtualInput(in) })
+	if len(virtuals) == 0 {
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("There are no PTZ virtual inputs. Create one with vmix_ptz_create_virtual_input after framing a shot.")), nil
+	}
+	lines := []string{"PTZ virtual inputs. Cutting to one or vmix_ptz_move_to_virtual_input moves its camera to the saved position:"}
+	for _, in := range virtuals {
+		line := "- " + describeInput(in)
+		if state.Active == in.Number {
+			line += ", in program"
+		} else if state.Preview == in.Number {
+			line += ", in preview"
+		}
+		lines = append(lines, line)
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(strings.Join(lines, "\n"))), nil
+}
+
+// isPTZVirtualInput reports whether in is a virtual input, which vMix creates for PTZ positions.
+func isPTZVirtualInput(in stateInput) bool {
+	return strings.EqualFold(in.Type, "Virtual")
+}
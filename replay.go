@@ -0,0 +1,328 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/samber/lo"
+
+	"github.com/FlowingSPDG/mcp-vmix/functions"
+)
+
+const (
+	replayCameras    = 8
+	replayEventLists = 20
+)
+
+// replayInputs returns the replay inputs of the state.
+func (s *vmixState) replayInputs() []stateInput {
+	return lo.Filter(s.Inputs, func(in stateInput, _ int) bool { return strings.EqualFold(in.Type, "Replay") })
+}
+
+// replayInput returns the replay input named by input, or the first replay input when input is empty.
+func (s *vmixState) replayInput(input string) (*stateInput, error) {
+	replays := s.replayInputs()
+	if len(replays) == 0 {
+		return nil, fmt.Errorf("there is no replay input. Add one in vMix first")
+	}
+	if input == "" {
+		return &replays[0], nil
+	}
+	in, ok := s.input(input)
+	if !ok {
+		return nil, fmt.Errorf("input %s not found", input)
+	}
+	if !strings.EqualFold(in.Type, "Replay") {
+		return nil, fmt.Errorf("input %s is a %s input, not a replay input", input, in.Type)
+	}
+	return in, nil
+}
+
+// sendReplayFunction checks the replay input and sends a replay function.
+// The replay functions need the 4K or Pro edition, so they are rejected before sending on lower editions.
+func (m *mcpVmix) sendReplayFunction(ctx context.Context, target vmixTarget, action string, arguments ReplayInput, function, value string) error {
+	if state, err := m.pool.state(target); err == nil {
+		if _, err := state.replayInput(arguments.Input); err != nil {
+			errMsg := fmt.Sprintf("Failed to %s, nothing was sent to vMix: %v", action, err)
+			m.log(ctx).Error(errMsg, "instance", target)
+			return fmt.Errorf(errMsg)
+		}
+	}
+	return m.sendCatalogueFunction(ctx, target, action, functions.Call{Function: function, Input: arguments.Input, Value: value})
+}
+
+// replay sends a replay function and responds with the replay status afterwards.
+func (m *mcpVmix) replay(ctx context.Context, base BaseVMixArguments, arguments ReplayInput, action, function, value string) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.sendReplayFunction(ctx, target, action, arguments, function, value); err != nil {
+		return nil, err
+	}
+	return m.replayStatusResponse(ctx, target, arguments.Input, fmt.Sprintf("Sent %s", strings.TrimSpace(function+" "+value)))
+}
+
+// ReplayMark implements MCPvMix.
+func (m *mcpVmix) ReplayMark(ctx context.Context, arguments ReplayMarkArguments) (*mcp_golang.ToolResponse, error) {
+	var function, value string
+	switch arguments.Mark {
+	case "in":
+		function = "ReplayMarkIn"
+	case "out":
+		function = "ReplayMarkOut"
+	case "inOut":
+		function, value = "ReplayMarkInOut", strconv.Itoa(arguments.Seconds)
+	case "cancel":
+		function = "ReplayMarkCancel"
+	default:
+		errMsg := fmt.Sprintf("Unknown replay mark: %s. Use in, out, inOut or cancel", arguments.Mark)
+		m.log(ctx).Error(errMsg)
+		return nil, fmt.Errorf(errMsg)
+	}
+	if (arguments.Mark == "inOut") != (arguments.Seconds != 0) {
+		errMsg := "seconds is required for mark inOut and only used with it"
+		m.log(ctx).Error(errMsg)
+		return nil, fmt.Errorf(errMsg)
+	}
+	return m.replay(ctx, arguments.BaseVMixArguments, arguments.ReplayInput, fmt.Sprintf("mark replay %s", arguments.Mark), function, value)
+}
+
+// ReplayPlay implements MCPvMix.
+func (m *mcpVmix) ReplayPlay(ctx context.Context, arguments ReplayPlayArguments) (*mcp_golang.ToolResponse, error) {
+	var function, value string
+	switch arguments.Event {
+	case "last":
+		function = "ReplayPlayLastEvent"
+	case "selected":
+		function = "ReplayPlaySelectedEvent"
+	case "all":
+		function = "ReplayPlayAllEvents"
+	case "index":
+		if arguments.Index == nil {
+			errMsg := "index is required to play event index"
+			m.log(ctx).Error(errMsg)
+			return nil, fmt.Errorf(errMsg)
+		}
+		function, value = "ReplayPlayEvent", strconv.Itoa(*arguments.Index)
+	default:
+		errMsg := fmt.Sprintf("Unknown replay event: %s. Use last, selected, all or index", arguments.Event)
+		m.log(ctx).Error(errMsg)
+		return nil, fmt.Errorf(errMsg)
+	}
+	if arguments.Event != "index" && arguments.Index != nil {
+		errMsg := "index is only used with event index"
+		m.log(ctx).Error(errMsg)
+		return nil, fmt.Errorf(errMsg)
+	}
+	if arguments.ToOutput {
+		function += "ToOutput"
+	}
+	action := fmt.Sprintf("play %s replay event%s", arguments.Event, lo.Ternary(arguments.ToOutput, " to output", ""))
+	return m.replay(ctx, arguments.BaseVMixArguments, arguments.ReplayInput, action, function, value)
+}
+
+// ReplaySpeed implements MCPvMix.
+func (m *mcpVmix) ReplaySpeed(ctx context.Context, arguments ReplaySpeedArguments) (*mcp_golang.ToolResponse, error) {
+	switch {
+	case arguments.Speed != nil && arguments.Change == nil:
+		value := strconv.FormatFloat(*arguments.Speed, 'g', -1, 64)
+		return m.replay(ctx, arguments.BaseVMixArguments, arguments.ReplayInput, fmt.Sprintf("set replay speed to %s", value), "ReplaySetSpeed", value)
+	case arguments.Change != nil && arguments.Speed == nil:
+		value := strconv.FormatFloat(*arguments.Change, 'g', -1, 64)
+		return m.replay(ctx, arguments.BaseVMixArguments, arguments.ReplayInput, fmt.Sprintf("change replay speed by %s", value), "ReplayChangeSpeed", value)
+	}
+	errMsg := "Give either speed or change"
+	m.log(ctx).Error(errMsg)
+	return nil, fmt.Errorf(errMsg)
+}
+
+// ReplaySelectEvents implements MCPvMix.
+func (m *mcpVmix) ReplaySelectEvents(ctx context.Context, arguments ReplaySelectEventsArguments) (*mcp_golang.ToolResponse, error) {
+	if arguments.List < 1 || arguments.List > replayEventLists {
+		errMsg := fmt.Sprintf("Event list must be 1~%d: %d", replayEventLists, arguments.List)
+		m.log(ctx).Error(errMsg)
+		return nil, fmt.Errorf(errMsg)
+	}
+	return m.replay(ctx, arguments.BaseVMixArguments, arguments.ReplayInput, fmt.Sprintf("select replay event list %d", arguments.List), fmt.Sprintf("ReplaySelectEvents%d", arguments.List), "")
+}
+
+// ReplayCamera implements MCPvMix.
+func (m *mcpVmix) ReplayCamera(ctx context.Context, arguments ReplayCameraArguments) (*mcp_golang.ToolResponse, error) {
+	if arguments.Camera < 1 || arguments.Camera > replayCameras {
+		errMsg := fmt.Sprintf("Replay camera must be 1~%d: %d", replayCameras, arguments.Camera)
+		m.log(ctx).Error(errMsg)
+		return nil, fmt.Errorf(errMsg)
+	}
+	channel := strings.ToUpper(arguments.Channel)
+	if channel != "" && channel != "A" && channel != "B" {
+		errMsg := fmt.Sprintf("Replay channel must be A or B: %s", arguments.Channel)
+		m.log(ctx).Error(errMsg)
+		return nil, fmt.Errorf(errMsg)
+	}
+	action := fmt.Sprintf("switch replay%s to camera %d", lo.Ternary(channel == "", "", " channel "+channel), arguments.Camera)
+	return m.replay(ctx, arguments.BaseVMixArguments, arguments.ReplayInput, action, fmt.Sprintf("Replay%sCamera%d", channel, arguments.Camera), "")
+}
+
+// ReplayStartRecording implements MCPvMix.
+func (m *mcpVmix) ReplayStartRecording(ctx context.Context, arguments ReplayRecordingArguments) (*mcp_golang.ToolResponse, error) {
+	return m.replay(ctx, arguments.BaseVMixArguments, arguments.ReplayInput, "start replay recording", "ReplayStartRecording", "")
+}
+
+// ReplayStopRecording implements MCPvMix.
+func (m *mcpVmix) ReplayStopRecording(ctx context.Context, arguments ReplayRecordingArguments) (*mcp_golang.ToolResponse, error) {
+	return m.replay(ctx, arguments.BaseVMixArguments, arguments.ReplayInput, "stop replay recording", "ReplayStopRecording", "")
+}
+
+// ReplayStatus implements MCPvMix.
+func (m *mcpVmix) ReplayStatus(ctx context.Context, arguments ReplayStatusArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.replayStatusResponse(ctx, target, arguments.Input, "")
+	if err != nil {
+		return nil, err
+	}
+	// イベント一覧を求められても代わりにならないことを明示する
+	resp.Content = append(resp.Content, mcp_golang.NewTextContent(replayEventsUnavailable))
+	return resp, nil
+}
+
+// replayEventsUnavailable explains why individual replay events are not listed.
+const replayEventsUnavailable = "Individual replay events are not listed: the vMix API reports only the selected event list, camera and speed of each channel, not the events in the lists, their cameras or tags."
+
+// ReplayEvents implements MCPvMix.
+func (m *mcpVmix) ReplayEvents(ctx context.Context, arguments ReplayEventsArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+	state, err := m.pool.refresh(target)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	replays := state.replayInputs()
+	if arguments.Input != "" {
+		in, err := state.replayInput(arguments.Input)
+		if err != nil {
+			errMsg := fmt.Sprintf("Failed to get replay events: %v", err)
+			m.log(ctx).Error(errMsg, "instance", target)
+			return nil, fmt.Errorf(errMsg)
+		}
+		replays = []stateInput{*in}
+	}
+
+	var lines []string
+	if len(replays) == 0 {
+		lines = append(lines, "There is no replay input.")
+	}
+	for _, in := range replays {
+		lines = append(lines, describeReplayEvents(in))
+	}
+	lines = append(lines, replayEventsUnavailable)
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(strings.Join(lines, "\n"))), nil
+}
+
+// replayChannel is the state of a replay channel reported in the replay element.
+type replayChannel struct {
+	name      string
+	eventList int
+	camera    string
+}
+
+// channels returns the channels the channel mode uses. An empty mode is treated as both channels.
+func (r *stateReplay) channels() []replayChannel {
+	all := []replayChannel{{"A", r.EventsA, r.CameraA}, {"B", r.EventsB, r.CameraB}}
+	mode := strings.ToUpper(r.ChannelMode)
+	if mode == "" {
+		return all
+	}
+	return lo.Filter(all, func(c replayChannel, _ int) bool { return strings.Contains(mode, c.name) })
+}
+
+// describeReplayEvents lists the event lists selected on the channels of a replay input, which is what the vMix API reports about events.
+func describeReplayEvents(in stateInput) string {
+	name := fmt.Sprintf("Replay input %d %s (key %s)", in.Number, strings.TrimSpace(in.Title), in.Key)
+	r := in.Replay
+	if r == nil {
+		return name + ": no replay state reported"
+	}
+
+	// イベントリストごとに、選択しているチャンネルをまとめる
+	selected := map[int][]string{}
+	for _, c := range r.channels() {
+		selected[c.eventList] = append(selected[c.eventList], fmt.Sprintf("channel %s (camera %s)", c.name, lo.Ternary(c.camera == "", "-", c.camera)))
+	}
+	if r.Events != 0 {
+		selected[r.Events] = append(selected[r.Events], "current")
+	}
+	lists := lo.Keys(selected)
+	slices.Sort(lists)
+
+	lines := []string{fmt.Sprintf("%s, channel mode %s: event lists 1~%d, %s", name, lo.Ternary(r.ChannelMode == "", "-", r.ChannelMode), replayEventLists, lo.Ternary(r.Recording, "recording", "not recording"))}
+	for _, list := range lists {
+		if list == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  Event list %d: selected on %s", list, strings.Join(selected[list], ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// replayStatusResponse reads the latest state and describes the replay inputs. input limits it to one replay input.
+func (m *mcpVmix) replayStatusResponse(ctx context.Context, target vmixTarget, input, header string) (*mcp_golang.ToolResponse, error) {
+	state, err := m.pool.refresh(target)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	replays := state.replayInputs()
+	if input != "" {
+		in, err := state.replayInput(input)
+		if err != nil {
+			errMsg := fmt.Sprintf("Failed to get replay status: %v", err)
+			m.log(ctx).Error(errMsg, "instance", target)
+			return nil, fmt.Errorf(errMsg)
+		}
+		replays = []stateInput{*in}
+	}
+
+	var lines []string
+	if header != "" {
+		lines = append(lines, header)
+	}
+	if len(replays) == 0 {
+		lines = append(lines, "There is no replay input.")
+	}
+	for _, in := range replays {
+		lines = append(lines, describeReplay(in))
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(strings.Join(lines, "\n"))), nil
+}
+
+// describeReplay describes the replay element of a replay input.
+// The vMix API reports the selected event list and camera of each channel, but not the individual events.
+func describeReplay(in stateInput) string {
+	name := fmt.Sprintf("Replay input %d %s (key %s)", in.Number, strings.TrimSpace(in.Title), in.Key)
+	r := in.Replay
+	if r == nil {
+		return name + ": no replay state reported"
+	}
+	lines := []string{
+		fmt.Sprintf("%s: %s, %s, channel mode %s, playback %s", name, lo.Ternary(r.Recording, "recording", "not recording"), lo.Ternary(r.Live, "live", "recorded"), r.ChannelMode, strings.ToLower(in.State)),
+		fmt.Sprintf("  Channel A: event list %d, camera %s, speed %g, timecode %s", r.EventsA, r.CameraA, r.SpeedA, lo.Ternary(r.TimecodeA == "", "-", r.TimecodeA)),
+		fmt.Sprintf("  Channel B: event list %d, camera %s, speed %g, timecode %s", r.EventsB, r.CameraB, r.SpeedB, lo.Ternary(r.TimecodeB == "", "-", r.TimecodeB)),
+		fmt.Sprintf("  Current: event list %d, speed %g, timecode %s", r.Events, r.Speed, lo.Ternary(r.Timecode == "", "-", r.Timecode)),
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,53 @@
+package mcpvmix
+
+import (
+	"testing"
+)
+
+func TestDescribeReplayEvents(t *testing.T) {
+	input := func(replay *stateReplay) stateInput {
+		return stateInput{Number: 4, Title: "Replay ", Key: "r1", Type: "Replay", Replay: replay}
+	}
+	cases := []struct {
+		name   string
+		replay *stateReplay
+		want   string
+	}{
+		{
+			name:   "both channels on different lists",
+			replay: &stateReplay{Recording: true, ChannelMode: "AB", Events: 3, EventsA: 3, EventsB: 1, CameraA: "1", CameraB: "2"},
+			want: "Replay input 4 Replay (key r1), channel mode AB: event lists 1~20, recording\n" +
+				"  Event list 1: selected on channel B (camera 2)\n" +
+				"  Event list 3: selected on channel A (camera 1), current",
+		},
+		{
+			name:   "both channels on one list",
+			replay: &stateReplay{ChannelMode: "AB", Events: 2, EventsA: 2, EventsB: 2, CameraA: "1", CameraB: "3"},
+			want: "Replay input 4 Replay (key r1), channel mode AB: event lists 1~20, not recording\n" +
+				"  Event list 2: selected on channel A (camera 1), channel B (camera 3), current",
+		},
+		{
+			name:   "single channel mode",
+			replay: &stateReplay{Recording: true, ChannelMode: "A", Events: 5, EventsA: 5, EventsB: 1, CameraA: "4", CameraB: "2"},
+			want: "Replay input 4 Replay (key r1), channel mode A: event lists 1~20, recording\n" +
+				"  Event list 5: selected on channel A (camera 4), current",
+		},
+		{
+			name:   "no channel mode or camera",
+			replay: &stateReplay{EventsA: 1},
+			want: "Replay input 4 Replay (key r1), channel mode -: event lists 1~20, not recording\n" +
+				"  Event list 1: selected on channel A (camera -)",
+		},
+		{
+			name: "no replay element",
+			want: "Replay input 4 Replay (key r1): no replay state reported",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := describeReplayEvents(input(tc.replay)); got != tc.want {
+				t.Errorf("describeReplayEvents() =\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
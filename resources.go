@@ -0,0 +1,372 @@
+package mcpvmix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/samber/lo"
+
+	"github.com/FlowingSPDG/mcp-vmix/functions"
+)
+
+const (
+	// resourcePollInterval is how often instances are polled for resource changes.
+	resourcePollInterval = 2 * time.Second
+	// resourceEventPollInterval is the poll interval of instances with the TCP API.
+	// They are checked as soon as a tally or activator event arrives, and polled only for changes
+	// which have no activator, such as title text.
+	resourceEventPollInterval = 30 * time.Second
+)
+
+// ResourceNotifier sends notifications/resources/updated to clients subscribed to the URI.
+type ResourceNotifier interface {
+	NotifyResourceUpdated(uri string) error
+	// Subscribed reports whether a client subscribed to a resource whose URI starts with prefix.
+	Subscribed(prefix string) bool
+	// OnSubscribe calls handler when a client subscribes to a resource.
+	OnSubscribe(handler func(uri string))
+}
+
+type resourceSection struct {
+	name        string
+	description string
+	build       func(s *vmixState) any
+	// fingerprint returns the part of the section whose change is notified. Meters change all the time so they are left out.
+	fingerprint func(s *vmixState) any
+}
+
+var resourceSections = []resourceSection{
+	{
+		name:        "inputs",
+		description: "All inputs of the vMix instance.",
+		build:       func(s *vmixState) any { return s.Inputs },
+		fingerprint: func(s *vmixState) any {
+			return lo.Map(s.Inputs, func(in stateInput, _ int) stateInput { return withoutMeters(in) })
+		},
+	},
+	{
+		name:        "audio",
+		description: "Master, bus and input audio levels, meters and routing of the vMix instance.",
+		build:       func(s *vmixState) any { return newAudioResource(s, true) },
+		fingerprint: func(s *vmixState) any { return newAudioResource(s, false) },
+	},
+	{
+		name:        "outputs",
+		description: "Program/preview, mixes, recording, streaming, external, MultiCorder, playlist and fullscreen state of the vMix instance.",
+		build:       func(s *vmixState) any { return newOutputsResource(s) },
+		fingerprint: func(s *vmixState) any { return newOutputsResource(s) },
+	},
+	{
+		name:        "overlays",
+		description: "Overlay channels of the vMix instance and the input on each channel.",
+		build:       func(s *vmixState) any { return s.Overlays },
+		fingerprint: func(s *vmixState) any { return s.Overlays },
+	},
+}
+
+type audioResource struct {
+	Buses  stateAudio           `json:"buses"`
+	Inputs []audioInputResource `json:"inputs"`
+}
+
+type audioInputResource struct {
+	Number      int      `json:"number"`
+	Key         string   `json:"key"`
+	Title       string   `json:"title"`
+	Muted       bool     `json:"muted"`
+	Volume      float64  `json:"volume"`
+	Balance     float64  `json:"balance"`
+	Solo        bool     `json:"solo"`
+	AudioBusses string   `json:"audioBusses"`
+	GainDb      float64  `json:"gainDb"`
+	MeterF1     *float64 `json:"meterF1,omitempty"`
+	MeterF2     *float64 `json:"meterF2,omitempty"`
+}
+
+func newAudioResource(s *vmixState, meters bool) audioResource {
+	res := audioResource{Buses: s.Audio, Inputs: []audioInputResource{}}
+	if !meters {
+		res.Buses = stateAudio{
+			Master: busWithoutMeters(s.Audio.Master),
+			BusA:   busWithoutMeters(s.Audio.BusA),
+			BusB:   busWithoutMeters(s.Audio.BusB),
+			BusC:   busWithoutMeters(s.Audio.BusC),
+			BusD:   busWithoutMeters(s.Audio.BusD),
+			BusE:   busWithoutMeters(s.Audio.BusE),
+			BusF:   busWithoutMeters(s.Audio.BusF),
+			BusG:   busWithoutMeters(s.Audio.BusG),
+		}
+	}
+	for _, in := range s.Inputs {
+		if in.Volume == nil {
+			continue
+		}
+		a := audioInputResource{
+			Number:      in.Number,
+			Key:         in.Key,
+			Title:       in.Title,
+			Muted:       lo.FromPtr(in.Muted),
+			Volume:      lo.FromPtr(in.Volume),
+			Balance:     lo.FromPtr(in.Balance),
+			Solo:        lo.FromPtr(in.Solo),
+			AudioBusses: lo.FromPtr(in.AudioBusses),
+			GainDb:      lo.FromPtr(in.GainDb),
+		}
+		if meters {
+			a.MeterF1 = in.MeterF1
+			a.MeterF2 = in.MeterF2
+		}
+		res.Inputs = append(res.Inputs, a)
+	}
+	return res
+}
+
+func busWithoutMeters(bus *stateBus) *stateBus {
+	if bus == nil {
+		return nil
+	}
+	b := *bus
+	b.MeterF1, b.MeterF2 = 0, 0
+	return &b
+}
+
+func withoutMeters(in stateInput) stateInput {
+	in.MeterF1, in.MeterF2 = nil, nil
+	return in
+}
+
+type outputsResource struct {
+	Active      int            `json:"active"`
+	Preview     int            `json:"preview"`
+	Mixes       []stateMix     `json:"mixes"`
+	FadeToBlack bool           `json:"fadeToBlack"`
+	Recording   stateRecording `json:"recording"`
+	Streaming   stateStreaming `json:"streaming"`
+	External    bool           `json:"external"`
+	MultiCorder bool           `json:"multiCorder"`
+	PlayList    bool           `json:"playList"`
+	FullScreen  bool           `json:"fullscreen"`
+}
+
+func newOutputsResource(s *vmixState) outputsResource {
+	return outputsResource{
+		Active:      s.Active,
+		Preview:     s.Preview,
+		Mixes:       s.Mixes,
+		FadeToBlack: s.FadeToBlack,
+		Recording:   s.Recording,
+		Streaming:   s.Streaming,
+		External:    s.External,
+		MultiCorder: s.MultiCorder,
+		PlayList:    s.PlayList,
+		FullScreen:  s.FullScreen,
+	}
+}
+
+func resourceURI(instance string, parts ...string) string {
+	return fmt.Sprintf("vmix://%s/%s", instance, strings.Join(parts, "/"))
+}
+
+func jsonResource(uri string, v any) (*mcp_golang.ResourceResponse, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource %s: %w", uri, err)
+	}
+	return mcp_golang.NewResourceResponse(mcp_golang.NewTextEmbeddedResource(uri, string(b), "application/json")), nil
+}
+
+// functionsResourceURI is the catalogue of vMix functions. It does not depend on an instance.
+const functionsResourceURI = "vmix://functions"
+
+// RegisterResources implements MCPvMix.
+// Instances are watched for changes until ctx is done, and polled only while a client subscribes to one of their resources.
+func (m *mcpVmix) RegisterResources(ctx context.Context, server *mcp_golang.Server, notifier ResourceNotifier) error {
+	catalogue, err := functions.Default()
+	if err != nil {
+		return err
+	}
+	if err := server.RegisterResource(functionsResourceURI, "vMix functions", "The catalogue of vMix shortcut functions with their parameters, value format and minimum vMix version/edition.", "application/json", func() (*mcp_golang.ResourceResponse, error) {
+		return jsonResource(functionsResourceURI, catalogue)
+	}); err != nil {
+		return fmt.Errorf("failed to register resource %s: %w", functionsResourceURI, err)
+	}
+
+	for _, in := range m.instances.list() {
+		target := instanceTarget(in)
+		for _, section := range resourceSections {
+			uri := resourceURI(in.Name, section.name)
+			handler := func() (*mcp_golang.ResourceResponse, error) {
+				state, err := m.pool.state(target)
+				if err != nil {
+					errMsg := fmt.Sprintf("Failed to read resource %s: %v", uri, err)
+					m.logger.Error(errMsg, "instance", target, "uri", uri)
+					return nil, fmt.Errorf(errMsg)
+				}
+				return jsonResource(uri, section.build(state))
+			}
+			if err := server.RegisterResource(uri, fmt.Sprintf("%s %s", in.Name, section.name), section.description, "application/json", handler); err != nil {
+				return fmt.Errorf("failed to register resource %s: %w", uri, err)
+			}
+		}
+
+		if monitor, ok := m.audioMonitors[in.Name]; ok {
+			uri := resourceURI(in.Name, "audio", "alerts")
+			if err := server.RegisterResource(uri, fmt.Sprintf("%s audio alerts", in.Name), "Active and recently ended alerts for sustained silence or clipping of inputs, buses and master of the vMix instance.", "application/json", func() (*mcp_golang.ResourceResponse, error) {
+				return jsonResource(uri, monitor.resource())
+			}); err != nil {
+				return fmt.Errorf("failed to register resource %s: %w", uri, err)
+			}
+			monitor.setOnChange(func() {
+				if err := notifier.NotifyResourceUpdated(uri); err != nil {
+					m.logger.Warn("Failed to notify resource update", "uri", uri, "error", err)
+				}
+			})
+		}
+
+		w := &resourceWatcher{
+			m:           m,
+			server:      server,
+			notifier:    notifier,
+			target:      target,
+			fingerprint: map[string][]byte{},
+			inputs:      map[string]struct{}{},
+			trigger:     make(chan struct{}, 1),
+		}
+		stop := m.events.listen(func(ev vmixEvent) {
+			if ev.Target.Host == target.Host && ev.Target.Port == target.Port {
+				w.poke()
+			}
+		})
+		notifier.OnSubscribe(func(uri string) {
+			if strings.HasPrefix(uri, w.prefix()) {
+				w.poke()
+			}
+		})
+		go func() {
+			defer stop()
+			w.run(ctx, lo.Ternary(target.TCPPort != 0, resourceEventPollInterval, resourcePollInterval))
+		}()
+	}
+	return nil
+}
+
+// resourceWatcher polls an instance, registers a resource per input and notifies changed resources.
+type resourceWatcher struct {
+	m        *mcpVmix
+	server   *mcp_golang.Server
+	notifier ResourceNotifier
+	target   vmixTarget
+
+	fingerprint map[string][]byte   // by URI
+	inputs      map[string]struct{} // registered input keys
+	trigger     chan struct{}
+}
+
+func (w *resourceWatcher) poke() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// prefix is the start of the URIs of the resources of the instance.
+func (w *resourceWatcher) prefix() string {
+	return resourceURI(w.target.Name, "")
+}
+
+// run checks the instance once to register the input resources, then polls it while a client subscribes to one of its resources.
+func (w *resourceWatcher) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	w.check()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.trigger:
+		}
+		if !w.notifier.Subscribed(w.prefix()) {
+			// Changes while nobody subscribes are not notified. The next check records the state again.
+			clear(w.fingerprint)
+			continue
+		}
+		w.check()
+	}
+}
+
+func (w *resourceWatcher) check() {
+	state, err := w.m.pool.state(w.target)
+	if err != nil {
+		w.m.logger.Debug("Failed to poll vMix instance for resources", "instance", w.target, "error", err)
+		return
+	}
+
+	for _, section := range resourceSections {
+		w.compare(resourceURI(w.target.Name, section.name), section.fingerprint(state))
+	}
+
+	current := map[string]struct{}{}
+	for _, in := range state.Inputs {
+		current[in.Key] = struct{}{}
+		uri := resourceURI(w.target.Name, "inputs", in.Key)
+		if _, ok := w.inputs[in.Key]; !ok {
+			w.registerInput(in.Key, uri)
+		}
+		w.compare(uri, withoutMeters(in))
+	}
+	for key := range w.inputs {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		uri := resourceURI(w.target.Name, "inputs", key)
+		if err := w.server.DeregisterResource(uri); err != nil {
+			w.m.logger.Warn("Failed to deregister resource", "instance", w.target, "uri", uri, "error", err)
+		}
+		delete(w.inputs, key)
+		delete(w.fingerprint, uri)
+	}
+}
+
+func (w *resourceWatcher) registerInput(key, uri string) {
+	target := w.target
+	handler := func() (*mcp_golang.ResourceResponse, error) {
+		state, err := w.m.pool.state(target)
+		if err != nil {
+			errMsg := fmt.Sprintf("Failed to read resource %s: %v", uri, err)
+			w.m.logger.Error(errMsg, "instance", w.target, "uri", uri)
+			return nil, fmt.Errorf(errMsg)
+		}
+		in, ok := state.input(key)
+		if !ok {
+			return nil, fmt.Errorf("input %s no longer exists", key)
+		}
+		return jsonResource(uri, in)
+	}
+	if err := w.server.RegisterResource(uri, fmt.Sprintf("%s input %s", target.Name, key), "A single input of the vMix instance.", "application/json", handler); err != nil {
+		w.m.logger.Warn("Failed to register resource", "instance", w.target, "uri", uri, "error", err)
+		return
+	}
+	w.inputs[key] = struct{}{}
+}
+
+// compare notifies uri when v differs from the last check. The first check only records v.
+func (w *resourceWatcher) compare(uri string, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	prev, ok := w.fingerprint[uri]
+	w.fingerprint[uri] = b
+	if !ok || bytes.Equal(prev, b) {
+		return
+	}
+	if err := w.notifier.NotifyResourceUpdated(uri); err != nil {
+		w.m.logger.Warn("Failed to notify resource update", "instance", w.target, "uri", uri, "error", err)
+	}
+}
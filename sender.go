@@ -0,0 +1,37 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/FlowingSPDG/mcp-vmix/config"
+	"github.com/FlowingSPDG/mcp-vmix/logger"
+)
+
+// FunctionSender sends vMix functions outside of tool calls, e.g. to replay an audit log.
+// Functions go through the same TCP/HTTP API selection as the tools.
+type FunctionSender struct {
+	m *mcpVmix
+}
+
+// NewFunctionSender creates a sender for the instances of cfg.
+func NewFunctionSender(logger logger.Logger, cfg *config.Config) *FunctionSender {
+	instances := newInstanceRegistry(cfg)
+	m := &mcpVmix{
+		logger:    logger,
+		pool:      newClientPool(defaultStateMaxAge, defaultIdleTTL),
+		instances: instances,
+		events:    newEventHub(logger),
+	}
+	m.pool.tcpClient = m.events.client
+	return &FunctionSender{m: m}
+}
+
+// Send sends function with query to the named instance. An empty name sends to the default instance.
+func (s *FunctionSender) Send(ctx context.Context, instance, function string, query map[string]string) error {
+	target, err := s.m.instances.resolve("", BaseVMixArguments{Instance: instance})
+	if err != nil {
+		return fmt.Errorf("failed to resolve vMix instance: %w", err)
+	}
+	return s.m.sendFunction(ctx, target, function, query)
+}
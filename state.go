@@ -0,0 +1,288 @@
+package mcpvmix
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// vmixState is the XML state returned by the vMix /api endpoint.
+// vmix-go's model drops title fields, list items and most of the audio, so the whole document is mirrored here.
+// JSON tags are part of the structured output of vmix_fetch and resources, keep them stable.
+type vmixState struct {
+	XMLName     xml.Name          `xml:"vmix" json:"-"`
+	Version     string            `xml:"version" json:"version"`
+	Edition     string            `xml:"edition" json:"edition"`
+	Preset      string            `xml:"preset" json:"preset"`
+	Inputs      []stateInput      `xml:"inputs>input" json:"inputs"`
+	Overlays    []stateOverlay    `xml:"overlays>overlay" json:"overlays"`
+	Preview     int               `xml:"preview" json:"preview"`
+	Active      int               `xml:"active" json:"active"`
+	FadeToBlack bool              `xml:"fadeToBlack" json:"fadeToBlack"`
+	Transitions []stateTransition `xml:"transitions>transition" json:"transitions"`
+	Recording   stateRecording    `xml:"recording" json:"recording"`
+	External    bool              `xml:"external" json:"external"`
+	Streaming   stateStreaming    `xml:"streaming" json:"streaming"`
+	PlayList    bool              `xml:"playList" json:"playList"`
+	MultiCorder bool              `xml:"multiCorder" json:"multiCorder"`
+	FullScreen  bool              `xml:"fullscreen" json:"fullscreen"`
+	Mixes       []stateMix        `xml:"mix" json:"mixes"`
+	Audio       stateAudio        `xml:"audio" json:"audio"`
+	Dynamic     stateDynamic      `xml:"dynamic" json:"dynamic"`
+}
+
+type stateInput struct {
+	Key           string   `xml:"key,attr" json:"key"`
+	Number        int      `xml:"number,attr" json:"number"`
+	Type          string   `xml:"type,attr" json:"type"`
+	Title         string   `xml:"title,attr" json:"title"`
+	ShortTitle    string   `xml:"shortTitle,attr" json:"shortTitle"`
+	State         string   `xml:"state,attr" json:"state"`
+	Position      int      `xml:"position,attr" json:"position"`
+	Duration      int      `xml:"duration,attr" json:"duration"`
+	Loop          bool     `xml:"loop,attr" json:"loop"`
+	MarkIn        *int     `xml:"markIn,attr" json:"markIn,omitempty"`
+	MarkOut       *int     `xml:"markOut,attr" json:"markOut,omitempty"`
+	SelectedIndex *int     `xml:"selectedIndex,attr" json:"selectedIndex,omitempty"`
+	Name          string   `xml:",chardata" json:"name"`
+	Muted         *bool    `xml:"muted,attr" json:"muted,omitempty"`
+	Volume        *float64 `xml:"volume,attr" json:"volume,omitempty"`
+	Balance       *float64 `xml:"balance,attr" json:"balance,omitempty"`
+	Solo          *bool    `xml:"solo,attr" json:"solo,omitempty"`
+	AudioBusses   *string  `xml:"audiobusses,attr" json:"audioBusses,omitempty"`
+	MeterF1       *float64 `xml:"meterF1,attr" json:"meterF1,omitempty"`
+	MeterF2       *float64 `xml:"meterF2,attr" json:"meterF2,omitempty"`
+	GainDb        *float64 `xml:"gainDb,attr" json:"gainDb,omitempty"`
+
+	Overlays []stateInputOverlay `xml:"overlay" json:"layers,omitempty"`
+	Texts    []stateTitleField   `xml:"text" json:"texts,omitempty"`
+	Images   []stateTitleField   `xml:"image" json:"images,omitempty"`
+	List     []stateListItem     `xml:"list>item" json:"list,omitempty"`
+	Replay   *stateReplay        `xml:"replay" json:"replay,omitempty"`
+}
+
+type stateInputOverlay struct {
+	Index    int                 `xml:"index,attr" json:"index"`
+	Key      string              `xml:"key,attr" json:"key"`
+	Position *stateLayerPosition `xml:"position" json:"position,omitempty"`
+	Crop     *stateLayerCrop     `xml:"crop" json:"crop,omitempty"`
+}
+
+type stateLayerPosition struct {
+	PanX  float64 `xml:"panX,attr" json:"panX"`
+	PanY  float64 `xml:"panY,attr" json:"panY"`
+	ZoomX float64 `xml:"zoomX,attr" json:"zoomX"`
+	ZoomY float64 `xml:"zoomY,attr" json:"zoomY"`
+}
+
+type stateLayerCrop struct {
+	X1 float64 `xml:"X1,attr" json:"x1"`
+	Y1 float64 `xml:"Y1,attr" json:"y1"`
+	X2 float64 `xml:"X2,attr" json:"x2"`
+	Y2 float64 `xml:"Y2,attr" json:"y2"`
+}
+
+type stateTitleField struct {
+	Index int    `xml:"index,attr" json:"index"`
+	Name  string `xml:"name,attr" json:"name"`
+	Value string `xml:",chardata" json:"value"`
+}
+
+type stateListItem struct {
+	Enabled  bool   `xml:"enabled,attr" json:"enabled"`
+	Selected bool   `xml:"selected,attr" json:"selected"`
+	Value    string `xml:",chardata" json:"value"`
+}
+
+type stateReplay struct {
+	Live        bool    `xml:"live,attr" json:"live"`
+	Recording   bool    `xml:"recording,attr" json:"recording"`
+	ChannelMode string  `xml:"channelMode,attr" json:"channelMode"`
+	Events      int     `xml:"events,attr" json:"events"`
+	EventsA     int     `xml:"eventsA,attr" json:"eventsA"`
+	EventsB     int     `xml:"eventsB,attr" json:"eventsB"`
+	CameraA     string  `xml:"cameraA,attr" json:"cameraA"`
+	CameraB     string  `xml:"cameraB,attr" json:"cameraB"`
+	Speed       float64 `xml:"speed,attr" json:"speed"`
+	SpeedA      float64 `xml:"speedA,attr" json:"speedA"`
+	SpeedB      float64 `xml:"speedB,attr" json:"speedB"`
+	Timecode    string  `xml:"timecode" json:"timecode,omitempty"`
+	TimecodeA   string  `xml:"timecodeA" json:"timecodeA,omitempty"`
+	TimecodeB   string  `xml:"timecodeB" json:"timecodeB,omitempty"`
+}
+
+type stateOverlay struct {
+	Number  int    `xml:"number,attr" json:"number"`
+	Preview bool   `xml:"preview,attr" json:"preview,omitempty"`
+	Input   string `xml:",chardata" json:"input"` // input number, empty when the channel is off
+}
+
+type stateTransition struct {
+	Number   int    `xml:"number,attr" json:"number"`
+	Effect   string `xml:"effect,attr" json:"effect"`
+	Duration int    `xml:"duration,attr" json:"duration"`
+}
+
+type stateRecording struct {
+	On       bool `xml:",chardata" json:"on"`
+	Duration *int `xml:"duration,attr" json:"duration,omitempty"` // seconds, vMix 26 or later
+}
+
+type stateStreaming struct {
+	On       bool  `xml:",chardata" json:"on"`
+	Channel1 *bool `xml:"channel1,attr" json:"channel1,omitempty"`
+	Channel2 *bool `xml:"channel2,attr" json:"channel2,omitempty"`
+	Channel3 *bool `xml:"channel3,attr" json:"channel3,omitempty"`
+	Channel4 *bool `xml:"channel4,attr" json:"channel4,omitempty"`
+	Channel5 *bool `xml:"channel5,attr" json:"channel5,omitempty"`
+}
+
+type stateMix struct {
+	Number  int `xml:"number,attr" json:"number"`
+	Preview int `xml:"preview" json:"preview"`
+	Active  int `xml:"active" json:"active"`
+}
+
+type stateAudio struct {
+	Master *stateBus `xml:"master" json:"master,omitempty"`
+	BusA   *stateBus `xml:"busA" json:"busA,omitempty"`
+	BusB   *stateBus `xml:"busB" json:"busB,omitempty"`
+	BusC   *stateBus `xml:"busC" json:"busC,omitempty"`
+	BusD   *stateBus `xml:"busD" json:"busD,omitempty"`
+	BusE   *stateBus `xml:"busE" json:"busE,omitempty"`
+	BusF   *stateBus `xml:"busF" json:"busF,omitempty"`
+	BusG   *stateBus `xml:"busG" json:"busG,omitempty"`
+}
+
+type stateBus struct {
+	Volume           float64  `xml:"volume,attr" json:"volume"`
+	Muted            bool     `xml:"muted,attr" json:"muted"`
+	MeterF1          float64  `xml:"meterF1,attr" json:"meterF1"`
+	MeterF2          float64  `xml:"meterF2,attr" json:"meterF2"`
+	HeadphonesVolume *float64 `xml:"headphonesVolume,attr" json:"headphonesVolume,omitempty"`
+	Solo             *bool    `xml:"solo,attr" json:"solo,omitempty"`
+	SendToMaster     *bool    `xml:"sendToMaster,attr" json:"sendToMaster,omitempty"`
+}
+
+type stateDynamic struct {
+	Input1 string `xml:"input1" json:"input1,omitempty"`
+	Input2 string `xml:"input2" json:"input2,omitempty"`
+	Input3 string `xml:"input3" json:"input3,omitempty"`
+	Input4 string `xml:"input4" json:"input4,omitempty"`
+	Value1 string `xml:"value1" json:"value1,omitempty"`
+	Value2 string `xml:"value2" json:"value2,omitempty"`
+	Value3 string `xml:"value3" json:"value3,omitempty"`
+	Value4 string `xml:"value4" json:"value4,omitempty"`
+}
+
+func parseState(body []byte) (*vmixState, error) {
+	state := &vmixState{}
+	if err := xml.Unmarshal(body, state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal XML: %w", err)
+	}
+	// Keep the JSON output stable: empty lists are [] rather than null.
+	state.Inputs = lo.Ternary(state.Inputs == nil, []stateInput{}, state.Inputs)
+	state.Overlays = lo.Ternary(state.Overlays == nil, []stateOverlay{}, state.Overlays)
+	state.Transitions = lo.Ternary(state.Transitions == nil, []stateTransition{}, state.Transitions)
+	state.Mixes = lo.Ternary(state.Mixes == nil, []stateMix{}, state.Mixes)
+	// Title inputs have child elements, so the chardata contains their indentation.
+	for i := range state.Inputs {
+		state.Inputs[i].Name = strings.TrimSpace(state.Inputs[i].Name)
+	}
+	return state, nil
+}
+
+// input finds an input by key, number or title.
+func (s *vmixState) input(input string) (*stateInput, bool) {
+	for i := range s.Inputs {
+		in := &s.Inputs[i]
+		if in.Key == input || fmt.Sprint(in.Number) == input || in.Title == input {
+			return in, true
+		}
+	}
+	return nil, false
+}
+
+// onAir returns the keys of inputs which are on air:
+// the program of every mix, inputs on overlay channels and layers of those inputs.
+func (s *vmixState) onAir() map[string]bool {
+	byNumber := map[int]*stateInput{}
+	byKey := map[string]*stateInput{}
+	for i := range s.Inputs {
+		byNumber[s.Inputs[i].Number] = &s.Inputs[i]
+		byKey[s.Inputs[i].Key] = &s.Inputs[i]
+	}
+
+	onAir := map[string]bool{}
+	var mark func(in *stateInput)
+	mark = func(in *stateInput) {
+		if in == nil || onAir[in.Key] {
+			return
+		}
+		onAir[in.Key] = true
+		for _, layer := range in.Overlays {
+			mark(byKey[layer.Key])
+		}
+	}
+
+	mark(byNumber[s.Active])
+	for _, mix := range s.Mixes {
+		mark(byNumber[mix.Active])
+	}
+	for _, overlay := range s.Overlays {
+		if overlay.Preview || overlay.Input == "" {
+			continue
+		}
+		if number, err := strconv.Atoi(overlay.Input); err == nil {
+			mark(byNumber[number])
+		}
+	}
+	return onAir
+}
+
+// inputFilter selects inputs of the state. The zero value matches every input.
+type inputFilter struct {
+	types []string // input types such as "Video" or "GT", case insensitive
+	name  string   // glob matched against the name and the title, case insensitive
+	onAir bool
+}
+
+func (f inputFilter) empty() bool {
+	return len(f.types) == 0 && f.name == "" && !f.onAir
+}
+
+// filterInputs returns the inputs matching the filter, keeping the order of the state.
+func (s *vmixState) filterInputs(f inputFilter) ([]stateInput, error) {
+	pattern := strings.ToLower(f.name)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid name pattern %q: %w", f.name, err)
+	}
+
+	var onAir map[string]bool
+	if f.onAir {
+		onAir = s.onAir()
+	}
+
+	inputs := []stateInput{}
+	for _, in := range s.Inputs {
+		if len(f.types) != 0 && !lo.ContainsBy(f.types, func(t string) bool { return strings.EqualFold(t, in.Type) }) {
+			continue
+		}
+		if pattern != "" {
+			nameMatched, _ := path.Match(pattern, strings.ToLower(in.Name))
+			titleMatched, _ := path.Match(pattern, strings.ToLower(in.Title))
+			if !nameMatched && !titleMatched {
+				continue
+			}
+		}
+		if f.onAir && !onAir[in.Key] {
+			continue
+		}
+		inputs = append(inputs, in)
+	}
+	return inputs, nil
+}
@@ -0,0 +1,119 @@
+package mcpvmix
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/samber/lo"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func loadState(t *testing.T) *vmixState {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "state.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := parseState(body)
+	if err != nil {
+		t.Fatalf("parseState() error = %v", err)
+	}
+	return state
+}
+
+// TestParseStateJSON guards the JSON shape of vmix_fetch format json and the resources.
+// Run with -update after an intended change of the shape.
+func TestParseStateJSON(t *testing.T) {
+	state := loadState(t)
+	got, err := json.MarshalIndent(fetchResult{Instance: "main (127.0.0.1:8088)", TotalInputs: len(state.Inputs), vmixState: *state}, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal state: %v", err)
+	}
+	got = append(got, '\n')
+
+	golden := filepath.Join("testdata", "state.golden.json")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("JSON of the state differs from %s:\n%s", golden, got)
+	}
+}
+
+func TestParseStateEmptyLists(t *testing.T) {
+	state, err := parseState([]byte("<vmix><version>27.0.0.49</version></vmix>"))
+	if err != nil {
+		t.Fatalf("parseState() error = %v", err)
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"inputs", "overlays", "transitions", "mixes"} {
+		if list, ok := got[key].([]any); !ok || len(list) != 0 {
+			t.Errorf("%s = %v, want []", key, got[key])
+		}
+	}
+}
+
+func TestParseStateInvalid(t *testing.T) {
+	if _, err := parseState([]byte("<vmix><inputs>")); err == nil {
+		t.Error("parseState() of broken XML succeeded")
+	}
+}
+
+func TestFilterInputs(t *testing.T) {
+	state := loadState(t)
+
+	tests := []struct {
+		name   string
+		filter inputFilter
+		want   []string
+	}{
+		{name: "empty", filter: inputFilter{}, want: []string{"k1", "k2", "k3", "k4", "k5", "k6", "k7"}},
+		{name: "type", filter: inputFilter{types: []string{"capture"}}, want: []string{"k1", "k2"}},
+		{name: "types", filter: inputFilter{types: []string{"GT", "Image"}}, want: []string{"k3", "k4"}},
+		{name: "unknown type", filter: inputFilter{types: []string{"NDI"}}, want: []string{}},
+		{name: "name glob", filter: inputFilter{name: "camera*"}, want: []string{"k1", "k2"}},
+		{name: "title glob", filter: inputFilter{name: "*.MP4"}, want: []string{"k5"}},
+		{name: "exact name", filter: inputFilter{name: "lower third"}, want: []string{"k3"}},
+		// program of each mix, the overlay channels which are not preview only and layers of those inputs
+		{name: "on air", filter: inputFilter{onAir: true}, want: []string{"k1", "k3", "k4", "k5"}},
+		{name: "combined", filter: inputFilter{types: []string{"Capture", "Video"}, onAir: true}, want: []string{"k1", "k5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs, err := state.filterInputs(tt.filter)
+			if err != nil {
+				t.Fatalf("filterInputs() error = %v", err)
+			}
+			got := lo.Map(inputs, func(in stateInput, _ int) string { return in.Key })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterInputs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterInputsInvalidPattern(t *testing.T) {
+	state := loadState(t)
+	if _, err := state.filterInputs(inputFilter{name: "cam["}); err == nil {
+		t.Error("filterInputs() with a broken pattern succeeded")
+	}
+}
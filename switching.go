@@ -0,0 +1,258 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/FlowingSPDG/mcp-vmix/functions"
+)
+
+// mixParams adds the Mix parameter of mix to params.
+// Tools count mixes from 1 like the vMix UI, while the API counts from 0, so Mix 1 is sent without the parameter.
+// Whether the instance has mix is checked by checkMix.
+func mixParams(params map[string]string, mix int) (map[string]string, error) {
+	if mix < 1 {
+		return nil, fmt.Errorf("invalid mix: %d. Mixes are counted from 1", mix)
+	}
+	if mix > 1 {
+		params["Mix"] = strconv.Itoa(mix - 1)
+	}
+	return params, nil
+}
+
+// singleMixEditions are the vMix editions without Mix 2 and later.
+var singleMixEditions = []string{"Basic", "Basic HD", "SD", "HD"}
+
+// checkMix returns an error if the instance does not have mix, judging by the edition and the mixes in its state.
+func (s *vmixState) checkMix(mix int) error {
+	if mix <= 1 {
+		return nil
+	}
+	for _, edition := range singleMixEditions {
+		if strings.EqualFold(s.Edition, edition) {
+			return fmt.Errorf("%s is not available on vMix %s edition. Only Mix 1 can be used", mixName(mix), s.Edition)
+		}
+	}
+	for _, m := range s.Mixes {
+		if m.Number == mix {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not available on this vMix instance (edition %s)", mixName(mix), s.Edition)
+}
+
+// mixName returns "Mix n".
+func mixName(mix int) string {
+	return fmt.Sprintf("Mix %d", mix)
+}
+
+// sendMixFunction sends a switching function to mix. action is used in log and error messages.
+func (m *mcpVmix) sendMixFunction(ctx context.Context, target vmixTarget, action, function string, params map[string]string, mix int) error {
+	params, err := mixParams(params, mix)
+	if err == nil && mix > 1 {
+		var state *vmixState
+		if state, err = m.pool.state(target); err == nil {
+			err = state.checkMix(mix)
+		}
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to %s: %v", action, err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return fmt.Errorf(errMsg)
+	}
+
+	log := m.log(ctx).With("instance", target, "function", function, "input", params["Input"], "mix", mixName(mix))
+	log.Info("Attempting to switch")
+	if err := m.sendFunction(ctx, target, function, params); err != nil {
+		errMsg := fmt.Sprintf("Failed to %s: %v", action, err)
+		log.Error(errMsg)
+		return fmt.Errorf(errMsg)
+	}
+	log.Info("Successfully switched")
+	return nil
+}
+
+// PreviewInput implements MCPvMix.
+func (m *mcpVmix) PreviewInput(ctx context.Context, arguments PreviewInputArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	action := fmt.Sprintf("put input %s in preview", arguments.Input)
+	if err := m.sendMixFunction(ctx, target, action, "PreviewInput", map[string]string{"Input": arguments.Input}, arguments.mix()); err != nil {
+		return nil, err
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Input %s is in preview of %s", arguments.Input, mixName(arguments.mix())))), nil
+}
+
+// PreviewNext implements MCPvMix.
+func (m *mcpVmix) PreviewNext(ctx context.Context, arguments PreviewStepArguments) (*mcp_golang.ToolResponse, error) {
+	return m.previewStep(ctx, arguments, "PreviewInputNext", "next")
+}
+
+// PreviewPrevious implements MCPvMix.
+func (m *mcpVmix) PreviewPrevious(ctx context.Context, arguments PreviewStepArguments) (*mcp_golang.ToolResponse, error) {
+	return m.previewStep(ctx, arguments, "PreviewInputPrevious", "previous")
+}
+
+func (m *mcpVmix) previewStep(ctx context.Context, arguments PreviewStepArguments, function, direction string) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	action := fmt.Sprintf("put the %s input in preview", direction)
+	if err := m.sendMixFunction(ctx, target, action, function, map[string]string{}, arguments.mix()); err != nil {
+		return nil, err
+	}
+	return m.mixStatusResponse(ctx, target, fmt.Sprintf("Put the %s input in preview", direction), arguments.mix())
+}
+
+// TransitionVMix implements MCPvMix.
+func (m *mcpVmix) TransitionVMix(ctx context.Context, arguments TransitionArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+	if arguments.Button < 1 || arguments.Button > 4 {
+		errMsg := fmt.Sprintf("Invalid transition button: %d. Use 1~4", arguments.Button)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	action := fmt.Sprintf("perform transition %d", arguments.Button)
+	if err := m.sendMixFunction(ctx, target, action, fmt.Sprintf("Transition%d", arguments.Button), map[string]string{}, arguments.mix()); err != nil {
+		return nil, err
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Performed transition %d on %s", arguments.Button, mixName(arguments.mix())))), nil
+}
+
+// StingerVMix implements MCPvMix.
+func (m *mcpVmix) StingerVMix(ctx context.Context, arguments StingerArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+	if arguments.Stinger < 1 || arguments.Stinger > 4 {
+		errMsg := fmt.Sprintf("Invalid stinger: %d. Use 1~4", arguments.Stinger)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	params := map[string]string{}
+	if arguments.Input != "" {
+		params["Input"] = arguments.Input
+	}
+	action := fmt.Sprintf("perform stinger %d", arguments.Stinger)
+	if err := m.sendMixFunction(ctx, target, action, fmt.Sprintf("Stinger%d", arguments.Stinger), params, arguments.mix()); err != nil {
+		return nil, err
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Performed stinger %d on %s", arguments.Stinger, mixName(arguments.mix())))), nil
+}
+
+// TransitionEffectVMix implements MCPvMix.
+func (m *mcpVmix) TransitionEffectVMix(ctx context.Context, arguments TransitionEffectArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	// 効果名はカタログで Duration を取るトランジションに限る
+	catalogue, err := functions.Default()
+	if err != nil {
+		m.log(ctx).Error(err.Error(), "instance", target)
+		return nil, err
+	}
+	function, ok := catalogue.Lookup(arguments.Effect)
+	if !ok || function.Category != "Transition" || function.Duration == "" {
+		errMsg := fmt.Sprintf("Unknown transition effect: %s", arguments.Effect)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	if arguments.Duration < 0 {
+		errMsg := fmt.Sprintf("Invalid duration: %d", arguments.Duration)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	params := map[string]string{}
+	if arguments.Input != "" {
+		params["Input"] = arguments.Input
+	}
+	if arguments.Duration > 0 {
+		params["Duration"] = strconv.Itoa(arguments.Duration)
+	}
+	action := fmt.Sprintf("perform %s transition", function.Name)
+	if err := m.sendMixFunction(ctx, target, action, function.Name, params, arguments.mix()); err != nil {
+		return nil, err
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("Performed %s transition on %s", function.Name, mixName(arguments.mix())))), nil
+}
+
+// MixStatus implements MCPvMix.
+func (m *mcpVmix) MixStatus(ctx context.Context, arguments MixStatusArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+	// 省略時は全てのミックスを返す
+	mix := 0
+	if arguments.Mix != nil {
+		mix = *arguments.Mix
+		if mix < 1 || mix > functions.MaxMix {
+			errMsg := fmt.Sprintf("Invalid mix: %d. Use 1~%d", mix, functions.MaxMix)
+			m.log(ctx).Error(errMsg, "instance", target)
+			return nil, fmt.Errorf(errMsg)
+		}
+	}
+	return m.mixStatusResponse(ctx, target, "", mix)
+}
+
+// mixStatusResponse reads the latest state and returns program and preview of mix, or of every mix when mix is 0.
+func (m *mcpVmix) mixStatusResponse(ctx context.Context, target vmixTarget, header string, mix int) (*mcp_golang.ToolResponse, error) {
+	state, err := m.pool.refresh(target)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	var lines []string
+	if header != "" {
+		lines = append(lines, header)
+	}
+	for _, s := range state.mixStates() {
+		if mix != 0 && s.Number != mix {
+			continue
+		}
+		lines = append(lines, state.describeMix(s))
+	}
+	if err := state.checkMix(mix); err != nil {
+		lines = append(lines, err.Error())
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(strings.Join(lines, "\n"))), nil
+}
+
+// mixStates returns program and preview of every mix. The main mix is number 1.
+func (s *vmixState) mixStates() []stateMix {
+	mixes := []stateMix{{Number: 1, Preview: s.Preview, Active: s.Active}}
+	return append(mixes, s.Mixes...)
+}
+
+// describeMix formats program and preview of mix with the input names.
+func (s *vmixState) describeMix(mix stateMix) string {
+	return fmt.Sprintf("Mix %d: Program: %s, Preview: %s", mix.Number, s.describeInputNumber(mix.Active), s.describeInputNumber(mix.Preview))
+}
+
+func (s *vmixState) describeInputNumber(number int) string {
+	in, ok := s.input(strconv.Itoa(number))
+	if !ok {
+		return fmt.Sprintf("%d", number)
+	}
+	return fmt.Sprintf("%d %s (key %s)", in.Number, strings.TrimSpace(in.Title), in.Key)
+}
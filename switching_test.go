@@ -0,0 +1,70 @@
+package mcpvmix
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/FlowingSPDG/mcp-vmix/functions"
+)
+
+func TestMixParams(t *testing.T) {
+	cases := []struct {
+		name    string
+		mix     int
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "main mix sends no parameter", mix: 1, want: map[string]string{"Input": "3"}},
+		{name: "mix 2", mix: 2, want: map[string]string{"Input": "3", "Mix": "1"}},
+		{name: "mix 4", mix: 4, want: map[string]string{"Input": "3", "Mix": "3"}},
+		{name: "highest mix", mix: functions.MaxMix, want: map[string]string{"Input": "3", "Mix": "15"}},
+		{name: "zero", mix: 0, wantErr: true},
+		{name: "negative", mix: -1, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := mixParams(map[string]string{"Input": "3"}, tc.mix)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("mixParams() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("mixParams() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckMix(t *testing.T) {
+	cases := []struct {
+		name    string
+		edition string
+		mixes   []int
+		mix     int
+		wantErr string
+	}{
+		{name: "main mix on any edition", edition: "Basic", mix: 1},
+		{name: "listed mix", edition: "4K", mixes: []int{2, 3}, mix: 3},
+		{name: "unlisted mix", edition: "Pro", mixes: []int{2}, mix: 5, wantErr: "Mix 5 is not available on this vMix instance"},
+		{name: "single mix edition", edition: "HD", mixes: []int{2}, mix: 2, wantErr: "not available on vMix HD edition"},
+		{name: "edition is case insensitive", edition: "basic hd", mix: 2, wantErr: "Only Mix 1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := &vmixState{Edition: tc.edition}
+			for _, n := range tc.mixes {
+				state.Mixes = append(state.Mixes, stateMix{Number: n})
+			}
+			err := state.checkMix(tc.mix)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkMix() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("checkMix() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
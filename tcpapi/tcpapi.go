@@ -0,0 +1,397 @@
+// Package tcpapi is a client of the vMix TCP API (port 8099).
+//
+// vmix-go's tcp package dials a fixed port and cannot match responses with requests,
+// so this package speaks the protocol directly. Responses of the same command are
+// returned by vMix in order, so every command keeps a FIFO of waiting callers.
+package tcpapi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	vmixtcp "github.com/FlowingSPDG/vmix-go/tcp"
+)
+
+// DefaultPort is the port of the vMix TCP API.
+const DefaultPort = 8099
+
+const (
+	commandVersion     = "VERSION"
+	commandTally       = "TALLY"
+	commandFunction    = "FUNCTION"
+	commandXML         = "XML"
+	commandXMLText     = "XMLTEXT"
+	commandSubscribe   = "SUBSCRIBE"
+	commandUnsubscribe = "UNSUBSCRIBE"
+	commandActs        = "ACTS"
+	commandQuit        = "QUIT"
+
+	statusOK = "OK"
+	statusER = "ER"
+)
+
+// Events which can be subscribed with Subscribe.
+const (
+	EventTally = commandTally
+	EventActs  = commandActs
+)
+
+var (
+	ErrClosed = errors.New("connection to vMix is closed")
+)
+
+// Error is returned when vMix answers a command with ER.
+type Error struct {
+	Command string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("vMix returned error for %s: %s", e.Command, e.Message)
+}
+
+// Event is an asynchronous message from a SUBSCRIBE'd event.
+type Event struct {
+	Type string // EventTally or EventActs
+
+	// TALLY
+	Tally []vmixtcp.TallyStatus
+
+	// ACTS. e.g. "Input 1 1" is Name="Input", Input=1, Value="1"
+	Name  string
+	Input int
+	Value string
+}
+
+type response struct {
+	body string
+	err  error
+}
+
+// Client is a connection to the vMix TCP API.
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	pending  map[string][]chan response
+	handlers map[int]func(Event)
+	nextID   int
+	version  string
+	err      error
+
+	done chan struct{}
+}
+
+// Dial connects to the vMix TCP API at addr (host:port).
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect vMix TCP API at %s: %w", addr, err)
+	}
+	c := &Client{
+		conn:     conn,
+		reader:   bufio.NewReader(conn),
+		pending:  map[string][]chan response{},
+		handlers: map[int]func(Event){},
+		done:     make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// Version returns the vMix version sent on connection. It is empty until vMix sent it.
+func (c *Client) Version() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// Done is closed when the connection is lost or closed.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the reason the connection was closed.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// OnEvent registers a handler for subscribed events. The returned function removes the handler.
+// Handlers are called from the reading goroutine and must not block.
+func (c *Client) OnEvent(handler func(Event)) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := c.nextID
+	c.nextID++
+	c.handlers[id] = handler
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.handlers, id)
+	}
+}
+
+// Function sends a shortcut function. params are sent as the query, e.g. Input=1&Duration=500.
+func (c *Client) Function(ctx context.Context, name string, params map[string]string) error {
+	q := url.Values{}
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	line := commandFunction + " " + name
+	if len(q) > 0 {
+		line += " " + q.Encode()
+	}
+	_, err := c.request(ctx, commandFunction, line)
+	return err
+}
+
+// XML returns the whole XML state. It is the same document as the HTTP /api endpoint.
+func (c *Client) XML(ctx context.Context) ([]byte, error) {
+	body, err := c.request(ctx, commandXML, commandXML)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(body), nil
+}
+
+// XMLText returns the value selected by an XPath, e.g. vmix/inputs/input[1]/@title.
+func (c *Client) XMLText(ctx context.Context, xpath string) (string, error) {
+	return c.request(ctx, commandXMLText, commandXMLText+" "+xpath)
+}
+
+// Tally returns the tally state of every input in input number order.
+func (c *Client) Tally(ctx context.Context) ([]vmixtcp.TallyStatus, error) {
+	body, err := c.request(ctx, commandTally, commandTally)
+	if err != nil {
+		return nil, err
+	}
+	return parseTally(body), nil
+}
+
+// Subscribe subscribes to EventTally or EventActs. Events are delivered to OnEvent handlers.
+func (c *Client) Subscribe(ctx context.Context, event string) error {
+	_, err := c.request(ctx, commandSubscribe, commandSubscribe+" "+event)
+	return err
+}
+
+// Unsubscribe stops receiving the event.
+func (c *Client) Unsubscribe(ctx context.Context, event string) error {
+	_, err := c.request(ctx, commandUnsubscribe, commandUnsubscribe+" "+event)
+	return err
+}
+
+// Close sends QUIT and closes the connection.
+func (c *Client) Close() error {
+	c.write(commandQuit)
+	err := c.conn.Close()
+	c.shutdown(ErrClosed)
+	return err
+}
+
+func (c *Client) request(ctx context.Context, command, line string) (string, error) {
+	ch := make(chan response, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return "", err
+	}
+	c.pending[command] = append(c.pending[command], ch)
+	c.mu.Unlock()
+
+	if err := c.write(line); err != nil {
+		c.cancel(command, ch)
+		return "", err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp.body, resp.err
+	case <-ctx.Done():
+		// The response will still arrive. Leave ch in the queue so the order is kept.
+		return "", ctx.Err()
+	}
+}
+
+func (c *Client) write(line string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := io.WriteString(c.conn, line+"\r\n"); err != nil {
+		return fmt.Errorf("failed to send %q: %w", line, err)
+	}
+	return nil
+}
+
+func (c *Client) cancel(command string, ch chan response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	queue := c.pending[command]
+	for i, q := range queue {
+		if q == ch {
+			c.pending[command] = append(queue[:i], queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// deliver passes the response to the oldest waiter of the command. It reports whether anyone was waiting.
+func (c *Client) deliver(command string, resp response) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	queue := c.pending[command]
+	if len(queue) == 0 {
+		return false
+	}
+	c.pending[command] = queue[1:]
+	queue[0] <- resp
+	return true
+}
+
+func (c *Client) emit(ev Event) {
+	c.mu.Lock()
+	handlers := make([]func(Event), 0, len(c.handlers))
+	for _, h := range c.handlers {
+		handlers = append(handlers, h)
+	}
+	c.mu.Unlock()
+
+	for _, h := range handlers {
+		h(ev)
+	}
+}
+
+func (c *Client) shutdown(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	for command, queue := range c.pending {
+		for _, ch := range queue {
+			ch <- response{err: err}
+		}
+		delete(c.pending, command)
+	}
+	close(c.done)
+}
+
+func (c *Client) readLoop() {
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+				c.shutdown(ErrClosed)
+			} else {
+				c.shutdown(fmt.Errorf("failed to read from vMix: %w", err))
+			}
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		if err := c.handleLine(line); err != nil {
+			c.conn.Close()
+			c.shutdown(err)
+			return
+		}
+	}
+}
+
+func (c *Client) handleLine(line string) error {
+	command, rest, _ := strings.Cut(line, " ")
+
+	// XML has a length instead of a status. e.g. "XML 1234" followed by 1234 bytes.
+	if command == commandXML {
+		length, err := strconv.Atoi(strings.TrimSpace(rest))
+		if err != nil {
+			return fmt.Errorf("failed to parse XML length %q: %w", rest, err)
+		}
+		b := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, b); err != nil {
+			return fmt.Errorf("failed to read XML: %w", err)
+		}
+		c.deliver(commandXML, response{body: string(b)})
+		return nil
+	}
+
+	status, body, _ := strings.Cut(rest, " ")
+	var resp response
+	switch status {
+	case statusOK:
+		resp.body = body
+	case statusER:
+		resp.err = &Error{Command: command, Message: body}
+	default:
+		// Some responses have no status, keep the whole rest as body.
+		resp.body = rest
+	}
+
+	switch command {
+	case commandVersion:
+		c.mu.Lock()
+		c.version = resp.body
+		c.mu.Unlock()
+	case commandTally:
+		c.deliver(commandTally, resp)
+		if resp.err == nil {
+			c.emit(Event{Type: EventTally, Tally: parseTally(resp.body)})
+		}
+	case commandActs:
+		if c.deliver(commandActs, resp) || resp.err != nil {
+			return nil
+		}
+		c.emit(parseActs(resp.body))
+	default:
+		c.deliver(command, resp)
+	}
+	return nil
+}
+
+func parseTally(body string) []vmixtcp.TallyStatus {
+	tally := make([]vmixtcp.TallyStatus, 0, len(body))
+	for _, b := range []byte(body) {
+		switch b {
+		case '1':
+			tally = append(tally, vmixtcp.Program)
+		case '2':
+			tally = append(tally, vmixtcp.Preview)
+		default:
+			tally = append(tally, vmixtcp.Off)
+		}
+	}
+	return tally
+}
+
+// parseActs parses an activator line such as "Input 1 1" or "InputPlaying 3 0".
+func parseActs(body string) Event {
+	ev := Event{Type: EventActs}
+	fields := strings.Fields(body)
+	if len(fields) > 0 {
+		ev.Name = fields[0]
+	}
+	switch len(fields) {
+	case 2:
+		ev.Value = fields[1]
+	case 3:
+		ev.Input, _ = strconv.Atoi(fields[1])
+		ev.Value = fields[2]
+	}
+	return ev
+}
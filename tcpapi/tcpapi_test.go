@@ -0,0 +1,219 @@
+package tcpapi_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	vmixtcp "github.com/FlowingSPDG/vmix-go/tcp"
+
+	"github.com/FlowingSPDG/mcp-vmix/tcpapi"
+	"github.com/FlowingSPDG/mcp-vmix/tcpapi/tcpapitest"
+)
+
+func connect(t *testing.T) (*tcpapitest.Server, *tcpapi.Client) {
+	t.Helper()
+	srv, err := tcpapitest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start fake server: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	client, err := tcpapi.Dial(ctx, srv.Addr())
+	if err != nil {
+		t.Fatalf("failed to dial fake server: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return srv, client
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestFunction(t *testing.T) {
+	srv, client := connect(t)
+	ctx := testContext(t)
+
+	if err := client.Function(ctx, "Cut", map[string]string{"Input": "1"}); err != nil {
+		t.Fatalf("Function() error = %v", err)
+	}
+	if err := client.Function(ctx, "FadeToBlack", nil); err != nil {
+		t.Fatalf("Function() error = %v", err)
+	}
+
+	want := []string{"Cut Input=1", "FadeToBlack"}
+	if got := srv.Functions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Functions() = %q, want %q", got, want)
+	}
+	if got := client.Version(); got != tcpapitest.Version {
+		t.Errorf("Version() = %q, want %q", got, tcpapitest.Version)
+	}
+}
+
+func TestFunctionError(t *testing.T) {
+	srv, client := connect(t)
+	ctx := testContext(t)
+	srv.FailFunction("Cut", "Input not found")
+
+	err := client.Function(ctx, "Cut", map[string]string{"Input": "99"})
+	var apiErr *tcpapi.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Function() error = %v, want *tcpapi.Error", err)
+	}
+	if apiErr.Command != "FUNCTION" || apiErr.Message != "Input not found" {
+		t.Errorf("Function() error = %+v", apiErr)
+	}
+
+	// The connection is still usable after ER.
+	if err := client.Function(ctx, "Fade", nil); err != nil {
+		t.Errorf("Function() after ER error = %v", err)
+	}
+}
+
+func TestXML(t *testing.T) {
+	srv, client := connect(t)
+	ctx := testContext(t)
+
+	// The body has line breaks and a line which looks like a response, so only the length frames it.
+	doc := "<vmix>\r\n<version>27</version>\r\nFUNCTION OK Completed\r\n</vmix>"
+	srv.SetXML(doc)
+
+	got, err := client.XML(ctx)
+	if err != nil {
+		t.Fatalf("XML() error = %v", err)
+	}
+	if string(got) != doc {
+		t.Errorf("XML() = %q, want %q", got, doc)
+	}
+
+	if err := client.Function(ctx, "Cut", nil); err != nil {
+		t.Fatalf("Function() after XML error = %v", err)
+	}
+	if got := srv.Functions(); len(got) != 1 {
+		t.Errorf("Functions() = %q, want only Cut", got)
+	}
+}
+
+func TestXMLText(t *testing.T) {
+	srv, client := connect(t)
+	ctx := testContext(t)
+	srv.SetXMLText("vmix/inputs/input[1]/@title", "Camera 1")
+
+	got, err := client.XMLText(ctx, "vmix/inputs/input[1]/@title")
+	if err != nil {
+		t.Fatalf("XMLText() error = %v", err)
+	}
+	if got != "Camera 1" {
+		t.Errorf("XMLText() = %q, want %q", got, "Camera 1")
+	}
+
+	_, err = client.XMLText(ctx, "vmix/missing")
+	var apiErr *tcpapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Command != "XMLTEXT" {
+		t.Errorf("XMLText() of a missing path error = %v, want XMLTEXT *tcpapi.Error", err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	srv, client := connect(t)
+	ctx := testContext(t)
+
+	events := make(chan tcpapi.Event, 10)
+	client.OnEvent(func(ev tcpapi.Event) { events <- ev })
+	for _, event := range []string{tcpapi.EventTally, tcpapi.EventActs} {
+		if err := client.Subscribe(ctx, event); err != nil {
+			t.Fatalf("Subscribe(%s) error = %v", event, err)
+		}
+	}
+
+	srv.SetTally("0120")
+	srv.Activator("Input 3 1")
+	srv.Activator("Recording 1")
+
+	want := []tcpapi.Event{
+		{Type: tcpapi.EventTally, Tally: []vmixtcp.TallyStatus{vmixtcp.Off, vmixtcp.Program, vmixtcp.Preview, vmixtcp.Off}},
+		{Type: tcpapi.EventActs, Name: "Input", Input: 3, Value: "1"},
+		{Type: tcpapi.EventActs, Name: "Recording", Value: "1"},
+	}
+	for i, w := range want {
+		select {
+		case got := <-events:
+			if !reflect.DeepEqual(got, w) {
+				t.Errorf("event %d = %+v, want %+v", i, got, w)
+			}
+		case <-ctx.Done():
+			t.Fatalf("event %d was not received", i)
+		}
+	}
+
+	// Activators after UNSUBSCRIBE are not delivered.
+	if err := client.Unsubscribe(ctx, tcpapi.EventActs); err != nil {
+		t.Fatalf("Unsubscribe() error = %v", err)
+	}
+	srv.Activator("Input 4 1")
+	srv.SetTally("1")
+	select {
+	case got := <-events:
+		if got.Type != tcpapi.EventTally {
+			t.Errorf("event after Unsubscribe = %+v, want only TALLY", got)
+		}
+	case <-ctx.Done():
+		t.Fatal("TALLY event after Unsubscribe was not received")
+	}
+}
+
+func TestTally(t *testing.T) {
+	srv, client := connect(t)
+	ctx := testContext(t)
+	srv.SetTally("21")
+
+	got, err := client.Tally(ctx)
+	if err != nil {
+		t.Fatalf("Tally() error = %v", err)
+	}
+	want := []vmixtcp.TallyStatus{vmixtcp.Preview, vmixtcp.Program}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tally() = %v, want %v", got, want)
+	}
+}
+
+func TestLateResponseAfterTimeout(t *testing.T) {
+	srv, client := connect(t)
+	srv.DelayFunction("Slow", 200*time.Millisecond)
+	srv.FailFunction("Slow", "late error")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.Function(ctx, "Slow", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Function() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// The late ER belongs to the timed out call and must not be taken as the answer of the next one.
+	if err := client.Function(testContext(t), "Fast", nil); err != nil {
+		t.Errorf("Function() after a timed out call error = %v", err)
+	}
+}
+
+func TestClosedConnection(t *testing.T) {
+	srv, client := connect(t)
+	srv.Close()
+
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done() was not closed after the server closed")
+	}
+	if err := client.Function(testContext(t), "Cut", nil); err == nil {
+		t.Error("Function() on a closed connection succeeded")
+	}
+	if client.Err() == nil {
+		t.Error("Err() = nil after the connection was lost")
+	}
+}
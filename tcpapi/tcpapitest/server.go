@@ -0,0 +1,222 @@
+// Package tcpapitest provides a fake vMix TCP API server for tests.
+package tcpapitest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is sent to clients on connection.
+const Version = "27.0.0.49"
+
+// Server is a fake vMix TCP API listening on a local port.
+// It answers FUNCTION, XML, XMLTEXT, TALLY, SUBSCRIBE and UNSUBSCRIBE and records received functions.
+type Server struct {
+	ln net.Listener
+
+	mu        sync.Mutex
+	xml       string
+	xmlText   map[string]string
+	tally     string
+	failures  map[string]string
+	delays    map[string]time.Duration
+	functions []string
+	conns     map[net.Conn]*subscriptions
+	wg        sync.WaitGroup
+}
+
+type subscriptions struct {
+	writeMu sync.Mutex
+	tally   bool
+	acts    bool
+}
+
+// NewServer starts a fake server on 127.0.0.1 with a random port.
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		ln:       ln,
+		xml:      "<vmix><version>" + Version + "</version></vmix>",
+		xmlText:  map[string]string{},
+		failures: map[string]string{},
+		delays:   map[string]time.Duration{},
+		conns:    map[net.Conn]*subscriptions{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns host:port of the server.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close stops the server and disconnects clients.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// SetXML sets the document returned by XML.
+func (s *Server) SetXML(xml string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.xml = xml
+}
+
+// SetXMLText sets the value returned by XMLTEXT for the xpath.
+func (s *Server) SetXMLText(xpath, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.xmlText[xpath] = value
+}
+
+// SetTally sets the tally string (e.g. "0121") and sends it to TALLY subscribers.
+func (s *Server) SetTally(tally string) {
+	s.mu.Lock()
+	s.tally = tally
+	s.mu.Unlock()
+	s.broadcast(func(sub *subscriptions) bool { return sub.tally }, "TALLY OK "+tally)
+}
+
+// Activator sends an activator line (e.g. "Input 1 1") to ACTS subscribers.
+func (s *Server) Activator(line string) {
+	s.broadcast(func(sub *subscriptions) bool { return sub.acts }, "ACTS OK "+line)
+}
+
+// FailFunction makes the function answer ER with the message.
+func (s *Server) FailFunction(name, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[strings.ToLower(name)] = message
+}
+
+// DelayFunction makes the server wait before answering the function.
+// Commands sent after it on the same connection are answered after it, as vMix does.
+func (s *Server) DelayFunction(name string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delays[strings.ToLower(name)] = d
+}
+
+// Functions returns received functions as "Name query" in received order.
+func (s *Server) Functions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.functions...)
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		sub := &subscriptions{}
+		s.mu.Lock()
+		s.conns[conn] = sub
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(conn, sub)
+	}
+}
+
+func (s *Server) broadcast(match func(*subscriptions) bool, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn, sub := range s.conns {
+		if match(sub) {
+			s.write(conn, sub, line)
+		}
+	}
+}
+
+func (s *Server) write(conn net.Conn, sub *subscriptions, line string) {
+	sub.writeMu.Lock()
+	defer sub.writeMu.Unlock()
+	fmt.Fprintf(conn, "%s\r\n", line)
+}
+
+func (s *Server) handle(conn net.Conn, sub *subscriptions) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	s.write(conn, sub, "VERSION OK "+Version)
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		command, rest, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(command) {
+		case "FUNCTION":
+			name, _, _ := strings.Cut(rest, " ")
+			s.mu.Lock()
+			s.functions = append(s.functions, rest)
+			message, fail := s.failures[strings.ToLower(name)]
+			delay := s.delays[strings.ToLower(name)]
+			s.mu.Unlock()
+			time.Sleep(delay)
+			if fail {
+				s.write(conn, sub, "FUNCTION ER "+message)
+				continue
+			}
+			s.write(conn, sub, "FUNCTION OK Completed")
+		case "XML":
+			s.mu.Lock()
+			xml := s.xml
+			s.mu.Unlock()
+			s.write(conn, sub, fmt.Sprintf("XML %d\r\n%s", len(xml), xml))
+		case "XMLTEXT":
+			s.mu.Lock()
+			value, ok := s.xmlText[rest]
+			s.mu.Unlock()
+			if !ok {
+				s.write(conn, sub, "XMLTEXT ER XPath not found")
+				continue
+			}
+			s.write(conn, sub, "XMLTEXT OK "+value)
+		case "TALLY":
+			s.mu.Lock()
+			tally := s.tally
+			s.mu.Unlock()
+			s.write(conn, sub, "TALLY OK "+tally)
+		case "SUBSCRIBE", "UNSUBSCRIBE":
+			on := strings.EqualFold(command, "SUBSCRIBE")
+			s.mu.Lock()
+			switch strings.ToUpper(rest) {
+			case "TALLY":
+				sub.tally = on
+			case "ACTS":
+				sub.acts = on
+			}
+			s.mu.Unlock()
+			s.write(conn, sub, strings.ToUpper(command)+" OK "+rest)
+		case "QUIT":
+			return
+		default:
+			s.write(conn, sub, strings.ToUpper(command)+" ER Unknown command")
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package mcpvmix
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// colourPattern matches the colours SetTextColour accepts: #RRGGBB, #AARRGGBB or a name.
+var colourPattern = regexp.MustCompile(`^(#[0-9A-Fa-f]{6}|#[0-9A-Fa-f]{8}|[A-Za-z]+)$`)
+
+// titleField is a text or image field of a title input.
+type titleField struct {
+	stateTitleField
+	image bool
+}
+
+func (in *stateInput) titleFields() []titleField {
+	fields := make([]titleField, 0, len(in.Texts)+len(in.Images))
+	for _, f := range in.Texts {
+		fields = append(fields, titleField{stateTitleField: f})
+	}
+	for _, f := range in.Images {
+		fields = append(fields, titleField{stateTitleField: f, image: true})
+	}
+	return fields
+}
+
+// titleField finds a field by name, case insensitive, or by index.
+// A name without the .Text/.Source suffix matches too when it is not ambiguous.
+func (in *stateInput) titleField(name string) (titleField, error) {
+	fields := in.titleFields()
+	for _, f := range fields {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	var matches []titleField
+	for _, f := range fields {
+		base, _, _ := strings.Cut(f.Name, ".")
+		if strings.EqualFold(f.Name, name) || strings.EqualFold(base, name) || strconv.Itoa(f.Index) == name {
+			matches = append(matches, f)
+		}
+	}
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		names := make([]string, len(fields))
+		for i, f := range fields {
+			names[i] = f.Name
+		}
+		if len(names) == 0 {
+			return titleField{}, fmt.Errorf("input %d %s has no title fields", in.Number, strings.TrimSpace(in.Title))
+		}
+		return titleField{}, fmt.Errorf("unknown field %q. Fields are %s", name, strings.Join(names, ", "))
+	}
+	return titleField{}, fmt.Errorf("field %q is ambiguous, use the full name", name)
+}
+
+// titleFunction is a function sent to update a field.
+type titleFunction struct {
+	function string
+	field    string
+	value    *string // nil sends no Value
+}
+
+func (f titleFunction) String() string {
+	if f.value == nil {
+		return fmt.Sprintf("%s %s", f.function, f.field)
+	}
+	return fmt.Sprintf("%s %s = %q", f.function, f.field, *f.value)
+}
+
+// titleFunctions validates update against field and returns the functions to send.
+func titleFunctions(field titleField, update TitleFieldUpdate) ([]titleFunction, error) {
+	var functions []titleFunction
+	kind := "text"
+	if field.image {
+		kind = "image"
+	}
+	if field.image && (update.Text != nil || update.Colour != "") {
+		return nil, fmt.Errorf("%s is an image field, set image instead of text or colour", field.Name)
+	}
+	if !field.image && update.Image != "" {
+		return nil, fmt.Errorf("%s is a text field, set text instead of image", field.Name)
+	}
+
+	if update.Text != nil {
+		functions = append(functions, titleFunction{function: "SetText", field: field.Name, value: update.Text})
+	}
+	if update.Image != "" {
+		functions = append(functions, titleFunction{function: "SetImage", field: field.Name, value: &update.Image})
+	}
+	if update.Colour != "" {
+		if !colourPattern.MatchString(update.Colour) {
+			return nil, fmt.Errorf("invalid colour %q for %s. Use #RRGGBB, #AARRGGBB or a colour name", update.Colour, field.Name)
+		}
+		functions = append(functions, titleFunction{function: "SetTextColour", field: field.Name, value: &update.Colour})
+	}
+	if update.Visible != nil {
+		state := "Off"
+		if *update.Visible {
+			state = "On"
+		}
+		prefix := "SetTextVisible"
+		if field.image {
+			prefix = "SetImageVisible"
+		}
+		functions = append(functions, titleFunction{function: prefix + state, field: field.Name})
+	}
+	if len(functions) == 0 {
+		return nil, fmt.Errorf("nothing to update on %s field %s", kind, field.Name)
+	}
+	return functions, nil
+}
+
+// TitleFields implements MCPvMix.
+func (m *mcpVmix) TitleFields(ctx context.Context, arguments TitleFieldsArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	in, err := m.titleInput(ctx, target, arguments.Input)
+	if err != nil {
+		return nil, err
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(describeTitleFields(in))), nil
+}
+
+// SetTitleFields implements MCPvMix.
+func (m *mcpVmix) SetTitleFields(ctx context.Context, arguments SetTitleFieldsArguments) (*mcp_golang.ToolResponse, error) {
+	target, err := m.resolveTarget(ctx, arguments.BaseVMixArguments)
+	if err != nil {
+		return nil, err
+	}
+	if len(arguments.Fields) == 0 {
+		errMsg := "No fields to update"
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	in, err := m.titleInput(ctx, target, arguments.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	// 途中で失敗して一部だけ反映されることを避けるため、送信前にすべて検証する
+	var functions []titleFunction
+	var errs []string
+	for _, update := range arguments.Fields {
+		field, err := in.titleField(update.Field)
+		if err == nil {
+			var fs []titleFunction
+			if fs, err = titleFunctions(field, update); err == nil {
+				functions = append(functions, fs...)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		errMsg := fmt.Sprintf("Invalid title field updates, nothing was sent to vMix: %s", strings.Join(errs, "; "))
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	m.log(ctx).Info("Attempting to update title fields", "instance", target, "input", in.Key, "fields", len(arguments.Fields))
+	for i, f := range functions {
+		params := map[string]string{"Input": in.Key, "SelectedName": f.field}
+		if f.value != nil {
+			params["Value"] = *f.value
+		}
+		if err := m.sendFunction(ctx, target, f.function, params); err != nil {
+			errMsg := fmt.Sprintf("Failed to send %s: %v. %d of %d functions were applied", f, err, i, len(functions))
+			m.log(ctx).Error(errMsg, "instance", target)
+			return nil, fmt.Errorf(errMsg)
+		}
+	}
+	m.log(ctx).Info("Successfully updated title fields", "instance", target, "input", in.Key)
+
+	lines := make([]string, 0, len(functions)+1)
+	for _, f := range functions {
+		lines = append(lines, "Sent "+f.String())
+	}
+	if updated, err := m.titleInput(ctx, target, in.Key); err == nil {
+		lines = append(lines, describeTitleFields(updated))
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(strings.Join(lines, "\n"))), nil
+}
+
+// titleInput reads the latest state and returns the input.
+func (m *mcpVmix) titleInput(ctx context.Context, target vmixTarget, input string) (*stateInput, error) {
+	state, err := m.pool.refresh(target)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to connect to vMix instance: %v", err)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	in, ok := state.input(input)
+	if !ok {
+		errMsg := fmt.Sprintf("Input %s not found", input)
+		m.log(ctx).Error(errMsg, "instance", target)
+		return nil, fmt.Errorf(errMsg)
+	}
+	return in, nil
+}
+
+// describeTitleFields lists the fields of a title input with their current values.
+func describeTitleFields(in *stateInput) string {
+	fields := in.titleFields()
+	if len(fields) == 0 {
+		return fmt.Sprintf("Input %d %s (%s) has no title fields", in.Number, strings.TrimSpace(in.Title), in.Type)
+	}
+	lines := []string{fmt.Sprintf("Title fields of input %d %s (key %s):", in.Number, strings.TrimSpace(in.Title), in.Key)}
+	for _, f := range fields {
+		kind := "text"
+		if f.image {
+			kind = "image"
+		}
+		lines = append(lines, fmt.Sprintf("- %s [%s, index %d]: %q", f.Name, kind, f.Index, f.Value))
+	}
+	return strings.Join(lines, "\n")
+}
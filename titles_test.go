@@ -0,0 +1,146 @@
+package mcpvmix
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/samber/lo"
+)
+
+func titleInputState() *stateInput {
+	return &stateInput{
+		Number: 3,
+		Title:  "Lower Third.gtzip ",
+		Texts: []stateTitleField{
+			{Index: 0, Name: "Headline.Text", Value: "Breaking"},
+			{Index: 1, Name: "Name.Text", Value: "Jane"},
+			{Index: 2, Name: "Logo.Text", Value: "RSLT"},
+		},
+		Images: []stateTitleField{
+			{Index: 3, Name: "Logo.Source", Value: `C:\logo.png`},
+			{Index: 4, Name: "Photo.Source"},
+		},
+	}
+}
+
+func TestTitleField(t *testing.T) {
+	cases := []struct {
+		name      string
+		field     string
+		wantName  string
+		wantImage bool
+		wantErr   string
+	}{
+		{name: "full name", field: "Headline.Text", wantName: "Headline.Text"},
+		{name: "case insensitive", field: "headline.text", wantName: "Headline.Text"},
+		{name: "base name", field: "Name", wantName: "Name.Text"},
+		{name: "base name of an image", field: "photo", wantName: "Photo.Source", wantImage: true},
+		{name: "index", field: "3", wantName: "Logo.Source", wantImage: true},
+		{name: "full name of an ambiguous base name", field: "Logo.Source", wantName: "Logo.Source", wantImage: true},
+		{name: "ambiguous base name", field: "Logo", wantErr: `field "Logo" is ambiguous`},
+		{name: "unknown", field: "Subtitle", wantErr: "Fields are Headline.Text, Name.Text, Logo.Text, Logo.Source, Photo.Source"},
+		{name: "unknown index", field: "9", wantErr: `unknown field "9"`},
+	}
+	in := titleInputState()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := in.titleField(tc.field)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("titleField() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("titleField() error = %v", err)
+			}
+			if got.Name != tc.wantName || got.image != tc.wantImage {
+				t.Errorf("titleField() = %s (image %v), want %s (image %v)", got.Name, got.image, tc.wantName, tc.wantImage)
+			}
+		})
+	}
+}
+
+func TestTitleFieldNoFields(t *testing.T) {
+	in := &stateInput{Number: 1, Title: "Camera 1 "}
+	if _, err := in.titleField("Headline"); err == nil || err.Error() != "input 1 Camera 1 has no title fields" {
+		t.Errorf("titleField() error = %v", err)
+	}
+}
+
+func TestTitleFunctions(t *testing.T) {
+	text := titleField{stateTitleField: stateTitleField{Name: "Headline.Text"}}
+	image := titleField{stateTitleField: stateTitleField{Name: "Logo.Source"}, image: true}
+	cases := []struct {
+		name    string
+		field   titleField
+		update  TitleFieldUpdate
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "text",
+			field:  text,
+			update: TitleFieldUpdate{Text: lo.ToPtr("Hello")},
+			want:   []string{`SetText Headline.Text = "Hello"`},
+		},
+		{
+			name:   "clear text",
+			field:  text,
+			update: TitleFieldUpdate{Text: lo.ToPtr("")},
+			want:   []string{`SetText Headline.Text = ""`},
+		},
+		{
+			name:   "text, colour and visibility",
+			field:  text,
+			update: TitleFieldUpdate{Text: lo.ToPtr("Hello"), Colour: "#FF0000", Visible: lo.ToPtr(false)},
+			want:   []string{`SetText Headline.Text = "Hello"`, `SetTextColour Headline.Text = "#FF0000"`, "SetTextVisibleOff Headline.Text"},
+		},
+		{
+			name:   "colour with alpha",
+			field:  text,
+			update: TitleFieldUpdate{Colour: "#80ff0000"},
+			want:   []string{`SetTextColour Headline.Text = "#80ff0000"`},
+		},
+		{
+			name:   "colour name",
+			field:  text,
+			update: TitleFieldUpdate{Colour: "Red"},
+			want:   []string{`SetTextColour Headline.Text = "Red"`},
+		},
+		{
+			name:   "image",
+			field:  image,
+			update: TitleFieldUpdate{Image: `C:\logo.png`, Visible: lo.ToPtr(true)},
+			want:   []string{`SetImage Logo.Source = "C:\\logo.png"`, "SetImageVisibleOn Logo.Source"},
+		},
+		{name: "short colour", field: text, update: TitleFieldUpdate{Colour: "#F00"}, wantErr: "invalid colour"},
+		{name: "colour with spaces", field: text, update: TitleFieldUpdate{Colour: "dark red"}, wantErr: "invalid colour"},
+		{name: "colour with a hex digit missing", field: text, update: TitleFieldUpdate{Colour: "#FF00000"}, wantErr: "invalid colour"},
+		{name: "text on an image", field: image, update: TitleFieldUpdate{Text: lo.ToPtr("Hello")}, wantErr: "is an image field"},
+		{name: "colour on an image", field: image, update: TitleFieldUpdate{Colour: "Red"}, wantErr: "is an image field"},
+		{name: "image on a text", field: text, update: TitleFieldUpdate{Image: "logo.png"}, wantErr: "is a text field"},
+		{name: "nothing", field: image, wantErr: "nothing to update on image field Logo.Source"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			functions, err := titleFunctions(tc.field, tc.update)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("titleFunctions() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("titleFunctions() error = %v", err)
+			}
+			got := make([]string, len(functions))
+			for i, f := range functions {
+				got[i] = f.String()
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("titleFunctions() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}